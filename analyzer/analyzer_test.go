@@ -0,0 +1,2064 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func callerNames(t *testing.T, a *Analyzer, signature string) map[string]bool {
+	t.Helper()
+	sites, err := a.FindCallers(signature, false)
+	if err != nil {
+		t.Fatalf("FindCallers(%q): %v", signature, err)
+	}
+	names := make(map[string]bool)
+	for _, cs := range sites {
+		names[cs.Caller.Name] = true
+	}
+	return names
+}
+
+func TestLoadPackagesFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":      {Data: []byte("package main\n\nfunc Target() {}\n\nfunc main() {\n\tTarget()\n}\n")},
+		"util/util.go": {Data: []byte("package util\n\nfunc Helper() {\n\tTarget()\n}\n")},
+	}
+
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	callers := callerNames(t, a, "func Target()")
+	if !callers["main"] || !callers["Helper"] || len(callers) != 2 {
+		t.Errorf("callers = %v, want main and Helper", callers)
+	}
+}
+
+func TestLoadPackagesWithOverlay(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go": {Data: []byte("package main\n\nfunc Target() {}\n\nfunc main() {\n\tTarget()\n}\n")},
+	}
+	overlay := map[string][]byte{
+		// An unsaved edit replacing the caller, and a buffer not yet on disk.
+		"main.go":  []byte("package main\n\nfunc Target() {}\n\nfunc main() {\n\trun()\n}\n\nfunc run() {\n\tTarget()\n}\n"),
+		"extra.go": []byte("package main\n\nfunc extra() {\n\tTarget()\n}\n"),
+	}
+
+	a := NewAnalyzer(WithFS(fsys), WithOverlay(overlay))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	callers := callerNames(t, a, "func Target()")
+	if callers["main"] {
+		t.Errorf("main should no longer call Target once the overlay is applied")
+	}
+	if !callers["run"] || !callers["extra"] {
+		t.Errorf("callers = %v, want run and extra", callers)
+	}
+}
+
+func TestHeldLock(t *testing.T) {
+	src := `package main
+
+import "sync"
+
+var mu sync.Mutex
+
+func Target() {}
+
+func locked() {
+	mu.Lock()
+	Target()
+	mu.Unlock()
+}
+
+func deferred() {
+	mu.Lock()
+	defer mu.Unlock()
+	Target()
+}
+
+func unlocked() {
+	mu.Lock()
+	mu.Unlock()
+	Target()
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	sites, err := a.FindCallers("func Target()", false)
+	if err != nil {
+		t.Fatalf("FindCallers: %v", err)
+	}
+	want := map[string]string{"locked": "mu", "deferred": "mu", "unlocked": ""}
+	for _, cs := range sites {
+		if cs.HeldLock != want[cs.Caller.Name] {
+			t.Errorf("%s: HeldLock = %q, want %q", cs.Caller.Name, cs.HeldLock, want[cs.Caller.Name])
+		}
+	}
+	if len(sites) != len(want) {
+		t.Errorf("got %d call sites, want %d", len(sites), len(want))
+	}
+}
+
+func TestFreshContext(t *testing.T) {
+	src := `package main
+
+import "context"
+
+func needsCtx(ctx context.Context) {}
+
+func direct(ctx context.Context) {
+	needsCtx(context.Background())
+}
+
+func viaVar(ctx context.Context) {
+	fresh, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	needsCtx(fresh)
+}
+
+func passed(ctx context.Context) {
+	_ = context.Background()
+	needsCtx(ctx)
+}
+
+func inClosure(ctx context.Context) {
+	go func() { needsCtx(context.Background()) }()
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	sites, err := a.FindCallers("func needsCtx(ctx context.Context)", false)
+	if err != nil {
+		t.Fatalf("FindCallers: %v", err)
+	}
+	// The literal's call is its own, not inClosure's
+	want := map[string]int{"direct": 8, "viaVar": 12, "passed": 0, "func(...)": 23}
+	for _, cs := range sites {
+		name, _, _ := strings.Cut(cs.Caller.Name, " in ")
+		if cs.FreshContext != want[name] {
+			t.Errorf("%s: FreshContext = %d, want %d", name, cs.FreshContext, want[name])
+		}
+	}
+	if len(sites) != len(want) {
+		t.Errorf("got %d call sites, want %d", len(sites), len(want))
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	src := `package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+var ErrX = errors.New("x")
+
+func Target() error { return nil }
+
+func handle(err error) {}
+
+func propagate() error { return Target() }
+
+func propagateVar() error {
+	err := Target()
+	return err
+}
+
+func wrap() error { return fmt.Errorf("target: %w", Target()) }
+
+func wrapNoW() error {
+	if err := Target(); err != nil {
+		return fmt.Errorf("target: %v", err)
+	}
+	return nil
+}
+
+func logs() {
+	if err := Target(); err != nil {
+		log.Println(err)
+	}
+}
+
+func swallow() { Target() }
+
+func guard() error {
+	if err := Target(); err != nil {
+		return nil
+	}
+	return nil
+}
+
+func blank() { _ = Target() }
+
+func deferred() { defer Target() }
+
+func goroutine() { go Target() }
+
+func compare() bool { return Target() != nil }
+
+func is() bool { return errors.Is(Target(), ErrX) }
+
+func isVar() bool {
+	err := Target()
+	return errors.Is(err, ErrX)
+}
+
+func argument() { handle(Target()) }
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	sites, err := a.FindCallers("func Target() error", false)
+	if err != nil {
+		t.Fatalf("FindCallers: %v", err)
+	}
+	got := make(map[string]string)
+	for _, cs := range sites {
+		got[cs.Caller.Name] = a.ErrorHandling(cs)
+	}
+	tests := []struct {
+		caller string
+		want   string
+	}{
+		{"propagate", ErrorPropagates},
+		{"propagateVar", ErrorPropagates},
+		{"wrap", ErrorWraps},
+		{"wrapNoW", ErrorWrapsNoW},
+		{"logs", ErrorLogs},
+		{"swallow", ErrorSwallows},
+		{"guard", ErrorSwallows},
+		{"blank", ErrorSwallows},
+		{"deferred", ErrorSwallows},
+		{"goroutine", ErrorSwallows},
+		{"compare", ErrorChecks},
+		{"is", ErrorChecks},
+		{"isVar", ErrorChecks},
+		{"argument", ErrorPassesOn},
+	}
+	for _, tt := range tests {
+		if got[tt.caller] != tt.want {
+			t.Errorf("%s: ErrorHandling = %q, want %q", tt.caller, got[tt.caller], tt.want)
+		}
+	}
+	if len(got) != len(tests) {
+		t.Errorf("got %d callers, want %d", len(got), len(tests))
+	}
+}
+
+func TestPrefilter(t *testing.T) {
+	// Each caller is in its own file, found in a later round than its
+	// callee, and other.go never mentions any of them
+	fsys := fstest.MapFS{
+		"target.go":  {Data: []byte("package app\n\nfunc Target() {}\n")},
+		"direct.go":  {Data: []byte("package app\n\nfunc Direct() {\n\tTarget()\n\tgo func() { Target() }()\n}\n")},
+		"middle.go":  {Data: []byte("package app\n\nfunc Middle() { Direct() }\n")},
+		"closure.go": {Data: []byte("package app\n\nfunc Outer() {\n\trun(func() { Middle() })\n}\n\nfunc run(f func()) { f() }\n")},
+		"top.go":     {Data: []byte("package app\n\nfunc Top() { Outer() }\n")},
+		"other.go":   {Data: []byte("package app\n\nfunc Other() { helper() }\n\nfunc helper() {}\n")},
+	}
+	callers := func(a *Analyzer) []string {
+		fn, err := a.FindFunction("func Target()")
+		if err != nil {
+			t.Fatalf("FindFunction: %v", err)
+		}
+		var names []string
+		for _, caller := range a.TransitiveCallers(fn, false) {
+			names = append(names, caller.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	full := NewAnalyzer(WithFS(fsys))
+	if err := full.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	filtered := NewAnalyzer(WithFS(fsys), WithPrefilter("func Target()"))
+	if err := filtered.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages with prefilter: %v", err)
+	}
+
+	want, got := callers(full), callers(filtered)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("prefiltered callers %v, want %v as without prefilter", got, want)
+	}
+	if len(want) != 6 {
+		t.Errorf("transitive callers %v, want Direct, Middle, Outer, Top and two literals", want)
+	}
+	helper, err := filtered.FindFunction("func helper()")
+	if err != nil {
+		t.Fatalf("FindFunction: %v", err)
+	}
+	if n := len(filtered.GetCallersOf(helper)); n != 0 {
+		t.Errorf("helper has %d callers with prefilter, want 0: other.go should not be parsed", n)
+	}
+}
+
+func TestIsGeneratedSource(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n", true},
+		{"// Copyright 2024\n\n// Code generated by stringer; DO NOT EDIT.\r\npackage x\n", true},
+		{"package x\n\n// Code generated by hand. DO NOT EDIT.\n", false},
+		{"// Code generated by hand, do not edit.\npackage x\n", false},
+	}
+	for _, tt := range tests {
+		if got := isGeneratedSource([]byte(tt.src)); got != tt.want {
+			t.Errorf("isGeneratedSource(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSkipDirs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"testdata/proj/main.go":           {Data: []byte("package main\n\nfunc Target() {}\n\nfunc main() {\n\tTarget()\n}\n")},
+		"testdata/proj/a_vendor.go":       {Data: []byte("package main\n\nfunc a() {\n\tTarget()\n}\n")},
+		"testdata/proj/vendor/lib.go":     {Data: []byte("package lib\n\nfunc Vendored() {\n\tTarget()\n}\n")},
+		"testdata/proj/myvendor/lib.go":   {Data: []byte("package lib\n\nfunc Kept() {\n\tTarget()\n}\n")},
+		"testdata/proj/gen/testdata/x.go": {Data: []byte("package x\n\nfunc Fixture() {\n\tTarget()\n}\n")},
+	}
+
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("testdata/proj"); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	callers := callerNames(t, a, "func Target()")
+	if !callers["main"] || !callers["a"] || !callers["Kept"] || len(callers) != 3 {
+		t.Errorf("callers = %v, want main, a and Kept", callers)
+	}
+
+	a = NewAnalyzer(WithFS(fsys), WithSkipDirs())
+	if err := a.LoadPackages("testdata/proj"); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	if callers := callerNames(t, a, "func Target()"); len(callers) != 5 {
+		t.Errorf("callers = %v, want all five with no skipped directories", callers)
+	}
+}
+
+func TestFunctionPositions(t *testing.T) {
+	src := "package main\n\nfunc Target() {\n\tgo func() {\n\t}()\n}\n"
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	type span struct{ line, col, endLine, endCol int }
+	want := map[string]span{
+		"Target":               {3, 1, 6, 1},
+		"func(...) in main.go": {4, 5, 5, 2},
+	}
+	for _, fn := range a.GetFunctions() {
+		got := span{fn.Line, fn.Column, fn.EndLine, fn.EndColumn}
+		if w, ok := want[fn.Name]; ok {
+			if got != w {
+				t.Errorf("%s spans %v, want %v", fn.Name, got, w)
+			}
+			delete(want, fn.Name)
+		}
+	}
+	for name := range want {
+		t.Errorf("function %s not found", name)
+	}
+}
+
+func TestFunctionDocs(t *testing.T) {
+	src := `package main
+
+// Target loads the configuration. It panics on error.
+func Target() {}
+
+func undocumented() {}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	want := map[string]string{
+		"Target":       "Target loads the configuration.",
+		"undocumented": "",
+	}
+	for _, fn := range a.GetFunctions() {
+		if w, ok := want[fn.Name]; ok && fn.Doc != w {
+			t.Errorf("%s has doc %q, want %q", fn.Name, fn.Doc, w)
+		}
+	}
+}
+
+func TestFunctionResults(t *testing.T) {
+	src := `package main
+
+func none() {}
+
+func single() error { return nil }
+
+func pair() (int, error) { return 0, nil }
+
+func named() (n int, err error) { return }
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	want := map[string]string{
+		"none":   "",
+		"single": "error",
+		"pair":   "(int, error)",
+		"named":  "(n int, err error)",
+	}
+	for _, fn := range a.GetFunctions() {
+		if w, ok := want[fn.Name]; ok {
+			if fn.Results != w {
+				t.Errorf("%s has results %q, want %q", fn.Name, fn.Results, w)
+			}
+			delete(want, fn.Name)
+		}
+	}
+	for name := range want {
+		t.Errorf("function %s not found", name)
+	}
+}
+
+func TestReceiverKind(t *testing.T) {
+	src := `package main
+
+type A struct{}
+
+type B struct{}
+
+func (a *A) Run() {}
+
+func (b B) Run() {}
+`
+	fsys := fstest.MapFS{"main.go": {Data: []byte(src)}}
+	for _, tc := range []struct{ kind, want string }{
+		{ReceiverPointer, "*A"},
+		{ReceiverValue, "B"},
+	} {
+		a := NewAnalyzer(WithFS(fsys), WithReceiverKind(tc.kind))
+		if err := a.LoadPackages("."); err != nil {
+			t.Fatalf("LoadPackages: %v", err)
+		}
+		fn, err := a.FindFunction("func Run()")
+		if err != nil {
+			t.Fatalf("%s: FindFunction: %v", tc.kind, err)
+		}
+		if fn.Receiver != tc.want || fn.PointerRecv != (tc.kind == ReceiverPointer) {
+			t.Errorf("%s: found receiver %s (pointer %v), want %s", tc.kind, fn.Receiver, fn.PointerRecv, tc.want)
+		}
+	}
+}
+
+func TestFindMethods(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/a.go": {Data: []byte("package a\n\ntype Service struct{}\n\nfunc (s *Service) Stop() {}\n\nfunc (s Service) Name() string { return \"\" }\n")},
+		"b/b.go": {Data: []byte("package b\n\ntype Service struct{}\n\nfunc (s *Service) Run() {}\n")},
+	}
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	if _, err := a.FindMethods("Service"); err == nil {
+		t.Error("FindMethods(Service) succeeded, want an ambiguity error")
+	}
+	methods, err := a.FindMethods("a.Service")
+	if err != nil {
+		t.Fatalf("FindMethods(a.Service): %v", err)
+	}
+	var names []string
+	for _, fn := range methods {
+		names = append(names, fn.Name)
+	}
+	if got := strings.Join(names, ","); got != "Name,Stop" {
+		t.Errorf("methods of a.Service = %s, want Name,Stop", got)
+	}
+}
+
+func TestImplementations(t *testing.T) {
+	src := `package main
+
+type Named interface{ Name() string }
+
+type Store interface {
+	Named
+	Save(key string, value []byte) error
+}
+
+type memStore struct{}
+
+func (m *memStore) Save(k string, v []byte) error { return nil }
+
+func (m memStore) Name() string { return "" }
+
+type fileStore struct{}
+
+func (f fileStore) Save(path string) error { return nil }
+
+func (f fileStore) Name() string { return "" }
+
+type buffer struct{}
+
+func (b *buffer) Read(p []byte) (n int, err error) { return 0, nil }
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	for name, want := range map[string]string{"Store": "*memStore", "Named": "fileStore,memStore", "io.Reader": "*buffer"} {
+		iface, err := a.FindInterface(name)
+		if err != nil {
+			t.Fatalf("FindInterface(%s): %v", name, err)
+		}
+		var got []string
+		for _, impl := range a.Implementations(iface) {
+			if impl.Pointer {
+				got = append(got, "*"+impl.Type)
+			} else {
+				got = append(got, impl.Type)
+			}
+		}
+		if strings.Join(got, ",") != want {
+			t.Errorf("%s is implemented by %v, want %s", name, got, want)
+		}
+	}
+}
+
+func TestInterfaceDispatch(t *testing.T) {
+	src := `package main
+
+type Processor interface{ DoWork() }
+
+type fast struct{}
+
+func (f *fast) DoWork() {}
+
+type slow struct{}
+
+func (s slow) DoWork() {}
+
+func run(p Processor) {
+	p.DoWork()
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	for _, fn := range a.GetFunctions() {
+		if fn.Name != "DoWork" {
+			continue
+		}
+		callers := a.GetCallersOf(fn)
+		if len(callers) != 1 || callers[0].Caller.Name != "run" || callers[0].Kind != CallInterface {
+			t.Errorf("(%s).DoWork has callers %v, want run through the interface", fn.Receiver, callers)
+		}
+	}
+}
+
+func TestPlatforms(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":        {Data: []byte("package main\n\nfunc main() {\n\topen()\n}\n")},
+		"open_plan9.go":  {Data: []byte("package main\n\nfunc open() {}\n")},
+		"open_zos.go":    {Data: []byte("package main\n\nfunc open() {}\n")},
+		"tagged.go":      {Data: []byte("//go:build zos && !integration\n\npackage main\n\nfunc tagged() {\n\topen()\n}\n")},
+		"integration.go": {Data: []byte("//go:build integration\n\npackage main\n\nfunc integration() {}\n")},
+	}
+
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	for _, fn := range a.GetFunctions() {
+		if fn.Name == "tagged" {
+			if fn.Constraint != "" {
+				t.Errorf("tagged has constraint %q without WithAllPlatforms", fn.Constraint)
+			}
+			break
+		}
+	}
+	if !callerNames(t, a, "func open()")["tagged"] {
+		t.Error("file for another platform was skipped by default")
+	}
+
+	a = NewAnalyzer(WithFS(fsys), WithHostPlatform())
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, fn := range a.GetFunctions() {
+		names[fn.Name] = true
+	}
+	if !names["integration"] {
+		t.Error("file with a custom build tag was skipped")
+	}
+	if names["tagged"] {
+		t.Error("file for another platform was analyzed with WithHostPlatform")
+	}
+
+	a = NewAnalyzer(WithFS(fsys), WithAllPlatforms())
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	var constraints []string
+	for _, fn := range a.GetFunctions() {
+		if fn.Name != "open" {
+			continue
+		}
+		constraints = append(constraints, fn.Constraint)
+		var callers []string
+		for _, cs := range a.GetCallersOf(fn) {
+			callers = append(callers, cs.Caller.Name)
+		}
+		sort.Strings(callers)
+		if want := "main"; fn.Constraint == "zos" {
+			want = "main,tagged"
+			if got := strings.Join(callers, ","); got != want {
+				t.Errorf("open [%s] has callers %s, want %s", fn.Constraint, got, want)
+			}
+		} else if got := strings.Join(callers, ","); got != want {
+			t.Errorf("open [%s] has callers %s, want %s", fn.Constraint, got, want)
+		}
+	}
+	sort.Strings(constraints)
+	if got := strings.Join(constraints, ","); got != "plan9,zos" {
+		t.Errorf("open variants have constraints %s, want plan9,zos", got)
+	}
+}
+
+func TestModules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/go.mod":     {Data: []byte("module example.com/app\n\ngo 1.21\n")},
+		"app/main.go":    {Data: []byte("package main\n\nimport \"example.com/lib/v2\"\n\nfunc main() {\n\tlib.Helper()\n}\n")},
+		"lib/go.mod":     {Data: []byte("module example.com/lib/v2\n")},
+		"lib/lib.go":     {Data: []byte("package lib\n\nfunc Helper() {}\n")},
+		"lib/sub/sub.go": {Data: []byte("package sub\n\nfunc Helper() {}\n")},
+	}
+
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	helpers := make(map[string]*Function)
+	for _, fn := range a.GetFunctions() {
+		switch fn.Name {
+		case "main":
+			if fn.Module != "example.com/app" {
+				t.Errorf("main has module %q, want example.com/app", fn.Module)
+			}
+		case "Helper":
+			helpers[fn.importPath] = fn
+		}
+	}
+	helper, sub := helpers["example.com/lib/v2"], helpers["example.com/lib/v2/sub"]
+	if helper == nil || sub == nil {
+		t.Fatalf("Helper import paths: %v", helpers)
+	}
+	if helper.Module != "example.com/lib/v2" || sub.Module != "example.com/lib/v2" {
+		t.Errorf("Helper modules are %q and %q, want example.com/lib/v2", helper.Module, sub.Module)
+	}
+	if callers := a.GetCallersOf(helper); len(callers) != 1 || callers[0].Caller.Name != "main" {
+		t.Errorf("lib.Helper() was not resolved to the imported package: %v", callers)
+	}
+	if callers := a.GetCallersOf(sub); len(callers) != 0 {
+		t.Errorf("sub.Helper has %d callers, want none", len(callers))
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":                             {Data: []byte("module example.com/app\n")},
+		"main.go":                            {Data: []byte("package main\n\nimport \"github.com/x/web\"\n\nfunc main() {\n\tweb.Run()\n}\n")},
+		"vendor/github.com/x/web/web.go":     {Data: []byte("package web\n\nfunc Run() {}\n")},
+		"cache/github.com/y/z@v1.2.0/go.mod": {Data: []byte("module github.com/y/z\n")},
+		"cache/github.com/y/z@v1.2.0/z.go":   {Data: []byte("package z\n\nfunc Z() {}\n")},
+	}
+
+	a := NewAnalyzer(WithFS(fsys), WithSkipDirs())
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	deps := make(map[string]string)
+	var run *Function
+	for _, fn := range a.GetFunctions() {
+		deps[fn.Name] = fn.Dependency
+		if fn.Name == "Run" {
+			run = fn
+		}
+	}
+	want := map[string]string{"main": "", "Run": "github.com/x/web", "Z": "github.com/y/z"}
+	for name, dep := range want {
+		if deps[name] != dep {
+			t.Errorf("%s has dependency %q, want %q", name, deps[name], dep)
+		}
+	}
+	if callers := a.GetCallersOf(run); len(callers) != 1 || callers[0].Caller.Name != "main" {
+		t.Errorf("web.Run() was not resolved to the vendored package: %v", callers)
+	}
+}
+
+func TestEntryPoints(t *testing.T) {
+	src := `package main
+
+import (
+	"net/http"
+
+	"example.com/web/pb"
+)
+
+type UserHandler struct{}
+
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	loadUsers()
+}
+
+type server struct{}
+
+func (s *server) GetUser() {
+	loadUsers()
+}
+
+func (s *server) helper() {}
+
+type api struct{}
+
+func (api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	loadUsers()
+}
+
+func routes(r Router, h *UserHandler) {
+	r.GET("/users", h.List)
+	r.Get("/things", listThings)
+	http.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		loadUsers()
+	})
+	http.Handle("/api/", &api{})
+	pb.RegisterUsersServer(nil, &server{})
+}
+
+func listThings(w http.ResponseWriter, r *http.Request) {
+	loadUsers()
+}
+
+func loadUsers() {}
+`
+	fsys := fstest.MapFS{
+		"go.mod":    {Data: []byte("module example.com/web\n")},
+		"routes.go": {Data: []byte(src)},
+		"pb/pb.go":  {Data: []byte("package pb\n\ntype UsersServer interface {\n\tGetUser()\n}\n\nfunc RegisterUsersServer(s interface{}, srv UsersServer) {}\n")},
+	}
+
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	want := map[string]string{
+		"List":       "HTTP GET /users (registered in routes.go:30)",
+		"listThings": "HTTP GET /things (registered in routes.go:31)",
+		"ServeHTTP":  "HTTP /api/ (registered in routes.go:35)",
+		"GetUser":    "gRPC Users/GetUser (registered in routes.go:36)",
+		"helper":     "",
+	}
+	var literal bool
+	for _, fn := range a.GetFunctions() {
+		var labels []string
+		for _, entry := range a.EntryPoints(fn) {
+			labels = append(labels, entry.String())
+		}
+		got := strings.Join(labels, "; ")
+		if strings.HasPrefix(fn.Name, "func(") {
+			literal = got == "HTTP POST /items (registered in routes.go:32)"
+			continue
+		}
+		if w, ok := want[fn.Name]; ok && got != w {
+			t.Errorf("%s has entry points %q, want %q", fn.Name, got, w)
+		}
+		if fn.Name == "List" || fn.Name == "listThings" {
+			if callers := a.GetCallersOf(fn); len(callers) != 0 {
+				t.Errorf("handler %s is called by %s, want no callers", fn.Name, callers[0].Caller.Name)
+			}
+		}
+	}
+	if !literal {
+		t.Error("function literal registered with http.HandleFunc has no entry point")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	src := `package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/urfave/cli/v2"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [flags]",
+	Short: "Serve",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return work()
+	},
+}
+
+func newMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "migrate"}
+	cmd.RunE = runMigrate
+	return cmd
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	return work()
+}
+
+func main() {
+	app := &cli.App{
+		Name: "tool",
+		Commands: []*cli.Command{
+			{Name: "sync", Action: syncAction},
+			{Name: "push", Action: func(c *cli.Context) error { return work() }},
+		},
+	}
+	_ = app
+	newMigrateCmd()
+}
+
+func syncAction(c *cli.Context) error {
+	return work()
+}
+
+func work() error { return nil }
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	var work *Function
+	for _, fn := range a.GetFunctions() {
+		if fn.Name == "work" {
+			work = fn
+		}
+	}
+	got := make(map[string]string)
+	for _, cs := range a.GetCallersOf(work) {
+		var labels []string
+		for _, entry := range a.EntryPoints(cs.Caller) {
+			labels = append(labels, entry.String())
+		}
+		callers := []string{}
+		for _, up := range a.GetCallersOf(cs.Caller) {
+			callers = append(callers, up.Caller.Name+" "+up.Kind)
+		}
+		got[strings.Join(labels, "; ")] = strings.Join(callers, ", ")
+	}
+	want := map[string]string{
+		"CLI serve (registered in main.go:8)":    "",
+		"CLI migrate (registered in main.go:18)": "newMigrateCmd command",
+		"CLI sync (registered in main.go:30)":    "main command",
+		"CLI push (registered in main.go:31)":    "main command",
+	}
+	for label, callers := range want {
+		if c, ok := got[label]; !ok || c != callers {
+			t.Errorf("handler %q has callers %q (found %v), want %q", label, c, ok, callers)
+		}
+	}
+}
+
+func TestEntryPatterns(t *testing.T) {
+	src := `package main
+
+type Job struct{}
+
+func (Job) Run() {
+	work()
+}
+
+func setup(c *cron.Cron, mux *asynq.ServeMux, bus *Bus) {
+	c.AddFunc("@every 1m", tick)
+	c.AddJob("0 3 * * *", &Job{})
+	mux.HandleFunc(TypeEmail, sendEmail)
+	bus.Subscribe("orders", onOrder)
+}
+
+func consume(r *kafka.Reader) {
+	for {
+		r.FetchMessage(ctx)
+		work()
+	}
+}
+
+func tick()      { work() }
+func sendEmail() { work() }
+func onOrder()   { work() }
+func work()      {}
+`
+	patterns, err := ParseEntryPatterns(strings.NewReader("# in-house bus\nbus *.Subscribe(topic, handler)\n"))
+	if err != nil {
+		t.Fatalf("ParseEntryPatterns: %v", err)
+	}
+	if _, err := ParseEntryPatterns(strings.NewReader("bus Subscribe(..., handler)\n")); err == nil {
+		t.Error("... before the last argument was accepted")
+	}
+
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}), WithEntryPatterns(patterns...))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	want := map[string]string{
+		"Run":       "cron 0 3 * * * (registered in main.go:11)",
+		"tick":      "cron @every 1m (registered in main.go:10)",
+		"sendEmail": "asynq TypeEmail (registered in main.go:12)",
+		"onOrder":   "bus orders (registered in main.go:13)",
+		"consume":   "Kafka r.FetchMessage (registered in main.go:18)",
+		"setup":     "",
+	}
+	for _, fn := range a.GetFunctions() {
+		var labels []string
+		for _, entry := range a.EntryPoints(fn) {
+			labels = append(labels, entry.String())
+		}
+		if got, w := strings.Join(labels, "; "), want[fn.Name]; got != w {
+			t.Errorf("%s has entry points %q, want %q", fn.Name, got, w)
+		}
+	}
+}
+
+func TestEdges(t *testing.T) {
+	src := `package main
+
+type Client struct{}
+
+func (c *Client) GetUser(id string) {}
+
+type Server struct{}
+
+func (s *Server) GetUser(id string) {
+	load()
+}
+
+type Handlers struct{}
+
+func (h *Handlers) HandleCreate() { load() }
+func (h *Handlers) HandleDelete() { load() }
+
+func Dispatch(msg string) {}
+
+func main() {
+	c := &Client{}
+	c.GetUser("x")
+	Dispatch("m")
+}
+
+func load() {}
+`
+	edges, err := ParseEdges(strings.NewReader(`# calls the analysis cannot see
+edges:
+  - caller: "func (c *Client) GetUser(id string)"
+    callee: Server.GetUser   # over gRPC
+    via: RPC
+  - caller: Dispatch
+    callee: '*.Handle*'
+    via: generated dispatcher
+`))
+	if err != nil {
+		t.Fatalf("ParseEdges: %v", err)
+	}
+	if len(edges) != 2 || edges[0].Callee != "Server.GetUser" || edges[1].Callee != "*.Handle*" || edges[1].Line != 6 {
+		t.Fatalf("ParseEdges returned %+v", edges)
+	}
+	if _, err := ParseEdges(strings.NewReader("- caller: main\n  calee: load\n")); err == nil {
+		t.Error("unknown key was accepted")
+	}
+
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	if err := a.AddEdges([]Edge{{Caller: "main", Callee: "Missing", Line: 3}}); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("AddEdges with an unknown callee returned %v", err)
+	}
+	if err := a.AddEdges(edges); err != nil {
+		t.Fatalf("AddEdges: %v", err)
+	}
+	got := make(map[string]string)
+	for _, fn := range a.GetFunctions() {
+		for _, cs := range a.GetCallersOf(fn) {
+			if cs.Kind == CallSynthetic {
+				got[cs.Caller.Name+" -> "+fn.Receiver+"."+fn.Name] = cs.Via
+			}
+		}
+	}
+	want := map[string]string{
+		"GetUser -> *Server.GetUser":         "RPC",
+		"Dispatch -> *Handlers.HandleCreate": "generated dispatcher",
+		"Dispatch -> *Handlers.HandleDelete": "generated dispatcher",
+	}
+	if len(got) != len(want) {
+		t.Errorf("synthetic calls are %v, want %v", got, want)
+	}
+	for edge, via := range want {
+		if got[edge] != via {
+			t.Errorf("%s via %q, want %q", edge, got[edge], via)
+		}
+	}
+}
+
+func TestStitchGRPC(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pb/users_grpc.pb.go": {Data: []byte(`// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+type UsersClient interface {
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*GetUserResponse, error)
+}
+
+type usersClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func (c *usersClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*GetUserResponse, error) {
+	out := new(GetUserResponse)
+	err := c.cc.Invoke(ctx, "/users.Users/GetUser", in, out, opts...)
+	return out, err
+}
+
+type UsersServer interface {
+	GetUser(context.Context, *GetUserRequest) (*GetUserResponse, error)
+	mustEmbedUnimplementedUsersServer()
+}
+
+type UnimplementedUsersServer struct{}
+
+func (UnimplementedUsersServer) GetUser(context.Context, *GetUserRequest) (*GetUserResponse, error) {
+	return nil, nil
+}
+func (UnimplementedUsersServer) mustEmbedUnimplementedUsersServer() {}
+`)},
+		"users/server.go": {Data: []byte(`package users
+
+import (
+	"context"
+
+	"example.com/pb"
+)
+
+type server struct {
+	pb.UnimplementedUsersServer
+}
+
+func (s *server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	return nil, nil
+}
+`)},
+	}
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	if n := a.StitchGRPC(); n != 1 {
+		t.Errorf("StitchGRPC added %d edges, want 1", n)
+	}
+
+	for _, fn := range a.GetFunctions() {
+		if fn.Name != "GetUser" {
+			continue
+		}
+		var callers []string
+		for _, cs := range a.GetCallersOf(fn) {
+			if cs.Kind == CallSynthetic && cs.Via == "gRPC" {
+				callers = append(callers, cs.Caller.Receiver)
+			}
+		}
+		want := ""
+		if fn.Receiver == "*server" {
+			want = "*usersClient"
+		}
+		if got := strings.Join(callers, ", "); got != want {
+			t.Errorf("gRPC callers of %s.GetUser are %q, want %q", fn.Receiver, got, want)
+		}
+	}
+}
+
+func TestStitchHTTP(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": {Data: []byte("module example.com/shop\n")},
+		"api/paths.go": {Data: []byte(`package api
+
+const UsersPath = "/users/"
+`)},
+		"users/server.go": {Data: []byte(`package users
+
+import "net/http"
+
+func Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /users/{id}", getUser)
+	mux.HandleFunc("GET /users/me", getMe)
+}
+
+func getUser(w http.ResponseWriter, r *http.Request) {}
+func getMe(w http.ResponseWriter, r *http.Request)   {}
+`)},
+		"orders/orders.go": {Data: []byte(`package orders
+
+import (
+	"fmt"
+	"net/http"
+
+	"example.com/shop/api"
+)
+
+var baseURL = "http://users:8080"
+
+func Owner(id string) {
+	http.Get(baseURL + api.UsersPath + id)
+}
+
+func Me(c *http.Client) {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/users/me?full=%t", baseURL, true), nil)
+	c.Do(req)
+}
+
+func Ship(c *http.Client) {
+	c.Post("http://shipping/shipments", "application/json", nil)
+}
+`)},
+		"shipping/shipping.go": {Data: []byte(`package shipping
+
+type Server struct{}
+
+func (s *Server) CreateShipment() {}
+`)},
+	}
+	routes, err := ParseRoutes(strings.NewReader(`openapi: 3.0.0
+info:
+  title: shipping
+paths:
+  /shipments:
+    parameters: []
+    post:
+      operationId: createShipment
+      responses: {}
+`))
+	if err != nil {
+		t.Fatalf("ParseRoutes: %v", err)
+	}
+	if len(routes) != 1 || routes[0].Method != "POST" || routes[0].Path != "/shipments" || routes[0].OperationID != "createShipment" {
+		t.Fatalf("ParseRoutes returned %+v", routes)
+	}
+	if _, err := ParseRoutes(strings.NewReader("FETCH /users Server.GetUser\n")); err == nil {
+		t.Error("unknown method was accepted")
+	}
+
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	if _, err := a.StitchHTTP([]Route{{Path: "/x", Handler: "Missing", Line: 2}}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("StitchHTTP with an unknown handler returned %v", err)
+	}
+	if _, err := a.StitchHTTP(routes); err != nil {
+		t.Fatalf("StitchHTTP: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, fn := range a.GetFunctions() {
+		for _, cs := range a.GetCallersOf(fn) {
+			if cs.Kind == CallSynthetic {
+				got[cs.Caller.Name+" -> "+fn.Name] = cs.Via
+			}
+		}
+	}
+	want := map[string]string{
+		"Owner -> getUser":       "HTTP GET /users/{id}",
+		"Me -> getMe":            "HTTP GET /users/me",
+		"Ship -> CreateShipment": "HTTP POST /shipments",
+	}
+	if len(got) != len(want) {
+		t.Errorf("synthetic calls are %v, want %v", got, want)
+	}
+	for edge, via := range want {
+		if got[edge] != via {
+			t.Errorf("%s via %q, want %q", edge, got[edge], via)
+		}
+	}
+}
+
+func TestWithFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":                  {Data: []byte("package main\n\nfunc main() { auth.Check() }\n")},
+		"internal/auth/auth.go":    {Data: []byte("package auth\n\nfunc Check() { verify() }\n\nfunc verify() {}\n")},
+		"internal/auth/jwt/jwt.go": {Data: []byte("package jwt\n\nfunc Parse() {}\n")},
+		"internal/store/store.go":  {Data: []byte("package store\n\nfunc Get() {}\n")},
+		"internal/authz/authz.go":  {Data: []byte("package authz\n\nfunc Allow() {}\n")},
+	}
+	a := NewAnalyzer(WithFS(fsys), WithFiles("internal/auth/**/*.go", "main.go"))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	var names []string
+	for _, fn := range a.GetFunctions() {
+		names = append(names, fn.Name)
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, " "), "Check Parse main verify"; got != want {
+		t.Errorf("functions are %s, want %s", got, want)
+	}
+
+	if err := NewAnalyzer(WithFS(fsys), WithFiles("[")).LoadPackages("."); err == nil {
+		t.Error("an invalid pattern was accepted")
+	}
+}
+
+func TestWithSourceFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ws/main.go":                     {Data: []byte("package main\n\nfunc main() { gen() }\n")},
+		"ws/unbuilt.go":                  {Data: []byte("package main\n\nfunc unbuilt() { gen() }\n")},
+		"ws/bazel-bin/gen.go":            {Data: []byte("package main\n\nfunc gen() {}\n")},
+		"cache/external/repo/lib/lib.go": {Data: []byte("package lib\n\nfunc Lib() {}\n")},
+	}
+	a := NewAnalyzer(WithFS(fsys), WithSourceFiles("ws/main.go", "ws/bazel-bin/gen.go", "cache/external/repo/lib/lib.go", "ws/missing.go"))
+	if err := a.LoadPackages("ws"); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	var names []string
+	for _, fn := range a.GetFunctions() {
+		names = append(names, fn.Name)
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, " "), "Lib gen main"; got != want {
+		t.Errorf("functions are %s, want %s", got, want)
+	}
+}
+
+// provideResolver links container.Provide(f) calls to f.
+type provideResolver struct{}
+
+func (provideResolver) Name() string { return "di" }
+
+func (provideResolver) Resolve(call ResolverCall) []Candidate {
+	sel, ok := call.Expr.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Provide" || len(call.Expr.Args) != 1 {
+		return nil
+	}
+	id, ok := call.Expr.Args[0].(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	fn, err := call.Analyzer.FindFunction("func " + id.Name + "() *Store")
+	if err != nil {
+		return nil
+	}
+	return []Candidate{{Callee: fn, Confidence: 0.9}}
+}
+
+func TestResolvers(t *testing.T) {
+	src := `package main
+
+type Store struct{}
+
+func NewStore() *Store { return &Store{} }
+
+func main() {
+	container.Provide(NewStore)
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}), WithResolvers(provideResolver{}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	fn, err := a.FindFunction("func NewStore() *Store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resolved []*CallSite
+	for _, cs := range a.GetCallersOf(fn) {
+		if cs.Kind == CallResolved {
+			resolved = append(resolved, cs)
+		}
+	}
+	if len(resolved) != 1 || resolved[0].Caller.Name != "main" || resolved[0].Via != "di" || resolved[0].Confidence != 0.9 || resolved[0].Line != 8 {
+		t.Errorf("resolved calls of NewStore are %+v", resolved)
+	}
+}
+
+func TestInjection(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": {Data: []byte("module example.com/app\n")},
+		"store/store.go": {Data: []byte(`package store
+
+import "github.com/google/wire"
+
+type Store struct{}
+
+func NewStore() *Store { return &Store{} }
+
+var Set = wire.NewSet(NewStore, wire.Bind(new(Getter), new(*Store)))
+`)},
+		"main.go": {Data: []byte(`package main
+
+import (
+	"example.com/app/store"
+	"go.uber.org/dig"
+	"go.uber.org/fx"
+	"github.com/google/wire"
+)
+
+func NewServer(s *store.Store) *Server { return &Server{} }
+
+func Register(s *Server) {}
+
+func initialize() *Server {
+	wire.Build(store.Set, NewServer)
+	return nil
+}
+
+func main() {
+	fx.New(fx.Provide(fx.Annotate(store.NewStore)), fx.Invoke(Register))
+	c := dig.New()
+	c.Provide(NewServer)
+}
+`)},
+	}
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	got := make(map[string]bool)
+	for _, fn := range a.GetFunctions() {
+		for _, cs := range a.GetCallersOf(fn) {
+			if cs.Kind == CallInjected {
+				got[cs.Caller.Name+" -> "+fn.Name+" via "+cs.Via] = true
+			}
+		}
+	}
+	set := a.GetCallersOf(&Function{Name: "Set", Package: "store", Line: 9})
+	if len(set) != 1 || set[0].Caller.Name != "initialize" || set[0].Via != "wire.Build" {
+		t.Errorf("callers of the provider set are %+v", set)
+	}
+	for _, want := range []string{
+		"Set -> NewStore via wire.NewSet",
+		"initialize -> NewServer via wire.Build",
+		"main -> NewStore via fx.Provide",
+		"main -> Register via fx.Invoke",
+		"main -> NewServer via c.Provide",
+	} {
+		if !got[want] {
+			t.Errorf("missing injected call %s, got %v", want, got)
+		}
+	}
+	if len(got) != 5 {
+		t.Errorf("injected calls are %v", got)
+	}
+}
+
+func TestMockExpectations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"store.go": {Data: []byte(`package store
+
+type Store interface {
+	Get(key string) string
+}
+
+type memStore struct{}
+
+func (m *memStore) Get(key string) string { return "" }
+`)},
+		"mocks/store.go": {Data: []byte(`// Code generated by MockGen. DO NOT EDIT.
+package mocks
+
+type MockStore struct{}
+
+type MockStoreMockRecorder struct{}
+
+func (m *MockStore) EXPECT() *MockStoreMockRecorder { return nil }
+
+func (m *MockStore) Get(key string) string { return "" }
+
+func (mr *MockStoreMockRecorder) Get(key interface{}) {}
+`)},
+		"service_test.go": {Data: []byte(`package store
+
+func TestLookup(t *testing.T) {
+	m := mocks.NewMockStore(ctrl)
+	m.EXPECT().Get("a").Return("b")
+}
+
+func TestTestify(t *testing.T) {
+	m := new(mocks.Store)
+	m.On("Get", "a").Return("b")
+}
+`)},
+	}
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	got := make(map[string]string)
+	for _, fn := range a.GetFunctions() {
+		if fn.Name == "Get" && fn.IsMock != (fn.Package == "mocks") {
+			t.Errorf("%s.Get has IsMock %v", fn.Receiver, fn.IsMock)
+		}
+		for _, cs := range a.GetCallersOf(fn) {
+			if cs.Kind == CallExpectation {
+				got[cs.Caller.Name+" -> "+fn.Receiver+"."+fn.Name] = cs.Via
+			}
+		}
+	}
+	want := map[string]string{
+		"TestLookup -> *MockStore.Get":  "m.EXPECT().Get",
+		"TestTestify -> *MockStore.Get": "m.On",
+	}
+	if len(got) != len(want) {
+		t.Errorf("expectations are %v, want %v", got, want)
+	}
+	for edge, via := range want {
+		if got[edge] != via {
+			t.Errorf("%s via %q, want %q", edge, got[edge], via)
+		}
+	}
+}
+
+func TestInlining(t *testing.T) {
+	fsys := fstest.MapFS{
+		"calc.go": {Data: []byte(`package calc
+
+func double(x int) int {
+	y := x * 2
+	return y
+}
+
+func square(x int) int { return x * x }
+
+func sign(x int) (s int) {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
+
+func fact(n int) int {
+	defer trace()
+	if n == 0 {
+		return 1
+	}
+	return n * fact(n-1)
+}
+
+func trace() {}
+
+func use() int {
+	a := double(1)
+	b := square(a) + double(2)
+	c := apply(square)
+	return b + c + sign(a)
+}
+
+func apply(f func(int) int) int { return f(1) }
+`)},
+	}
+	a := NewAnalyzer(WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	reasons := map[string]string{
+		"double": "",
+		"square": "",
+		"sign":   "several returns, named results",
+		"fact":   "several returns, defers calls, recursive",
+	}
+	sites := map[string]bool{}
+	for _, fn := range a.GetFunctions() {
+		want, ok := reasons[fn.Name]
+		if !ok {
+			continue
+		}
+		in, err := a.Inlining(fn)
+		if err != nil {
+			t.Fatalf("Inlining(%s): %v", fn.Name, err)
+		}
+		if got := strings.Join(in.Reasons, ", "); got != want {
+			t.Errorf("Inlining(%s) reasons are %q, want %q", fn.Name, got, want)
+		}
+		for _, cs := range a.GetCallersOf(fn) {
+			if cs.Caller.Name == "use" {
+				sites[fmt.Sprintf("%s:%d %s", fn.Name, cs.Line, a.InlineSite(cs, in))] = true
+			}
+		}
+	}
+	for _, want := range []string{
+		"double:28 ",
+		"double:29 used inside an expression",
+		"square:29 ",
+		"sign:31 used inside an expression",
+	} {
+		if !sites[want] {
+			t.Errorf("missing call site %q in %v", want, sites)
+		}
+	}
+}
+
+func TestWrappers(t *testing.T) {
+	src := `package store
+
+type Store struct{ db *DB }
+
+func (s *Store) Get(key string) (string, error) { return s.db.Get(key) }
+
+func (s *Store) Log(format string, args ...interface{}) { logf(format, args...) }
+
+func (s *Store) Swap(a, b string) { s.db.Put(b, a) }
+
+func (s *Store) Keys(prefix string) []string {
+	keys := s.db.Keys(prefix)
+	return keys
+}
+
+func (s *Store) Close() { s.db.Close() }
+
+func lookup(key string) string { return strings.ToLower(key) + "!" }
+
+func main() { run() }
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"store.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	want := map[string]bool{"Get": true, "Log": true, "Swap": false, "Keys": false, "Close": true, "lookup": false, "main": false}
+	for _, fn := range a.GetFunctions() {
+		if fn.IsWrapper != want[fn.Name] {
+			t.Errorf("%s has IsWrapper %v, want %v", fn.Name, fn.IsWrapper, want[fn.Name])
+		}
+	}
+}
+
+func TestCentrality(t *testing.T) {
+	src := `package app
+
+func handleA() { validate() }
+
+func handleB() { validate() }
+
+func handleC() { validate(); audit() }
+
+func validate() { check() }
+
+func audit() {}
+
+func check() {}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	scores := make(map[string]float64)
+	var total float64
+	for key, score := range a.Centrality() {
+		total += score
+		for _, fn := range a.GetFunctions() {
+			if fn.Key() == key {
+				scores[fn.Name] = score
+			}
+		}
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("Scores sum to %f, want 1", total)
+	}
+	// validate is called by three functions, audit by one
+	if scores["validate"] <= scores["audit"] {
+		t.Errorf("validate scores %f, want more than audit's %f", scores["validate"], scores["audit"])
+	}
+	if scores["audit"] <= scores["handleA"] {
+		t.Errorf("audit scores %f, want more than the uncalled handleA's %f", scores["audit"], scores["handleA"])
+	}
+	// check inherits the score of validate, its only caller
+	if scores["check"] <= scores["validate"] {
+		t.Errorf("check scores %f, want more than validate's %f", scores["check"], scores["validate"])
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	src := `package app
+
+func straight() int { return 1 }
+
+func branches(x int, ok bool) int {
+	if x > 0 && ok {
+		return 1
+	}
+	for i := 0; i < x; i++ {
+		switch {
+		case i == 2, i == 3:
+			return i
+		case i > 5 || !ok:
+			return -i
+		default:
+		}
+	}
+	go func() {
+		if ok {
+			x++
+		}
+	}()
+	return 0
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	// branches: if, &&, for, two cases and || (the literal counts apart)
+	want := map[string]int{"straight": 1, "branches": 7, "func(...) in app.go": 2}
+	for _, fn := range a.GetFunctions() {
+		if fn.Complexity != want[fn.Name] {
+			t.Errorf("%s has complexity %d, want %d", fn.Name, fn.Complexity, want[fn.Name])
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	src := `package app
+
+type T struct{ name string }
+
+func (t *T) Name() string { return t.name }
+
+func work(xs []int) (sum int) {
+	for _, x := range xs {
+		if x > 0 {
+			sum += x
+		} else {
+			sum--
+		}
+	}
+	defer func() {
+		sum *= 2
+		sum++
+	}()
+	return
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	// work: range, if, both assignments, defer and return
+	want := map[string][2]int{"Name": {1, 1}, "work": {6, 14}, "func(...) in app.go": {2, 4}}
+	for _, fn := range a.GetFunctions() {
+		if got := [2]int{fn.Statements, fn.Lines()}; got != want[fn.Name] {
+			t.Errorf("%s has %d statements over %d lines, want %v", fn.Name, got[0], got[1], want[fn.Name])
+		}
+	}
+}
+
+func TestArguments(t *testing.T) {
+	src := `package app
+
+const query = "SELECT 1"
+
+func run(sql string, args ...interface{}) {}
+
+func caller(n int, xs []interface{}) {
+	run("DROP TABLE users")
+	run(query, n, n+1)
+	run(query, xs...)
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	fn, err := a.FindFunction("run")
+	if err != nil {
+		t.Fatalf("FindFunction: %v", err)
+	}
+	var got []string
+	for _, cs := range a.GetCallersOf(fn) {
+		got = append(got, strings.Join(a.Arguments(cs), ", "))
+	}
+	sort.Strings(got)
+	want := []string{`"DROP TABLE users"`, "query, n, n + 1", "query, xs..."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Arguments = %q, want %q", got, want)
+	}
+}
+
+func TestArgumentConstants(t *testing.T) {
+	src := `package app
+
+import "strconv"
+
+const base = "rm"
+
+var mutable = "ls"
+
+func run(name string, n int) {}
+
+func caller(param string) {
+	cmd := base
+	flags := " -rf"
+	size := 2 * 512
+	retries := 3
+	retries++
+	run(cmd+flags, size)
+	run(mutable, retries)
+	run(param, -1)
+	run(strconv.Itoa(size), (4))
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	fn, err := a.FindFunction("run")
+	if err != nil {
+		t.Fatalf("FindFunction: %v", err)
+	}
+	var got []string
+	for _, cs := range a.GetCallersOf(fn) {
+		a.Arguments(cs)
+		got = append(got, fmt.Sprintf("%d:%q", cs.Line, cs.Constants))
+	}
+	sort.Strings(got)
+	want := []string{`17:["\"rm -rf\"" "1024"]`, `18:["" ""]`, `19:["" "-1"]`, `20:["" "4"]`}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Constants = %s, want %s", got, want)
+	}
+
+	if got := DescribeArgument("cmd", `"rm"`); got != `constant "rm"` {
+		t.Errorf("DescribeArgument = %q", got)
+	}
+	if got := DescribeArgument("cfg.Cmd", ""); got != "variable cfg.Cmd" {
+		t.Errorf("DescribeArgument = %q", got)
+	}
+}
+
+func TestUnsafeUses(t *testing.T) {
+	src := `package mem
+
+// #include <stdlib.h>
+import "C"
+
+import u "unsafe"
+
+func alloc(n int) u.Pointer { return C.malloc(C.size_t(n)) }
+
+func size() uintptr { return u.Sizeof(0) }
+
+func cast(b []byte) string {
+	return func() string { return *(*string)(u.Pointer(&b)) }()
+}
+
+func unsafeName(unsafe struct{ Pointer int }) int { return unsafe.Pointer }
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"mem.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	want := map[string]string{"alloc": "C.malloc C.size_t", "func(...) in mem.go": "unsafe.Pointer"}
+	for _, fn := range a.GetFunctions() {
+		if got := strings.Join(a.UnsafeUses(fn), " "); got != want[fn.Name] {
+			t.Errorf("%s uses %q, want %q", fn.Name, got, want[fn.Name])
+		}
+	}
+}
+
+func TestSuppressions(t *testing.T) {
+	src := `package app
+
+// legacy is kept for old clients.
+//gogotrace:ignore unreachable
+func legacy() {}
+
+func quiet() {} //gogotrace:ignore
+
+func run() {
+	legacy() //gogotrace:ignore trace reviewed
+	quiet()
+	//gogotrace:ignore
+	// called on purpose
+	legacy()
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	want := map[string]string{"legacy": "unreachable", "quiet": "panics trace unreachable unsafe", "run": ""}
+	for _, fn := range a.GetFunctions() {
+		var rules []string
+		for _, rule := range []string{RulePanics, RuleTrace, RuleUnreachable, RuleUnsafe} {
+			if a.Suppressed(fn, rule) {
+				rules = append(rules, rule)
+			}
+		}
+		if got := strings.Join(rules, " "); got != want[fn.Name] {
+			t.Errorf("%s suppresses %q, want %q", fn.Name, got, want[fn.Name])
+		}
+	}
+
+	var got []string
+	for _, callSites := range a.GetCallGraph() {
+		for _, cs := range callSites {
+			got = append(got, fmt.Sprintf("%d:%v/%v", cs.Line, a.SuppressedCall(cs, RuleTrace), a.SuppressedCall(cs, RuleUnsafe)))
+		}
+	}
+	sort.Strings(got)
+	if want := "10:true/false|11:false/false|14:true/true"; strings.Join(got, "|") != want {
+		t.Errorf("call sites = %s, want %s", strings.Join(got, "|"), want)
+	}
+}
+
+func TestClosureCalls(t *testing.T) {
+	src := `package app
+
+func Target() {}
+
+func outer() {
+	Target()
+	Target()
+	go func() {
+		Target()
+	}()
+	defer func() {
+		inner := func() { Target() }
+		inner()
+	}()
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	// Every call is a call site of its own, credited to the innermost
+	// function making it
+	sites, err := a.FindCallers("func Target()", false)
+	if err != nil {
+		t.Fatalf("FindCallers: %v", err)
+	}
+	var got []string
+	for _, cs := range sites {
+		got = append(got, fmt.Sprintf("%s@%d:%d", strings.Fields(cs.Caller.Name)[0], cs.Caller.Line, cs.Line))
+	}
+	sort.Strings(got)
+	if want := "func(...)@12:12|func(...)@8:9|outer@5:6|outer@5:7"; strings.Join(got, "|") != want {
+		t.Errorf("call sites = %s, want %s", strings.Join(got, "|"), want)
+	}
+
+	// The enclosing function still reaches the calls through its closures
+	target, err := a.FindFunction("func Target()")
+	if err != nil {
+		t.Fatalf("FindFunction: %v", err)
+	}
+	var callers []string
+	for _, fn := range a.TransitiveCallers(target, false) {
+		callers = append(callers, fmt.Sprintf("%s@%d", strings.Fields(fn.Name)[0], fn.Line))
+	}
+	sort.Strings(callers)
+	if want := "func(...)@11|func(...)@12|func(...)@8|outer@5"; strings.Join(callers, "|") != want {
+		t.Errorf("transitive callers = %s, want %s", strings.Join(callers, "|"), want)
+	}
+}
+
+func TestPanicsAndRecovers(t *testing.T) {
+	src := `package app
+
+func Panics() { panic("boom") }
+
+func Recovers() {
+	defer func() {
+		if r := recover(); r != nil {
+			println(r)
+		}
+	}()
+	Panics()
+}
+
+func DefersRecoverDirectly() {
+	defer recover()
+	panic("not stopped")
+}
+
+func RecoversOutsideDefer() {
+	f := func() { recover() }
+	f()
+	panic("not stopped")
+}
+
+func PanicsInClosure() {
+	go func() { panic("in the literal") }()
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		panics, recovers bool
+	}{
+		{"Panics", true, false},
+		{"Recovers", false, true},
+		{"DefersRecoverDirectly", true, false},
+		{"RecoversOutsideDefer", true, false},
+		// The panic is the literal's, which PanicsInClosure only starts
+		{"PanicsInClosure", false, false},
+	}
+	for _, tt := range tests {
+		fn, err := a.FindFunction("func " + tt.name + "()")
+		if err != nil {
+			t.Fatalf("FindFunction: %v", err)
+		}
+		if got := a.Panics(fn); got != tt.panics {
+			t.Errorf("Panics(%s) = %v, want %v", tt.name, got, tt.panics)
+		}
+		if got := a.Recovers(fn); got != tt.recovers {
+			t.Errorf("Recovers(%s) = %v, want %v", tt.name, got, tt.recovers)
+		}
+	}
+	literalPanics := false
+	for _, fn := range a.GetFunctions() {
+		if strings.HasPrefix(fn.Name, "func(") && fn.Line == 26 {
+			literalPanics = a.Panics(fn)
+		}
+	}
+	if !literalPanics {
+		t.Errorf("the literal of PanicsInClosure does not panic")
+	}
+}
+
+func TestIsExported(t *testing.T) {
+	tests := []struct {
+		fn   Function
+		want bool
+	}{
+		{Function{Name: "Load"}, true},
+		{Function{Name: "load"}, false},
+		{Function{Name: "Run", Receiver: "*Server"}, true},
+		{Function{Name: "Run", Receiver: "server"}, false},
+		{Function{Name: "run", Receiver: "Server"}, false},
+		{Function{Name: "Get", Receiver: "*Cache[K, V]"}, true},
+		{Function{Name: "Get", Receiver: "cache[K]"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.fn.IsExported(); got != tt.want {
+			t.Errorf("(%s).%s: IsExported = %v, want %v", tt.fn.Receiver, tt.fn.Name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyTest(t *testing.T) {
+	tests := []struct {
+		decl string
+		want string
+	}{
+		{"func Test(t *testing.T)", TestKindTest},
+		{"func TestLoad(t *testing.T)", TestKindTest},
+		{"func Test_load(t *testing.T)", TestKindTest},
+		{"func Testify(t *testing.T)", TestKindHelper},
+		{"func TestLoad(m *testing.M)", TestKindHelper},
+		{"func TestMain(m *testing.M)", TestKindTest},
+		{"func TestLoad(t *testing.T, n int)", TestKindHelper},
+		{"func TestLoad(t testing.T)", TestKindHelper},
+		{"func (s *suite) TestLoad(t *testing.T)", TestKindHelper},
+		{"func BenchmarkLoad(b *testing.B)", TestKindBenchmark},
+		{"func BenchmarkLoad(t *testing.T)", TestKindHelper},
+		{"func Benchmarking(b *testing.B)", TestKindHelper},
+		{"func FuzzParse(f *testing.F)", TestKindFuzz},
+		{"func Fuzzy(f *testing.F)", TestKindHelper},
+		{"func Example()", TestKindExample},
+		{"func ExampleLoad_second()", TestKindExample},
+		{"func ExampleLoad(t *testing.T)", TestKindHelper},
+		{"func ExampleLoad() error", TestKindHelper},
+		{"func Examples()", TestKindHelper},
+		{"func helper(t *testing.T)", TestKindHelper},
+	}
+	for _, tt := range tests {
+		file, err := parser.ParseFile(token.NewFileSet(), "x_test.go", "package x\n\n"+tt.decl+" {}\n", 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.decl, err)
+		}
+		if got := classifyTest(file.Decls[0].(*ast.FuncDecl)); got != tt.want {
+			t.Errorf("%s: classifyTest = %q, want %q", tt.decl, got, tt.want)
+		}
+	}
+}
+
+func TestCallKinds(t *testing.T) {
+	src := `package main
+
+type Store interface{ Save() }
+
+type memStore struct{}
+
+func (m *memStore) Save() {}
+
+func (m *memStore) Flush() {}
+
+func work() {}
+
+func register(f func()) {}
+
+func run(store Store, m *memStore) {
+	work()
+	go work()
+	defer work()
+	register(m.Flush)
+	m.Flush()
+	store.Save()
+	register(func() {})
+}
+`
+	a := NewAnalyzer(WithFS(fstest.MapFS{"main.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	var kinds []string
+	for _, callSites := range a.GetCallGraph() {
+		for _, cs := range callSites {
+			if cs.Caller.Name == "run" {
+				kinds = append(kinds, fmt.Sprintf("%d:%s", cs.Line, cs.Kind))
+			}
+		}
+	}
+	sort.Strings(kinds)
+	want := []string{"16:direct", "17:go", "18:defer", "19:callback", "19:direct", "20:method", "21:interface", "22:callback", "22:direct"}
+	if strings.Join(kinds, " ") != strings.Join(want, " ") {
+		t.Errorf("call kinds = %v, want %v", kinds, want)
+	}
+}
@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"path/filepath"
+	"strings"
+)
+
+// maxArgumentLength bounds the text of an argument, so that a long SQL
+// string or function literal does not swamp the report.
+const maxArgumentLength = 120
+
+// Arguments returns the argument expressions of the call cs as written in
+// the caller, such as "5", "n" or `"SELECT * FROM users"`, and records them
+// in cs.Args. The values of those known statically, through literals,
+// constants and local variables assigned once, go to cs.Constants. It
+// returns nil when the call cannot be found, e.g. for method values passed
+// as callbacks.
+func (a *Analyzer) Arguments(cs *CallSite) []string {
+	if cs.Args != nil {
+		return cs.Args
+	}
+	filePath := filepath.Join(a.baseDir, cs.Caller.FullPath)
+	pf, err := a.parsedSource(filePath)
+	if err != nil {
+		return nil
+	}
+
+	// Find the call and the functions around it
+	var stack []ast.Node
+	var call *ast.CallExpr
+	scope := argumentScope{params: make(map[string]bool), pkg: cs.Caller.Package, filePath: filePath}
+	ast.Inspect(pf.file, func(n ast.Node) bool {
+		if call != nil {
+			return false
+		}
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if c, ok := n.(*ast.CallExpr); ok && a.callsAt(pf.fset, c, cs) {
+			call = c
+			for _, outer := range stack {
+				var fields []*ast.FieldList
+				switch fn := outer.(type) {
+				case *ast.FuncDecl:
+					fields = []*ast.FieldList{fn.Recv, fn.Type.Params, fn.Type.Results}
+					scope.body = fn.Body
+				case *ast.FuncLit:
+					fields = []*ast.FieldList{fn.Type.Params, fn.Type.Results}
+					if scope.body == nil {
+						scope.body = fn.Body
+					}
+				}
+				for _, list := range fields {
+					if list == nil {
+						continue
+					}
+					for _, field := range list.List {
+						for _, name := range field.Names {
+							scope.params[name.Name] = true
+						}
+					}
+				}
+			}
+			return false
+		}
+		stack = append(stack, n)
+		return true
+	})
+	if call == nil {
+		return nil
+	}
+
+	args := make([]string, 0, len(call.Args))
+	constants := make([]string, 0, len(call.Args))
+	for i, arg := range call.Args {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, pf.fset, arg); err != nil {
+			return nil
+		}
+		text := shortArgument(buf.String())
+		value := ""
+		if i == len(call.Args)-1 && call.Ellipsis.IsValid() {
+			text += "..."
+		} else if v, ok := a.constantValue(arg, scope, 0); ok {
+			value = shortArgument(v.ExactString())
+		}
+		args = append(args, text)
+		constants = append(constants, value)
+	}
+	cs.Args, cs.Constants = args, constants
+	return args
+}
+
+// shortArgument cuts the text of an argument to its first line and to
+// maxArgumentLength.
+func shortArgument(text string) string {
+	// Multi-line arguments such as function literals keep their first
+	// line
+	text, _, cut := strings.Cut(text, "\n")
+	if runes := []rune(text); len(runes) > maxArgumentLength {
+		text, cut = string(runes[:maxArgumentLength]), true
+	}
+	if cut {
+		text += " ..."
+	}
+	return text
+}
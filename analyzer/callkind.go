@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// Ways a call site reaches its callee, as recorded in CallSite.Kind.
+const (
+	CallDirect      = "direct"      // f() or an immediately invoked literal
+	CallMethod      = "method"      // x.M() with x matched to the receiver type
+	CallGo          = "go"          // go f()
+	CallDefer       = "defer"       // defer f()
+	CallCallback    = "callback"    // x.M or func(){...} handed over to be called later
+	CallInterface   = "interface"   // x.M() where x is a parameter of interface type
+	CallHeuristic   = "heuristic"   // callee guessed among several candidates
+	CallCommand     = "command"     // handler of a cobra or urfave/cli command declared by the caller
+	CallSynthetic   = "synthetic"   // declared by hand with AddEdges
+	CallResolved    = "resolved"    // found by a CallResolver
+	CallInjected    = "injected"    // constructor or function handed to a wire, fx or dig container
+	CallExpectation = "expectation" // m.EXPECT().M() or m.On("M") in a test, on a mock's M
+)
+
+// as returns the context with the call kind set, unless the call is
+// already known to be made by a go or defer statement.
+func (c callContext) as(kind string) callContext {
+	if c.kind == "" {
+		c.kind = kind
+	}
+	return c
+}
+
+// callKinds finds the calls of body made by go and defer statements, and
+// the kind of every function literal that is invoked on the spot. Literals
+// missing from the result are stored or passed on, i.e. callbacks.
+func callKinds(body *ast.BlockStmt) map[ast.Node]string {
+	kinds := make(map[ast.Node]string)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.GoStmt:
+			kinds[node.Call] = CallGo
+		case *ast.DeferStmt:
+			kinds[node.Call] = CallDefer
+		case *ast.CallExpr:
+			if lit, ok := node.Fun.(*ast.FuncLit); ok {
+				kind := kinds[node]
+				if kind == "" {
+					kind = CallDirect
+				}
+				kinds[lit] = kind
+			}
+		}
+		return true
+	})
+	// Handlers of the CLI commands declared in body
+	for _, cmd := range findCommands(body) {
+		for _, h := range cmd.handlers {
+			if lit, ok := h.(*ast.FuncLit); ok {
+				kinds[lit] = CallCommand
+			}
+		}
+	}
+	return kinds
+}
+
+// interfaceParam reports whether name is a parameter of caller whose type
+// is an interface declared in the analyzed code.
+func (a *Analyzer) interfaceParam(caller *Function, name string) bool {
+	typ, ok := paramType(caller, name)
+	if !ok {
+		return false
+	}
+	// Drop a package qualifier: io.Reader is looked up as Reader
+	if i := strings.LastIndex(typ, "."); i >= 0 {
+		typ = typ[i+1:]
+	}
+	_, ok = a.interfaces.Load(typ)
+	return ok
+}
+
+// paramType returns the type of caller's parameter called name.
+func paramType(caller *Function, name string) (string, bool) {
+	params := strings.TrimSuffix(strings.TrimPrefix(caller.Parameters, "("), ")")
+	for _, param := range strings.Split(params, ", ") {
+		paramName, typ, ok := strings.Cut(param, " ")
+		if ok && paramName == name {
+			return typ, true
+		}
+	}
+	return "", false
+}
+
+// dispatchTargets returns the implementations of method that a call
+// name.method() can reach when name is a parameter of caller with an
+// interface type, or nil when they cannot be determined.
+func (a *Analyzer) dispatchTargets(caller *Function, name, method string) []*Function {
+	typ, ok := paramType(caller, name)
+	if !ok {
+		return nil
+	}
+	key := caller.Package + "\x00" + typ + "\x00" + method
+	if targets, ok := a.dispatch.Load(key); ok {
+		return targets.([]*Function)
+	}
+
+	var targets []*Function
+	if iface, err := a.lookupInterface(typ, caller.Package); err == nil {
+		iface = a.resolve(iface, map[*Interface]bool{})
+		if _, ok := iface.Methods[method]; ok {
+			for _, impl := range a.Implementations(iface) {
+				for _, fn := range impl.Methods {
+					if fn.Name == method {
+						targets = append(targets, fn)
+					}
+				}
+			}
+		}
+	}
+	a.dispatch.Store(key, targets)
+	return targets
+}
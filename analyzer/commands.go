@@ -0,0 +1,260 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// commandType describes a CLI command struct: the field naming the command
+// and the fields holding the functions it runs.
+type commandType struct {
+	name     string
+	handlers map[string]bool
+}
+
+// commandTypes are the command structs of cobra and urfave/cli, by type
+// as written with the usual package names.
+var commandTypes = map[string]commandType{
+	"cobra.Command": {name: "Use", handlers: map[string]bool{"Run": true, "RunE": true}},
+	"cli.Command":   {name: "Name", handlers: map[string]bool{"Action": true}},
+	"cli.App":       {name: "Name", handlers: map[string]bool{"Action": true}},
+}
+
+// command is a CLI command declared in the code and the handlers it runs.
+type command struct {
+	name     string
+	pos      token.Pos
+	handlers []ast.Expr
+}
+
+// findCommands returns the commands declared in node, as composite
+// literals such as &cobra.Command{Use: "serve", RunE: runServe} or as
+// elements of a []*cli.Command, and the handlers later assigned to a
+// command variable, as in cmd.RunE = runServe. Function literals other
+// than handlers are not searched.
+func findCommands(node ast.Node) []command {
+	var commands []command
+	names := make(map[string]string)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CompositeLit:
+			if ct, ok := commandTypes[typeString(n.Type)]; ok {
+				commands = append(commands, commandLiteral(n, ct))
+				return true
+			}
+			// Elements of []*cli.Command{{Name: ...}} omit their type
+			arr, ok := n.Type.(*ast.ArrayType)
+			if !ok {
+				return true
+			}
+			ct, ok := commandTypes[strings.TrimPrefix(typeString(arr.Elt), "*")]
+			if !ok {
+				return true
+			}
+			for _, elt := range n.Elts {
+				if u, ok := elt.(*ast.UnaryExpr); ok && u.Op == token.AND {
+					elt = u.X
+				}
+				if lit, ok := elt.(*ast.CompositeLit); ok && lit.Type == nil {
+					commands = append(commands, commandLiteral(lit, ct))
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				if i >= len(n.Rhs) {
+					break
+				}
+				if id, ok := lhs.(*ast.Ident); ok {
+					if name := commandName(n.Rhs[i]); name != "" {
+						names[id.Name] = name
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, id := range n.Names {
+				if i < len(n.Values) {
+					if name := commandName(n.Values[i]); name != "" {
+						names[id.Name] = name
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	// Assignments are matched once every command variable is known
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				sel, ok := lhs.(*ast.SelectorExpr)
+				if !ok || i >= len(n.Rhs) {
+					continue
+				}
+				x, ok := sel.X.(*ast.Ident)
+				if !ok || !isCommandHandlerField(sel.Sel.Name) {
+					continue
+				}
+				name, ok := names[x.Name]
+				if !ok {
+					continue
+				}
+				commands = append(commands, command{name: name, pos: n.Pos(), handlers: []ast.Expr{n.Rhs[i]}})
+			}
+		}
+		return true
+	})
+	return commands
+}
+
+// commandLiteral returns the command declared by lit, of type ct.
+func commandLiteral(lit *ast.CompositeLit, ct commandType) command {
+	cmd := command{pos: lit.Pos()}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		switch {
+		case key.Name == ct.name:
+			cmd.name = commandUse(kv.Value)
+		case ct.handlers[key.Name]:
+			cmd.handlers = append(cmd.handlers, kv.Value)
+		}
+	}
+	if cmd.name == "" {
+		cmd.name = "(unnamed)"
+	}
+	return cmd
+}
+
+// commandName returns the name of the command declared by expr, or "" when
+// it does not declare one.
+func commandName(expr ast.Expr) string {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = u.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return ""
+	}
+	ct, ok := commandTypes[typeString(lit.Type)]
+	if !ok {
+		return ""
+	}
+	return commandLiteral(lit, ct).name
+}
+
+// commandUse returns the command name in a Use or Name field: the first
+// word of a string literal such as "serve [flags]".
+func commandUse(expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	use, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(use)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// isCommandHandlerField reports whether name is a handler field of one of
+// the commandTypes.
+func isCommandHandlerField(name string) bool {
+	for _, ct := range commandTypes {
+		if ct.handlers[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// typeString returns a type written as an identifier or a qualified
+// identifier, such as "cobra.Command", or "".
+func typeString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			return x.Name + "." + t.Sel.Name
+		}
+	case *ast.StarExpr:
+		if s := typeString(t.X); s != "" {
+			return "*" + s
+		}
+	}
+	return ""
+}
+
+// recordCommands links caller to the handlers of the CLI commands declared
+// in body, with CallCommand call sites, and records each handler's
+// command as an entry point. Function literal handlers are linked by the
+// walk of the body, see callKinds.
+func (a *Analyzer) recordCommands(fset *token.FileSet, body ast.Node, caller *Function, localFuncs []*Function) {
+	for _, cmd := range findCommands(body) {
+		at := EntryPoint{Kind: EntryCLI, Route: cmd.name, File: caller.FullPath, Line: fset.Position(cmd.pos).Line}
+		for _, h := range cmd.handlers {
+			if lit, ok := h.(*ast.FuncLit); ok {
+				a.addEntryPoint(a.createAnonymousFunction(fset, lit, caller), at)
+				continue
+			}
+			for _, fn := range a.handlerFunctions(fset, h, caller, localFuncs) {
+				a.addEntryPoint(fn, at)
+				a.addCallSite(caller, fn, siteAt(fset, nil, h.Pos()).as(CallCommand))
+			}
+		}
+	}
+}
+
+// recordPackageCommands handles the CLI commands declared by package
+// variables, as in var serveCmd = &cobra.Command{...}. Their handlers are
+// recorded as entry points without a caller; function literal handlers
+// become functions of their own, named after the variable, whose calls are
+// analyzed.
+func (a *Analyzer) recordPackageCommands(fset *token.FileSet, file *ast.File, packagePath, relPath string, localFuncs []*Function) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			commands := findCommands(vs)
+			if len(commands) == 0 {
+				continue
+			}
+			holder := a.packageVarHolder(fset, vs, packagePath, relPath)
+
+			for _, cmd := range commands {
+				at := EntryPoint{Kind: EntryCLI, Route: cmd.name, File: relPath, Line: fset.Position(cmd.pos).Line}
+				for _, h := range cmd.handlers {
+					if lit, ok := h.(*ast.FuncLit); ok {
+						anon := a.createAnonymousFunction(fset, lit, holder)
+						a.addEntryPoint(anon, at)
+						a.analyzeAnonFunctionBody(fset, lit, anon, localFuncs)
+						continue
+					}
+					for _, fn := range a.handlerFunctions(fset, h, holder, localFuncs) {
+						a.addEntryPoint(fn, at)
+					}
+				}
+			}
+		}
+	}
+}
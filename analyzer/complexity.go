@@ -0,0 +1,39 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// cyclomatic counts the decision points of body plus one, as gocyclo
+// does, while the body is walked at parse time: each if, for, range, non-default case and comm clause, and each
+// && and ||. Function literals are functions of their own and are not
+// counted.
+func cyclomatic(body *ast.BlockStmt) int {
+	complexity := 1
+	if body == nil {
+		return complexity
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
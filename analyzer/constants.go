@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"strings"
+)
+
+// maxConstantDepth bounds the chains of constants and assignments
+// followed, against cycles such as x := y; y := x.
+const maxConstantDepth = 8
+
+// argumentScope is what an argument expression is resolved in: the
+// outermost function around the call, the parameters of the functions
+// around it, and the caller's package and file.
+type argumentScope struct {
+	body     *ast.BlockStmt
+	params   map[string]bool
+	pkg      string
+	filePath string
+}
+
+// constantValue returns the value of expr when it is known statically: a
+// literal, a const of the package or of an imported one, a local variable
+// assigned once from a constant, or an arithmetic or concatenation of
+// those. The value is written as Go source, strings quoted.
+func (a *Analyzer) constantValue(expr ast.Expr, scope argumentScope, depth int) (constant.Value, bool) {
+	if depth > maxConstantDepth {
+		return nil, false
+	}
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		return v, v.Kind() != constant.Unknown
+	case *ast.ParenExpr:
+		return a.constantValue(e.X, scope, depth)
+	case *ast.UnaryExpr:
+		if e.Op != token.SUB && e.Op != token.ADD {
+			return nil, false
+		}
+		x, ok := a.constantValue(e.X, scope, depth)
+		if !ok || x.Kind() == constant.String {
+			return nil, false
+		}
+		return constant.UnaryOp(e.Op, x, 0), true
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD && e.Op != token.SUB && e.Op != token.MUL {
+			return nil, false
+		}
+		x, ok := a.constantValue(e.X, scope, depth)
+		if !ok {
+			return nil, false
+		}
+		y, ok := a.constantValue(e.Y, scope, depth)
+		if !ok || (x.Kind() == constant.String) != (y.Kind() == constant.String) {
+			return nil, false
+		}
+		if x.Kind() == constant.String && e.Op != token.ADD {
+			return nil, false
+		}
+		return constant.BinaryOp(x, e.Op, y), true
+	case *ast.Ident:
+		if scope.params[e.Name] {
+			return nil, false
+		}
+		if value, assigned := localAssignment(scope.body, e.Name); assigned {
+			if value == nil {
+				return nil, false
+			}
+			return a.constantValue(value, scope, depth+1)
+		}
+		if c, ok := a.constants.Load(scope.pkg + "." + e.Name); ok {
+			return a.declaredConstant(c.(constantDecl), depth)
+		}
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		imports, ok := a.imports.Load(scope.filePath)
+		if !ok {
+			break
+		}
+		if importPath, ok := imports.(map[string]string)[x.Name]; ok {
+			if c, ok := a.constants.Load(importPath + "." + e.Sel.Name); ok {
+				return a.declaredConstant(c.(constantDecl), depth)
+			}
+		}
+	}
+	return nil, false
+}
+
+// declaredConstant resolves the value of a package-level const; package
+// variables may be reassigned anywhere and are not constant.
+func (a *Analyzer) declaredConstant(c constantDecl, depth int) (constant.Value, bool) {
+	if !c.isConst {
+		return nil, false
+	}
+	return a.constantValue(c.value, argumentScope{pkg: c.pkg, filePath: c.filePath}, depth+1)
+}
+
+// localAssignment looks for the declarations and assignments of name in
+// body. It reports whether there are any, and returns the value assigned
+// when there is exactly one, a definition or var or const declaration
+// with a value. Its address being taken counts as an assignment.
+func localAssignment(body *ast.BlockStmt, name string) (value ast.Expr, assigned bool) {
+	if body == nil {
+		return nil, false
+	}
+	count := 0
+	single := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range s.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && id.Name == name {
+					count++
+					value, single = nil, false
+					if s.Tok == token.DEFINE && len(s.Lhs) == len(s.Rhs) {
+						value, single = s.Rhs[i], true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, id := range s.Names {
+				if id.Name == name {
+					count++
+					value, single = nil, false
+					if len(s.Names) == len(s.Values) {
+						value, single = s.Values[i], true
+					}
+				}
+			}
+		case *ast.IncDecStmt:
+			if id, ok := s.X.(*ast.Ident); ok && id.Name == name {
+				count++
+			}
+		case *ast.RangeStmt:
+			for _, x := range []ast.Expr{s.Key, s.Value} {
+				if id, ok := x.(*ast.Ident); ok && id.Name == name {
+					count++
+				}
+			}
+		case *ast.UnaryExpr:
+			if id, ok := s.X.(*ast.Ident); ok && s.Op == token.AND && id.Name == name {
+				count++
+			}
+		}
+		return true
+	})
+	if count == 1 && single {
+		return value, true
+	}
+	return nil, count > 0
+}
+
+// DescribeArgument tells how an argument of Args is passed, given its
+// entry in Constants: as in `constant "rm"`, `variable cmd` or
+// `expression f(x)`.
+func DescribeArgument(arg, value string) string {
+	switch {
+	case value != "":
+		return "constant " + value
+	case isReference(arg):
+		return "variable " + arg
+	default:
+		return "expression " + arg
+	}
+}
+
+// isReference reports whether arg is an identifier or a chain of field
+// selections such as cfg.Cmd.
+func isReference(arg string) bool {
+	for _, part := range strings.Split(arg, ".") {
+		if !token.IsIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
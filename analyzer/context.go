@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// TakesContext reports whether fn has a context.Context parameter.
+func (fn *Function) TakesContext() bool {
+	return strings.Contains(fn.Parameters, "context.Context")
+}
+
+// freshContextArgs finds the calls of body that are passed a context made
+// by context.Background or context.TODO, either directly or through a
+// variable assigned from one, and maps each to the line of the constructor.
+// Function literals are skipped: their calls are recorded on their own.
+func freshContextArgs(fset *token.FileSet, body *ast.BlockStmt) map[ast.Node]int {
+	// Variables holding a fresh context, by name
+	vars := make(map[string]int)
+	assign := func(names []*ast.Ident, values []ast.Expr) {
+		for i, value := range values {
+			line := freshContextIn(fset, value, nil)
+			if line == 0 {
+				continue
+			}
+			// ctx, cancel := context.WithCancel(context.Background())
+			if len(values) == 1 {
+				i = 0
+			}
+			if i < len(names) && names[i].Name != "_" {
+				vars[names[i].Name] = line
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			var names []*ast.Ident
+			for _, lhs := range node.Lhs {
+				ident, _ := lhs.(*ast.Ident)
+				if ident == nil {
+					ident = ast.NewIdent("_")
+				}
+				names = append(names, ident)
+			}
+			assign(names, node.Rhs)
+		case *ast.ValueSpec:
+			assign(node.Names, node.Values)
+		}
+		return true
+	})
+
+	fresh := make(map[ast.Node]int)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if isContextConstructor(node) {
+				return true
+			}
+			for _, arg := range node.Args {
+				if line := freshContextIn(fset, arg, vars); line != 0 {
+					fresh[node] = line
+					break
+				}
+			}
+		}
+		return true
+	})
+	return fresh
+}
+
+// freshContextIn returns the line of the first context.Background or TODO
+// call in expr, either made there or behind a variable of vars it uses.
+func freshContextIn(fset *token.FileSet, expr ast.Expr, vars map[string]int) int {
+	line := 0
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if line != 0 {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if isContextConstructor(node) {
+				line = fset.Position(node.Pos()).Line
+			}
+		case *ast.SelectorExpr:
+			// s.ctx is a field, not the local ctx
+			line = freshContextIn(fset, node.X, vars)
+			return false
+		case *ast.Ident:
+			line = vars[node.Name]
+		}
+		return true
+	})
+	return line
+}
+
+func isContextConstructor(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "context" && (sel.Sel.Name == "Background" || sel.Sel.Name == "TODO")
+}
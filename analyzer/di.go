@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+// injectionFuncs are the functions of google/wire and uber fx taking the
+// constructors and functions a dependency injection container calls, by
+// package name.
+var injectionFuncs = map[string]map[string]bool{
+	"wire": {"NewSet": true, "Build": true},
+	"fx":   {"Provide": true, "Invoke": true, "Decorate": true},
+}
+
+// injectionMethods are the methods of a uber dig container, or of an fx
+// module's, taking the functions it calls.
+var injectionMethods = map[string]bool{"Provide": true, "Invoke": true, "Decorate": true}
+
+// isInjection reports whether call hands functions over to a dependency
+// injection container.
+func isInjection(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	if x, ok := sel.X.(*ast.Ident); ok {
+		if funcs, ok := injectionFuncs[x.Name]; ok {
+			return funcs[sel.Sel.Name]
+		}
+	}
+	return injectionMethods[sel.Sel.Name]
+}
+
+// recordInjection links caller to the constructors and functions that call
+// hands over to a dependency injection container, as in
+// fx.Provide(NewStore), wire.NewSet(NewStore) or c.Invoke(Register), with
+// CallInjected calls labeled with the container function. Provider sets
+// declared by package variables, as in wire.Build(StoreSet), are linked
+// the same way. Function literals are left to the walk of the body, which
+// links them as callbacks. It returns the arguments linked.
+func (a *Analyzer) recordInjection(fset *token.FileSet, locks []lockRegion, call *ast.CallExpr, caller *Function, localFuncs []*Function) map[ast.Expr]bool {
+	if !isInjection(call) {
+		return nil
+	}
+	filePath := fset.Position(call.Pos()).Filename
+	injected := make(map[ast.Expr]bool)
+	for _, arg := range call.Args {
+		target := arg
+		// fx.Annotate(NewStore, fx.As(new(Store))) annotates its first argument
+		if inner, ok := arg.(*ast.CallExpr); ok && types.ExprString(inner.Fun) == "fx.Annotate" && len(inner.Args) > 0 {
+			target = inner.Args[0]
+		}
+		if _, ok := target.(*ast.FuncLit); ok {
+			continue
+		}
+
+		pos := siteAt(fset, locks, target.Pos())
+		pos.kind, pos.via = CallInjected, types.ExprString(call.Fun)
+		functions := a.handlerFunctions(fset, target, caller, localFuncs)
+		if set := a.providerSet(target, caller, filePath); set != nil {
+			functions = append(functions, set)
+		}
+		for _, fn := range functions {
+			a.addCallSite(caller, fn, pos)
+			injected[arg] = true
+		}
+	}
+	return injected
+}
+
+// providerSet returns the holder of the package variable expr refers to,
+// as StoreSet or store.Set, when it declares a provider set or module.
+func (a *Analyzer) providerSet(expr ast.Expr, caller *Function, filePath string) *Function {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if set, ok := a.providerSets.Load(caller.Package + "." + e.Name); ok {
+			return set.(*Function)
+		}
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		imports, ok := a.imports.Load(filePath)
+		if !ok {
+			return nil
+		}
+		if importPath, ok := imports.(map[string]string)[x.Name]; ok {
+			if set, ok := a.providerSets.Load(importPath + "." + e.Sel.Name); ok {
+				return set.(*Function)
+			}
+		}
+	}
+	return nil
+}
+
+// recordProviderSets remembers the package variables of file whose value
+// hands functions over to a dependency injection container, as in
+// var StoreSet = wire.NewSet(NewStore) or var Module = fx.Options(...),
+// each as a function named after the variable, by package path and, with a
+// go.mod, import path.
+func (a *Analyzer) recordProviderSets(fset *token.FileSet, file *ast.File, packagePath, relPath string) {
+	for _, vs := range injectionVars(file) {
+		holder := a.packageVarHolder(fset, vs, packagePath, relPath)
+		a.providerSets.Store(packagePath+"."+holder.Name, holder)
+		if holder.importPath != "" && holder.importPath != packagePath {
+			a.providerSets.Store(holder.importPath+"."+holder.Name, holder)
+		}
+	}
+}
+
+// recordPackageInjections records the injections made by the package
+// variables of file, from the holders of recordProviderSets.
+func (a *Analyzer) recordPackageInjections(fset *token.FileSet, file *ast.File, packagePath string, localFuncs []*Function) {
+	for _, vs := range injectionVars(file) {
+		set, ok := a.providerSets.Load(packagePath + "." + vs.Names[0].Name)
+		if !ok {
+			continue
+		}
+		ast.Inspect(vs, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.CallExpr:
+				a.recordInjection(fset, nil, n, set.(*Function), localFuncs)
+			}
+			return true
+		})
+	}
+}
+
+// injectionVars returns the package variable declarations of file whose
+// value makes an injection.
+func injectionVars(file *ast.File) []*ast.ValueSpec {
+	var specs []*ast.ValueSpec
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			found := false
+			for _, value := range vs.Values {
+				ast.Inspect(value, func(n ast.Node) bool {
+					if call, ok := n.(*ast.CallExpr); ok && isInjection(call) {
+						found = true
+					}
+					_, lit := n.(*ast.FuncLit)
+					return !found && !lit
+				})
+			}
+			if found {
+				specs = append(specs, vs)
+			}
+		}
+	}
+	return specs
+}
+
+// packageVarHolder returns a function standing for the initialization of
+// the package variable declared by vs, named after it, to which the calls
+// made there are attributed.
+func (a *Analyzer) packageVarHolder(fset *token.FileSet, vs *ast.ValueSpec, packagePath, relPath string) *Function {
+	pos := fset.Position(vs.Pos())
+	holder := &Function{
+		Name:     vs.Names[0].Name,
+		Package:  packagePath,
+		File:     filepath.Base(relPath),
+		Line:     pos.Line,
+		Column:   pos.Column,
+		FullPath: relPath,
+	}
+	holder.Module, holder.importPath = a.moduleOf(pos.Filename)
+	holder.Constraint = a.fileConstraintOf(pos.Filename)
+	return holder
+}
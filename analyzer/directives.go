@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// directiveLines finds the comments of file starting with directive, such
+// as "//gogotrace:ignore", and returns the words following each of them by
+// the line the comment applies to: its own line when it follows code,
+// otherwise the line after its comment group, which for a doc comment is
+// the line of the func keyword.
+func (a *Analyzer) directiveLines(fset *token.FileSet, file *ast.File, filePath, directive string) map[int][][]string {
+	var data []byte
+	var lines map[int][][]string
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if c.Text != directive && !strings.HasPrefix(c.Text, directive+" ") {
+				continue
+			}
+			if data == nil {
+				var err error
+				if data, err = a.readFile(filePath); err != nil {
+					return nil
+				}
+			}
+			pos := fset.PositionFor(c.Pos(), false)
+			line := fset.PositionFor(group.End(), false).Line + 1
+			if lineStart := pos.Offset - pos.Column + 1; len(bytes.TrimSpace(data[lineStart:pos.Offset])) > 0 {
+				line = pos.Line
+			}
+			if lines == nil {
+				lines = make(map[int][][]string)
+			}
+			lines[line] = append(lines[line], strings.Fields(strings.TrimPrefix(c.Text, directive)))
+		}
+	}
+	return lines
+}
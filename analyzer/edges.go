@@ -0,0 +1,183 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Edge is a call the analysis cannot see, declared by hand, such as a
+// client method calling a server method through RPC. Caller and Callee are
+// signatures, as accepted by FindFunction, or name patterns such as
+// "Dispatch", "Server.GetUser" or "*.Handle*", where * matches any part of
+// a receiver type or function name. A pattern may match several functions,
+// and each caller is linked to each callee.
+type Edge struct {
+	Caller string
+	Callee string
+	Via    string // how the call is made, e.g. "RPC"
+	Line   int    // line of the edge in its file
+}
+
+// ParseEdges reads edges from a YAML document of the form
+//
+//	edges:
+//	  - caller: func (c *Client) GetUser(id string) (*User, error)
+//	    callee: Server.GetUser
+//	    via: RPC
+//
+// Only this structure is supported: a list of mappings with the keys
+// caller, callee and via, whose values are plain or quoted strings.
+func ParseEdges(r io.Reader) ([]Edge, error) {
+	var edges []Edge
+	var current *Edge
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripYAMLComment(scanner.Text()))
+		if line == "" || line == "edges:" || line == "---" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "-"); ok {
+			edges = append(edges, Edge{Line: n})
+			current = &edges[len(edges)-1]
+			if line = strings.TrimSpace(rest); line == "" {
+				continue
+			}
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: expected a list item starting with -", n)
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		value, err := yamlScalar(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		switch strings.TrimSpace(key) {
+		case "caller":
+			current.Caller = value
+		case "callee":
+			current.Callee = value
+		case "via":
+			current.Via = value
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q, want caller, callee or via", n, strings.TrimSpace(key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range edges {
+		if e.Caller == "" || e.Callee == "" {
+			return nil, fmt.Errorf("line %d: an edge needs both caller and callee", e.Line)
+		}
+	}
+	return edges, nil
+}
+
+// stripYAMLComment removes a # comment from line, unless it is quoted.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// yamlScalar returns the string a plain, single-quoted or double-quoted
+// YAML scalar stands for.
+func yamlScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, nil
+}
+
+// AddEdges merges edges into the call graph, as calls of kind
+// CallSynthetic located at the caller's declaration. It fails on the
+// first edge whose caller or callee matches no function.
+func (a *Analyzer) AddEdges(edges []Edge) error {
+	for _, e := range edges {
+		callers, err := a.edgeFunctions(e.Caller)
+		if err != nil {
+			return fmt.Errorf("line %d: caller: %v", e.Line, err)
+		}
+		callees, err := a.edgeFunctions(e.Callee)
+		if err != nil {
+			return fmt.Errorf("line %d: callee: %v", e.Line, err)
+		}
+		for _, caller := range callers {
+			at := callContext{Position: token.Position{Filename: caller.FullPath, Line: caller.Line, Column: caller.Column}, kind: CallSynthetic, via: e.Via}
+			for _, callee := range callees {
+				if callee != caller {
+					a.addCallSite(caller, callee, at)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// edgeFunctions returns the functions an edge endpoint refers to, sorted
+// by key.
+func (a *Analyzer) edgeFunctions(spec string) ([]*Function, error) {
+	if strings.HasPrefix(spec, "func") {
+		fn, err := a.FindFunction(spec)
+		if err != nil {
+			return nil, err
+		}
+		return []*Function{fn}, nil
+	}
+
+	recvPattern, namePattern := "", spec
+	if i := strings.LastIndex(spec, "."); i >= 0 {
+		recvPattern, namePattern = spec[:i], spec[i+1:]
+	}
+	if _, err := path.Match(recvPattern+namePattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q", spec)
+	}
+	var matches []*Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Receiver == "" != (recvPattern == "") || strings.HasPrefix(fn.Name, "func(") {
+			return true
+		}
+		nameOK, _ := path.Match(namePattern, fn.Name)
+		recvOK, _ := path.Match(recvPattern, receiverType(fn.Receiver))
+		if nameOK && recvOK {
+			matches = append(matches, fn)
+		}
+		return true
+	})
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no function matches %q", spec)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Key() < matches[j].Key()
+	})
+	return matches, nil
+}
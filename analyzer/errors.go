@@ -0,0 +1,278 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// Ways a caller handles the error returned by a call, as reported by
+// ErrorHandling.
+const (
+	ErrorPropagates   = "propagates"
+	ErrorWraps        = "wraps"
+	ErrorWrapsNoW     = "wraps without %w"
+	ErrorLogs         = "logs"
+	ErrorChecks       = "checks"
+	ErrorPassesOn     = "passes on"
+	ErrorSwallows     = "swallows"
+	ErrorHandlingNone = ""
+)
+
+// parsedFile is a source file parsed on demand after the analysis.
+type parsedFile struct {
+	fset *token.FileSet
+	file *ast.File
+}
+
+// ReturnsError reports whether the last result of fn is an error.
+func (fn *Function) ReturnsError() bool {
+	return strings.HasSuffix(fn.Signature, "error") || strings.HasSuffix(fn.Signature, "error)")
+}
+
+// ErrorHandling inspects the caller's body around cs and reports what it
+// does with the error the call returns: any of ErrorPropagates, ErrorWraps,
+// ErrorWrapsNoW, ErrorLogs, ErrorChecks (comparing it or testing it with
+// errors.Is or errors.As) and ErrorPassesOn (handing it to another
+// function) joined by ", ", or ErrorSwallows when it does none of them. It returns ErrorHandlingNone when the call cannot be found,
+// e.g. for method values passed as callbacks.
+func (a *Analyzer) ErrorHandling(cs *CallSite) string {
+	pf, err := a.parsedSource(filepath.Join(a.baseDir, cs.Caller.FullPath))
+	if err != nil {
+		return ErrorHandlingNone
+	}
+
+	// Find the call and the enclosing statement and function body
+	var stack []ast.Node
+	var call *ast.CallExpr
+	var parent ast.Node
+	var stmt ast.Stmt
+	var body *ast.BlockStmt
+	ast.Inspect(pf.file, func(n ast.Node) bool {
+		if call != nil {
+			return false
+		}
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if c, ok := n.(*ast.CallExpr); ok && a.callsAt(pf.fset, c, cs) {
+			call = c
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if s, ok := stack[i].(ast.Stmt); ok && stmt == nil {
+					stmt = s
+				}
+				switch fn := stack[i].(type) {
+				case *ast.FuncDecl:
+					body = fn.Body
+				case *ast.FuncLit:
+					body = fn.Body
+				}
+				if body != nil {
+					break
+				}
+			}
+			return false
+		}
+		stack = append(stack, n)
+		return true
+	})
+	if call == nil || stmt == nil || body == nil {
+		return ErrorHandlingNone
+	}
+
+	// The call is used directly: return f(), fmt.Errorf("...: %w", f()),
+	// f() != nil, errors.Is(f(), ErrX), handle(f())
+	switch outer := parent.(type) {
+	case *ast.CallExpr:
+		if isArgument(outer, call) {
+			return argumentKind(outer)
+		}
+	case *ast.BinaryExpr:
+		if outer.Op == token.EQL || outer.Op == token.NEQ {
+			return ErrorChecks
+		}
+	case *ast.ReturnStmt:
+		return ErrorPropagates
+	}
+
+	errVar := errorVariable(stmt, call)
+	if errVar == "" {
+		return ErrorSwallows
+	}
+
+	// Follow the variable through the rest of the function. A bare
+	// err != nil guard is not counted: returning nil under it is how
+	// errors get swallowed in the first place
+	found := make(map[string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil || n.Pos() < stmt.End() {
+			return true
+		}
+		switch node := n.(type) {
+		case *ast.ReturnStmt:
+			for _, r := range node.Results {
+				if ident, ok := r.(*ast.Ident); ok && ident.Name == errVar {
+					found[ErrorPropagates] = true
+				}
+			}
+		case *ast.CallExpr:
+			if usesIdent(node.Args, errVar) {
+				if kind := argumentKind(node); kind != "" {
+					found[kind] = true
+				}
+			}
+		}
+		return true
+	})
+
+	var kinds []string
+	for _, kind := range []string{ErrorPropagates, ErrorWraps, ErrorWrapsNoW, ErrorLogs, ErrorChecks, ErrorPassesOn} {
+		if found[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return ErrorSwallows
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// parsedSource parses filePath once and keeps it for later lookups.
+func (a *Analyzer) parsedSource(filePath string) (*parsedFile, error) {
+	if pf, ok := a.sources.Load(filePath); ok {
+		return pf.(*parsedFile), nil
+	}
+	fset := token.NewFileSet()
+	file, err := a.parseFile(fset, filePath)
+	if err != nil {
+		return nil, err
+	}
+	pf, _ := a.sources.LoadOrStore(filePath, &parsedFile{fset: fset, file: file})
+	return pf.(*parsedFile), nil
+}
+
+// callsAt reports whether call names its callee at the position of cs.
+func (a *Analyzer) callsAt(fset *token.FileSet, call *ast.CallExpr, cs *CallSite) bool {
+	var pos token.Pos
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		pos = fun.Pos()
+	case *ast.SelectorExpr:
+		pos = fun.Sel.Pos()
+	default:
+		return false
+	}
+	p := fset.Position(pos)
+	return p.Line == cs.Line && p.Column == cs.Column
+}
+
+// errorVariable returns the name the error result of call is assigned to
+// in stmt, or "" when it is discarded.
+func errorVariable(stmt ast.Stmt, call *ast.CallExpr) string {
+	var lhs []ast.Expr
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if len(s.Rhs) != 1 || s.Rhs[0] != call {
+			return ""
+		}
+		lhs = s.Lhs
+	case *ast.DeclStmt:
+		gen, ok := s.Decl.(*ast.GenDecl)
+		if !ok || len(gen.Specs) != 1 {
+			return ""
+		}
+		spec, ok := gen.Specs[0].(*ast.ValueSpec)
+		if !ok || len(spec.Values) != 1 || spec.Values[0] != call {
+			return ""
+		}
+		for _, name := range spec.Names {
+			lhs = append(lhs, name)
+		}
+	default:
+		return ""
+	}
+
+	// The error is the last result by convention
+	if ident, ok := lhs[len(lhs)-1].(*ast.Ident); ok && ident.Name != "_" {
+		return ident.Name
+	}
+	return ""
+}
+
+// argumentKind classifies a call receiving an error as wrapping, logging
+// or checking it, or else as passing it on.
+func argumentKind(call *ast.CallExpr) string {
+	if kind := wrapKind(call); kind != "" {
+		return kind
+	}
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "errors" && (sel.Sel.Name == "Is" || sel.Sel.Name == "As") {
+			return ErrorChecks
+		}
+	}
+	return ErrorPassesOn
+}
+
+// isArgument reports whether expr is one of the arguments of call.
+func isArgument(call *ast.CallExpr, expr ast.Expr) bool {
+	for _, arg := range call.Args {
+		if arg == expr {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapKind classifies a call receiving an error as wrapping or logging it.
+func wrapKind(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	pkg := ""
+	if ident, ok := sel.X.(*ast.Ident); ok {
+		pkg = ident.Name
+	}
+	name := sel.Sel.Name
+
+	switch {
+	case pkg == "fmt" && name == "Errorf":
+		if len(call.Args) > 0 {
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && strings.Contains(lit.Value, "%w") {
+				return ErrorWraps
+			}
+		}
+		return ErrorWrapsNoW
+	case pkg == "errors" && (name == "Join" || strings.HasPrefix(name, "Wrap") || strings.HasPrefix(name, "WithMessage")):
+		return ErrorWraps
+	case pkg == "log" || pkg == "slog":
+		return ErrorLogs
+	}
+	for _, prefix := range []string{"Print", "Log", "Error", "Warn", "Info", "Debug", "Fatal"} {
+		if strings.HasPrefix(name, prefix) {
+			return ErrorLogs
+		}
+	}
+	return ""
+}
+
+func usesIdent(exprs []ast.Expr, name string) bool {
+	for _, e := range exprs {
+		found := false
+		ast.Inspect(e, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && ident.Name == name {
+				found = true
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,469 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Route maps an HTTP method and path to the function handling it, for
+// StitchHTTP, as declared in a route file or an OpenAPI spec.
+type Route struct {
+	Method      string // "" for any
+	Path        string // pattern such as "/users/{id}" or "/users/:id"
+	Handler     string // signature or name pattern, as in Edge
+	OperationID string // for OpenAPI operations: the name of the handler, in any case
+	Line        int    // line of the route in its file, 0 for a JSON spec
+}
+
+// clientRequest is an HTTP request made by a function with net/http, as
+// http.Get(url) or http.NewRequest(method, url, body).
+type clientRequest struct {
+	caller *Function
+	method string // "" when not known
+	path   string // URL path, with * for the parts not known statically
+	at     token.Position
+}
+
+// constantDecl is a package-level string constant or variable, by which a
+// request URL may be given.
+type constantDecl struct {
+	value    ast.Expr
+	pkg      string
+	filePath string
+	isConst  bool // declared with const rather than var
+}
+
+// openAPIMethods are the operations of an OpenAPI path item.
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// formatVerb matches the verbs of a fmt format string.
+var formatVerb = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]*)?[a-zA-Z%]`)
+
+// ParseRoutes reads a route table: either an OpenAPI spec, in JSON or in
+// YAML, whose operationIds name the handlers, or lines of the form
+//
+//	GET /users/{id} Server.GetUser
+//
+// where the method may be * for any and the handler is a signature or a
+// name pattern as in Edge. Blank lines and lines starting with # are
+// ignored.
+func ParseRoutes(r io.Reader) ([]Route, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		return parseOpenAPIJSON(trimmed)
+	}
+
+	var routes []Route
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "openapi:") || strings.HasPrefix(line, "swagger:") {
+			return parseOpenAPIYAML(data)
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("line %d: want <method> <path> <handler>, got %q", n, line)
+		}
+		method := strings.ToUpper(fields[0])
+		if method == "*" {
+			method = ""
+		} else if !httpMethods[method] {
+			return nil, fmt.Errorf("line %d: unknown method %s", n, fields[0])
+		}
+		routes = append(routes, Route{Method: method, Path: fields[1], Handler: strings.Join(fields[2:], " "), Line: n})
+	}
+	return routes, scanner.Err()
+}
+
+// parseOpenAPIJSON returns the operations of an OpenAPI spec in JSON,
+// sorted by path and method.
+func parseOpenAPIJSON(data []byte) ([]Route, error) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	var routes []Route
+	for p, item := range spec.Paths {
+		for method, raw := range item {
+			if !openAPIMethods[method] {
+				continue
+			}
+			var op struct {
+				OperationID string `json:"operationId"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %v", method, p, err)
+			}
+			routes = append(routes, Route{Method: strings.ToUpper(method), Path: p, OperationID: op.OperationID})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes, nil
+}
+
+// parseOpenAPIYAML returns the operations of an OpenAPI spec in YAML. Only
+// the paths mapping is read, by indentation: its path items, their
+// operations and the operationId of each.
+func parseOpenAPIYAML(data []byte) ([]Route, error) {
+	var routes []Route
+	var current *Route
+	inPaths := false
+	pathIndent, opIndent := -1, -1
+	currentPath := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		text := stripYAMLComment(scanner.Text())
+		line := strings.TrimSpace(text)
+		if line == "" {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		// Keys may contain colons, as in /users/:id
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			key = strings.TrimSuffix(line, ":")
+		}
+		key, err := yamlScalar(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+
+		switch {
+		case indent == 0:
+			inPaths = key == "paths"
+			pathIndent, current = -1, nil
+		case !inPaths:
+		case pathIndent < 0 || indent <= pathIndent:
+			pathIndent, opIndent = indent, -1
+			currentPath, current = key, nil
+		case opIndent < 0 || indent <= opIndent:
+			opIndent, current = indent, nil
+			if openAPIMethods[key] {
+				routes = append(routes, Route{Method: strings.ToUpper(key), Path: currentPath, Line: n})
+				current = &routes[len(routes)-1]
+			}
+		case current != nil && key == "operationId":
+			if current.OperationID, err = yamlScalar(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+		}
+	}
+	return routes, scanner.Err()
+}
+
+// recordConstants remembers the package-level string constants and
+// variables of file, by package path and, with a go.mod, import path, so
+// request URLs given by name can be resolved.
+func (a *Analyzer) recordConstants(file *ast.File, packagePath, filePath string) {
+	_, importPath := a.moduleOf(filePath)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST && gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					break
+				}
+				c := constantDecl{value: vs.Values[i], pkg: packagePath, filePath: filePath, isConst: gen.Tok == token.CONST}
+				a.constants.Store(packagePath+"."+name.Name, c)
+				if importPath != "" && importPath != packagePath {
+					a.constants.Store(importPath+"."+name.Name, c)
+				}
+			}
+		}
+	}
+}
+
+// recordRequest records call when it makes an HTTP request with net/http
+// or an http.Client, to a URL whose path is known.
+func (a *Analyzer) recordRequest(fset *token.FileSet, call *ast.CallExpr, caller *Function) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	var method string
+	var url ast.Expr
+	switch name := sel.Sel.Name; {
+	case name == "NewRequest" && len(call.Args) == 3:
+		method, url = requestMethod(call.Args[0]), call.Args[1]
+	case name == "NewRequestWithContext" && len(call.Args) == 4:
+		method, url = requestMethod(call.Args[1]), call.Args[2]
+	case (name == "Get" || name == "Head") && len(call.Args) == 1:
+		method, url = strings.ToUpper(name), call.Args[0]
+	case name == "Post" && len(call.Args) == 3, name == "PostForm" && len(call.Args) == 2:
+		method, url = "POST", call.Args[0]
+	default:
+		return
+	}
+
+	at := fset.Position(call.Pos())
+	p := requestPath(a.urlPattern(url, caller.Package, at.Filename, 0))
+	if p == "" {
+		return
+	}
+	req := clientRequest{caller: caller, method: method, path: p, at: at}
+	a.requests.Store(fmt.Sprintf("%s#%s:%d:%d", caller.Key(), at.Filename, at.Line, at.Column), req)
+}
+
+// requestMethod returns the method given to http.NewRequest, as a string
+// literal or an http.MethodGet style constant, or "".
+func requestMethod(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if s, err := strconv.Unquote(e.Value); err == nil && httpMethods[strings.ToUpper(s)] {
+			return strings.ToUpper(s)
+		}
+	case *ast.SelectorExpr:
+		if m := strings.ToUpper(strings.TrimPrefix(e.Sel.Name, "Method")); httpMethods[m] {
+			return m
+		}
+	}
+	return ""
+}
+
+// urlPattern returns the text of a URL expression of filePath in package
+// pkg, with * standing for the parts not known statically: string
+// literals, constants, concatenations and fmt.Sprintf calls are resolved.
+func (a *Analyzer) urlPattern(expr ast.Expr, pkg, filePath string, depth int) string {
+	if depth > 8 {
+		return "*"
+	}
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if s, err := strconv.Unquote(e.Value); err == nil && e.Kind == token.STRING {
+			return s
+		}
+	case *ast.ParenExpr:
+		return a.urlPattern(e.X, pkg, filePath, depth)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			return a.urlPattern(e.X, pkg, filePath, depth) + a.urlPattern(e.Y, pkg, filePath, depth)
+		}
+	case *ast.Ident:
+		if c, ok := a.constants.Load(pkg + "." + e.Name); ok {
+			c := c.(constantDecl)
+			return a.urlPattern(c.value, c.pkg, c.filePath, depth+1)
+		}
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		imports, ok := a.imports.Load(filePath)
+		if !ok {
+			break
+		}
+		if importPath, ok := imports.(map[string]string)[x.Name]; ok {
+			if c, ok := a.constants.Load(importPath + "." + e.Sel.Name); ok {
+				c := c.(constantDecl)
+				return a.urlPattern(c.value, c.pkg, c.filePath, depth+1)
+			}
+		}
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Sprintf" && len(e.Args) > 0 {
+			format := a.urlPattern(e.Args[0], pkg, filePath, depth)
+			return formatVerb.ReplaceAllStringFunc(format, func(verb string) string {
+				if verb == "%%" {
+					return "%"
+				}
+				return "*"
+			})
+		}
+	}
+	return "*"
+}
+
+// requestPath returns the path of a URL pattern, without the scheme, the
+// host and the query, or "" when it is not known. A leading unknown part
+// followed by a path, as in baseURL + "/users", is taken for the host.
+func requestPath(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+len("://"):]
+		i = strings.Index(url, "/")
+		if i < 0 {
+			return "/"
+		}
+		url = url[i:]
+	} else if !strings.HasPrefix(url, "/") {
+		host, p, ok := strings.Cut(url, "/")
+		if !ok || host != "*" {
+			return ""
+		}
+		url = "/" + p
+	}
+	url, _, _ = strings.Cut(url, "?")
+	return url
+}
+
+// routeMatch scores how well route matches a request path, lower being
+// more specific, or returns -1 when it does not match. Path parameters,
+// as {id}, :id or *, match any segment; a {name...} or * last segment
+// matches the rest of the path.
+func routeMatch(route, request string) int {
+	routeSegs := strings.Split(strings.Trim(route, "/"), "/")
+	reqSegs := strings.Split(strings.Trim(request, "/"), "/")
+	score := 0
+	for i, seg := range routeSegs {
+		last := i == len(routeSegs)-1
+		if last && (seg == "*" || strings.HasPrefix(seg, "*") || strings.HasSuffix(seg, "...}")) {
+			if len(reqSegs) < len(routeSegs) {
+				return -1
+			}
+			return score + 1
+		}
+		if i >= len(reqSegs) {
+			return -1
+		}
+		switch {
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"), strings.HasPrefix(seg, ":"):
+			score++
+		case seg == reqSegs[i]:
+		default:
+			if ok, _ := path.Match(reqSegs[i], seg); !ok {
+				return -1
+			}
+			score += 2
+		}
+	}
+	if len(reqSegs) != len(routeSegs) {
+		return -1
+	}
+	return score
+}
+
+// StitchHTTP links the HTTP requests made in the analyzed code to the
+// handlers of their routes, with CallSynthetic calls labeled with the
+// route, as "HTTP GET /users/{id}". The route table is made of routes and
+// of the HTTP handlers registered in the analyzed code; a request is
+// linked to the handlers of its most specific matching routes. It returns
+// the number of edges added, and fails when a route's handler matches no
+// function.
+func (a *Analyzer) StitchHTTP(routes []Route) (int, error) {
+	type handledRoute struct {
+		method, path string
+		fn           *Function
+	}
+	var table []handledRoute
+	for _, r := range routes {
+		var handlers []*Function
+		if r.Handler != "" {
+			var err error
+			if handlers, err = a.edgeFunctions(r.Handler); err != nil {
+				return 0, fmt.Errorf("line %d: %v", r.Line, err)
+			}
+		} else if r.OperationID != "" {
+			handlers = a.operationHandlers(r.OperationID)
+		}
+		for _, fn := range handlers {
+			table = append(table, handledRoute{r.Method, r.Path, fn})
+		}
+	}
+	a.entryFuncs.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		for _, entry := range a.EntryPoints(fn) {
+			if entry.Kind == EntryHTTP {
+				table = append(table, handledRoute{entry.Method, entry.Route, fn})
+			}
+		}
+		return true
+	})
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].fn.Key() < table[j].fn.Key()
+	})
+
+	var requests []clientRequest
+	a.requests.Range(func(key, value interface{}) bool {
+		requests = append(requests, value.(clientRequest))
+		return true
+	})
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].at.Filename != requests[j].at.Filename {
+			return requests[i].at.Filename < requests[j].at.Filename
+		}
+		if requests[i].at.Line != requests[j].at.Line {
+			return requests[i].at.Line < requests[j].at.Line
+		}
+		return requests[i].at.Column < requests[j].at.Column
+	})
+
+	added := 0
+	for _, req := range requests {
+		best := -1
+		var matches []handledRoute
+		for _, r := range table {
+			if r.method != "" && req.method != "" && r.method != req.method {
+				continue
+			}
+			score := routeMatch(r.path, req.path)
+			switch {
+			case score < 0:
+			case best < 0 || score < best:
+				best, matches = score, []handledRoute{r}
+			case score == best:
+				matches = append(matches, r)
+			}
+		}
+
+		linked := make(map[string]bool)
+		for _, r := range matches {
+			if linked[r.fn.Key()] {
+				continue
+			}
+			linked[r.fn.Key()] = true
+			via := "HTTP " + r.path
+			if r.method != "" {
+				via = "HTTP " + r.method + " " + r.path
+			}
+			a.addCallSite(req.caller, r.fn, callContext{Position: req.at, kind: CallSynthetic, via: via})
+			added++
+		}
+	}
+	return added, nil
+}
+
+// operationHandlers returns the named functions and methods whose name is
+// an OpenAPI operationId in any case, as GetUser for getUser, sorted by
+// key.
+func (a *Analyzer) operationHandlers(operationID string) []*Function {
+	var handlers []*Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if strings.EqualFold(fn.Name, operationID) {
+			handlers = append(handlers, fn)
+		}
+		return true
+	})
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].Key() < handlers[j].Key()
+	})
+	return handlers
+}
@@ -0,0 +1,255 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Interface is an interface type and the methods it requires, each with
+// its shape: parameter and result types without names, as in
+// "([]byte) (int, error)".
+type Interface struct {
+	Name     string // as declared, or qualified for the standard library ones
+	Package  string // "" for the standard library ones
+	FullPath string // file of the declaration, "" for the standard library ones
+	Line     int
+	Methods  map[string]string
+	embeds   []string // embedded interface types, merged by FindInterface
+}
+
+// Implementation is a type whose method set satisfies an interface.
+type Implementation struct {
+	Type    string // type name, without pointer or type parameters
+	Package string
+	Pointer bool        // only *Type implements it: some method has a pointer receiver
+	Methods []*Function // the implementing methods, sorted by name
+}
+
+// stdInterfaces are common standard library interfaces, which are not
+// analyzed but often implemented.
+var stdInterfaces = map[string]*Interface{
+	"error":                    {Methods: map[string]string{"Error": "() string"}},
+	"fmt.Stringer":             {Methods: map[string]string{"String": "() string"}},
+	"io.Reader":                {Methods: map[string]string{"Read": "([]byte) (int, error)"}},
+	"io.Writer":                {Methods: map[string]string{"Write": "([]byte) (int, error)"}},
+	"io.Closer":                {Methods: map[string]string{"Close": "() error"}},
+	"io.ReadCloser":            {embeds: []string{"io.Reader", "io.Closer"}},
+	"io.WriteCloser":           {embeds: []string{"io.Writer", "io.Closer"}},
+	"io.ReadWriter":            {embeds: []string{"io.Reader", "io.Writer"}},
+	"io.ReadWriteCloser":       {embeds: []string{"io.Reader", "io.Writer", "io.Closer"}},
+	"io.StringWriter":          {Methods: map[string]string{"WriteString": "(string) (int, error)"}},
+	"io.WriterTo":              {Methods: map[string]string{"WriteTo": "(io.Writer) (int64, error)"}},
+	"io.ReaderFrom":            {Methods: map[string]string{"ReadFrom": "(io.Reader) (int64, error)"}},
+	"sort.Interface":           {Methods: map[string]string{"Len": "() int", "Less": "(int, int) bool", "Swap": "(int, int)"}},
+	"flag.Value":               {Methods: map[string]string{"String": "() string", "Set": "(string) error"}},
+	"http.Handler":             {Methods: map[string]string{"ServeHTTP": "(http.ResponseWriter, *http.Request)"}},
+	"json.Marshaler":           {Methods: map[string]string{"MarshalJSON": "() ([]byte, error)"}},
+	"json.Unmarshaler":         {Methods: map[string]string{"UnmarshalJSON": "([]byte) error"}},
+	"encoding.TextMarshaler":   {Methods: map[string]string{"MarshalText": "() ([]byte, error)"}},
+	"encoding.TextUnmarshaler": {Methods: map[string]string{"UnmarshalText": "([]byte) error"}},
+}
+
+// recordInterfaces remembers the interface types declared in file, by
+// name for interfaceParam and with their methods for FindInterface.
+func (a *Analyzer) recordInterfaces(fset *token.FileSet, file *ast.File, packagePath, relPath string) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			it, ok := ts.Type.(*ast.InterfaceType)
+			if !ok {
+				continue
+			}
+			a.interfaces.Store(ts.Name.Name, true)
+
+			iface := &Interface{
+				Name:     ts.Name.Name,
+				Package:  packagePath,
+				FullPath: relPath,
+				Line:     fset.Position(ts.Pos()).Line,
+				Methods:  make(map[string]string),
+			}
+			for _, field := range it.Methods.List {
+				ft, ok := field.Type.(*ast.FuncType)
+				if !ok {
+					// Embedded interface, or a type set element of a constraint
+					iface.embeds = append(iface.embeds, a.formatType(field.Type))
+					continue
+				}
+				for _, name := range field.Names {
+					iface.Methods[name.Name] = a.funcShape(ft)
+				}
+			}
+			a.ifaceDecls.Store(packagePath+"."+ts.Name.Name, iface)
+		}
+	}
+}
+
+// funcShape returns the parameter and result types of ft without names.
+func (a *Analyzer) funcShape(ft *ast.FuncType) string {
+	types := func(fields *ast.FieldList) []string {
+		var list []string
+		if fields == nil {
+			return list
+		}
+		for _, field := range fields.List {
+			typ := a.formatType(field.Type)
+			for i := 0; i < len(field.Names) || i == 0 && len(field.Names) == 0; i++ {
+				list = append(list, typ)
+			}
+		}
+		return list
+	}
+
+	shape := "(" + strings.Join(types(ft.Params), ", ") + ")"
+	switch results := types(ft.Results); len(results) {
+	case 0:
+	case 1:
+		shape += " " + results[0]
+	default:
+		shape += " (" + strings.Join(results, ", ") + ")"
+	}
+	return shape
+}
+
+// FindInterface returns the interface with the given name: one of the
+// common standard library interfaces such as "io.Reader", or one declared
+// in the analyzed code, optionally qualified by the end of its package
+// path as in "store.Store". Embedded interfaces are resolved, so Methods
+// lists the full method set.
+func (a *Analyzer) FindInterface(name string) (*Interface, error) {
+	iface, err := a.lookupInterface(name, "")
+	if err != nil {
+		return nil, err
+	}
+	return a.resolve(iface, map[*Interface]bool{}), nil
+}
+
+// lookupInterface finds an interface by name, preferring one declared in
+// pkg for unqualified names.
+func (a *Analyzer) lookupInterface(name, pkg string) (*Interface, error) {
+	if iface, ok := stdInterfaces[name]; ok {
+		return &Interface{Name: name, Methods: iface.Methods, embeds: iface.embeds}, nil
+	}
+
+	qualifier, bare := "", name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		qualifier, bare = name[:i], name[i+1:]
+	}
+	var matches []*Interface
+	a.ifaceDecls.Range(func(key, value interface{}) bool {
+		iface := value.(*Interface)
+		if iface.Name != bare {
+			return true
+		}
+		if qualifier != "" && iface.Package != qualifier && !strings.HasSuffix(iface.Package, "/"+qualifier) {
+			return true
+		}
+		if qualifier == "" && iface.Package == pkg {
+			matches = []*Interface{iface}
+			return false
+		}
+		matches = append(matches, iface)
+		return true
+	})
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("interface %s not found", name)
+	case 1:
+		return matches[0], nil
+	}
+	var packages []string
+	for _, iface := range matches {
+		packages = append(packages, iface.Package)
+	}
+	sort.Strings(packages)
+	return nil, fmt.Errorf("interface %s is declared in several packages (%s); qualify it as <package>.%s", name, strings.Join(packages, ", "), bare)
+}
+
+// resolve returns iface with the methods of its embedded interfaces
+// merged in. Embedded interfaces that cannot be found are ignored.
+func (a *Analyzer) resolve(iface *Interface, seen map[*Interface]bool) *Interface {
+	resolved := *iface
+	resolved.Methods = make(map[string]string)
+	resolved.embeds = nil
+	seen[iface] = true
+	for _, embed := range iface.embeds {
+		inner, err := a.lookupInterface(embed, iface.Package)
+		if err != nil || seen[inner] {
+			continue
+		}
+		for name, shape := range a.resolve(inner, seen).Methods {
+			resolved.Methods[name] = shape
+		}
+	}
+	for name, shape := range iface.Methods {
+		resolved.Methods[name] = shape
+	}
+	return &resolved
+}
+
+// Implementations returns the types of the analyzed code whose methods
+// satisfy iface, matching method names and shapes, sorted by package and
+// type. Interfaces without methods are satisfied by every type and yield
+// none.
+func (a *Analyzer) Implementations(iface *Interface) []Implementation {
+	if len(iface.Methods) == 0 {
+		return nil
+	}
+
+	type typeKey struct{ pkg, name string }
+	methods := make(map[typeKey]map[string]*Function)
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Receiver == "" {
+			return true
+		}
+		if _, ok := iface.Methods[fn.Name]; !ok {
+			return true
+		}
+		k := typeKey{fn.Package, receiverType(fn.Receiver)}
+		if methods[k] == nil {
+			methods[k] = make(map[string]*Function)
+		}
+		methods[k][fn.Name] = fn
+		return true
+	})
+
+	var impls []Implementation
+	for k, byName := range methods {
+		impl := Implementation{Type: k.name, Package: k.pkg}
+		for name, shape := range iface.Methods {
+			fn, ok := byName[name]
+			if !ok || fn.shape != shape {
+				impl.Methods = nil
+				break
+			}
+			impl.Methods = append(impl.Methods, fn)
+			impl.Pointer = impl.Pointer || fn.PointerRecv
+		}
+		if impl.Methods == nil {
+			continue
+		}
+		sort.Slice(impl.Methods, func(i, j int) bool {
+			return impl.Methods[i].Name < impl.Methods[j].Name
+		})
+		impls = append(impls, impl)
+	}
+	sort.Slice(impls, func(i, j int) bool {
+		if impls[i].Package != impls[j].Package {
+			return impls[i].Package < impls[j].Package
+		}
+		return impls[i].Type < impls[j].Type
+	})
+	return impls
+}
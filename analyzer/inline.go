@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+)
+
+// Inlining tells whether the body of a function can be pasted in place of
+// its calls without rewriting it, as reported by Inlining.
+type Inlining struct {
+	Statements int      // statements of the body, not counting nested blocks
+	Returns    int      // return statements, outside function literals
+	Reasons    []string // why inlining is not straightforward, none when it is
+}
+
+// Straightforward reports whether no reason prevents a plain inlining.
+func (in Inlining) Straightforward() bool {
+	return len(in.Reasons) == 0
+}
+
+// Inlining inspects the body of fn: it is straightforward to inline when it
+// has a single return, last, no named results, no deferred calls and does
+// not call itself.
+func (a *Analyzer) Inlining(fn *Function) (Inlining, error) {
+	pf, err := a.parsedSource(filepath.Join(a.baseDir, fn.FullPath))
+	if err != nil {
+		return Inlining{}, err
+	}
+	var typ *ast.FuncType
+	var body *ast.BlockStmt
+	ast.Inspect(pf.file, func(n ast.Node) bool {
+		if body != nil {
+			return false
+		}
+		switch f := n.(type) {
+		case *ast.FuncDecl:
+			if a.declaredAt(pf.fset, f.Pos(), fn) {
+				typ, body = f.Type, f.Body
+			}
+		case *ast.FuncLit:
+			if a.declaredAt(pf.fset, f.Pos(), fn) {
+				typ, body = f.Type, f.Body
+			}
+		}
+		return true
+	})
+	if body == nil {
+		return Inlining{Reasons: []string{"no body"}}, nil
+	}
+
+	in := Inlining{Statements: len(body.List)}
+	defers := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			in.Returns++
+		case *ast.DeferStmt:
+			defers = true
+		}
+		return true
+	})
+
+	if in.Returns > 1 {
+		in.Reasons = append(in.Reasons, "several returns")
+	} else if in.Returns == 1 {
+		if _, ok := body.List[len(body.List)-1].(*ast.ReturnStmt); !ok {
+			in.Reasons = append(in.Reasons, "returns before its end")
+		}
+	}
+	if typ.Results != nil && len(typ.Results.List) > 0 && len(typ.Results.List[0].Names) > 0 {
+		in.Reasons = append(in.Reasons, "named results")
+	}
+	if defers {
+		in.Reasons = append(in.Reasons, "defers calls")
+	}
+	for _, cs := range a.GetCallersOf(fn) {
+		if cs.Caller.Key() == fn.Key() {
+			in.Reasons = append(in.Reasons, "recursive")
+			break
+		}
+	}
+	return in, nil
+}
+
+// InlineSite reports why the call of cs cannot simply be replaced by the
+// body of the callee, described by in, or "" when it can.
+func (a *Analyzer) InlineSite(cs *CallSite, in Inlining) string {
+	switch cs.Kind {
+	case CallDirect, CallMethod, CallGo, CallDefer:
+	case CallCallback:
+		return "passed as a function value"
+	case CallInterface, CallHeuristic:
+		return "callee not known statically"
+	default:
+		return "not called in the source"
+	}
+	if in.Statements <= 1 {
+		return ""
+	}
+
+	// Statements before the return have to be hoisted out of an expression
+	pf, err := a.parsedSource(filepath.Join(a.baseDir, cs.Caller.FullPath))
+	if err != nil {
+		return ""
+	}
+	reason := ""
+	var stack []ast.Node
+	ast.Inspect(pf.file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if call, ok := n.(*ast.CallExpr); ok && a.callsAt(pf.fset, call, cs) {
+			switch p := stack[len(stack)-1].(type) {
+			case *ast.ExprStmt, *ast.GoStmt, *ast.DeferStmt, *ast.ReturnStmt:
+			case *ast.AssignStmt:
+				if len(p.Rhs) != 1 {
+					reason = "used in a multiple assignment"
+				}
+			default:
+				reason = "used inside an expression"
+			}
+			return false
+		}
+		stack = append(stack, n)
+		return true
+	})
+	return reason
+}
+
+// declaredAt reports whether the function starting at pos is fn.
+func (a *Analyzer) declaredAt(fset *token.FileSet, pos token.Pos, fn *Function) bool {
+	p := fset.Position(pos)
+	return p.Line == fn.Line && p.Column == fn.Column
+}
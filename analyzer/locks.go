@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// callContext describes where in the caller's body a call happens.
+type callContext struct {
+	token.Position
+	heldLock     string
+	freshContext int     // see CallSite.FreshContext
+	kind         string  // one of the Call kind constants
+	via          string  // how a CallSynthetic call is made, or the CallResolver of a CallResolved one
+	confidence   float64 // of a CallResolved call
+}
+
+// lockRegion is a stretch of a function body during which a mutex is held.
+type lockRegion struct {
+	from, to token.Pos
+	lock     string
+}
+
+// siteAt returns the context of a call at pos.
+func siteAt(fset *token.FileSet, locks []lockRegion, pos token.Pos) callContext {
+	at := callContext{Position: fset.Position(pos)}
+	for _, r := range locks {
+		if pos > r.from && pos < r.to {
+			at.heldLock = r.lock
+			break
+		}
+	}
+	return at
+}
+
+// lockedRegions finds the stretches of body between x.Lock() (or RLock)
+// and the matching x.Unlock() in source order. A deferred unlock, or none,
+// holds the lock until the end of the body. Function literals are skipped
+// since they usually run later, outside the lock.
+func lockedRegions(body *ast.BlockStmt) []lockRegion {
+	type event struct {
+		pos      token.Pos
+		lock     string
+		acquire  bool
+		deferred bool
+	}
+	var events []event
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			if lock, op := mutexCall(node.Call); op == "Unlock" || op == "RUnlock" {
+				events = append(events, event{pos: node.Pos(), lock: lock, deferred: true})
+			}
+			return false
+		case *ast.CallExpr:
+			switch lock, op := mutexCall(node); op {
+			case "Lock", "RLock":
+				events = append(events, event{pos: node.Pos(), lock: lock, acquire: true})
+			case "Unlock", "RUnlock":
+				events = append(events, event{pos: node.Pos(), lock: lock})
+			}
+		}
+		return true
+	})
+	sort.Slice(events, func(i, j int) bool { return events[i].pos < events[j].pos })
+
+	var regions []lockRegion
+	held := make(map[string]token.Pos)
+	deferred := make(map[string]bool)
+	for _, e := range events {
+		switch {
+		case e.acquire:
+			if _, ok := held[e.lock]; !ok {
+				held[e.lock] = e.pos
+			}
+		case e.deferred:
+			deferred[e.lock] = true
+		default:
+			if from, ok := held[e.lock]; ok && !deferred[e.lock] {
+				regions = append(regions, lockRegion{from: from, to: e.pos, lock: e.lock})
+				delete(held, e.lock)
+			}
+		}
+	}
+	for lock, from := range held {
+		regions = append(regions, lockRegion{from: from, to: body.End(), lock: lock})
+	}
+	return regions
+}
+
+// mutexCall recognizes x.Lock(), x.Unlock(), x.RLock() and x.RUnlock(),
+// returning x and the method name.
+func mutexCall(call *ast.CallExpr) (lock, op string) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 0 {
+		return "", ""
+	}
+	switch sel.Sel.Name {
+	case "Lock", "Unlock", "RLock", "RUnlock":
+		return types.ExprString(sel.X), sel.Sel.Name
+	}
+	return "", ""
+}
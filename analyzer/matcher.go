@@ -7,10 +7,35 @@ import (
 )
 
 func (a *Analyzer) FindCallers(targetSignature string, excludeTests bool) ([]*CallSite, error) {
-	targetSignature = a.normalizeSignature(targetSignature)
+	targetFunc, err := a.FindFunction(targetSignature)
+	if err != nil {
+		return nil, err
+	}
 	
-	var targetFunc *Function
 	var allCallSites []*CallSite
+	if sites, ok := a.callGraph.Load(a.getFunctionKey(targetFunc)); ok {
+		allCallSites = append(allCallSites, sites.([]*CallSite)...)
+	}
+	
+	if excludeTests {
+		var filtered []*CallSite
+		for _, cs := range allCallSites {
+			if !cs.Caller.IsTest {
+				filtered = append(filtered, cs)
+			}
+		}
+		allCallSites = filtered
+	}
+	
+	return allCallSites, nil
+}
+
+// FindFunction returns the function matching the signature. When several
+// functions match, the one with the smallest key is used so the choice is
+// deterministic.
+func (a *Analyzer) FindFunction(targetSignature string) (*Function, error) {
+	targetSignature = a.normalizeSignature(targetSignature)
+	
 	var matchingFunctions []*Function
 	
 	// Search through all functions for matching signature
@@ -22,41 +47,104 @@ func (a *Analyzer) FindCallers(targetSignature string, excludeTests bool) ([]*Ca
 		return true
 	})
 	
-	// Sort matching functions for deterministic behavior
-	if len(matchingFunctions) > 0 {
-		// Sort by function key to ensure consistent ordering
-		sort.Slice(matchingFunctions, func(i, j int) bool {
-			return a.getFunctionKey(matchingFunctions[i]) < a.getFunctionKey(matchingFunctions[j])
-		})
-		
-		// Use the first matching function as the target
-		targetFunc = matchingFunctions[0]
-		
-		// Collect call sites from the first matching function only
-		fnKey := a.getFunctionKey(targetFunc)
-		if sites, ok := a.callGraph.Load(fnKey); ok {
-			allCallSites = append(allCallSites, sites.([]*CallSite)...)
-		}
+	if len(matchingFunctions) == 0 {
+		return nil, fmt.Errorf("function with signature '%s' not found", targetSignature)
 	}
 	
-	if targetFunc == nil {
-		return nil, fmt.Errorf("function with signature '%s' not found", targetSignature)
+	// Sort by function key to ensure consistent ordering
+	sort.Slice(matchingFunctions, func(i, j int) bool {
+		return a.getFunctionKey(matchingFunctions[i]) < a.getFunctionKey(matchingFunctions[j])
+	})
+	
+	return matchingFunctions[0], nil
+}
+
+// FindMethods returns the methods declared on the named type, sorted by
+// name. typeName may be qualified by the end of its package path, as in
+// "server.Service"; unqualified names declared in several packages are an
+// error listing them.
+func (a *Analyzer) FindMethods(typeName string) ([]*Function, error) {
+	pkg, name := "", typeName
+	if i := strings.LastIndex(typeName, "."); i >= 0 {
+		pkg, name = typeName[:i], typeName[i+1:]
 	}
 	
-	if excludeTests {
-		var filtered []*CallSite
-		for _, cs := range allCallSites {
-			if !cs.Caller.IsTest {
-				filtered = append(filtered, cs)
-			}
+	byPackage := make(map[string][]*Function)
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Receiver == "" || receiverType(fn.Receiver) != name || !a.matchesReceiverKind(fn) {
+			return true
 		}
-		allCallSites = filtered
+		if pkg != "" && fn.Package != pkg && !strings.HasSuffix(fn.Package, "/"+pkg) {
+			return true
+		}
+		byPackage[fn.Package] = append(byPackage[fn.Package], fn)
+		return true
+	})
+	
+	if len(byPackage) == 0 {
+		return nil, fmt.Errorf("no methods found on type %s", typeName)
+	}
+	if len(byPackage) > 1 {
+		var packages []string
+		for p := range byPackage {
+			packages = append(packages, p)
+		}
+		sort.Strings(packages)
+		return nil, fmt.Errorf("type %s has methods in several packages (%s); qualify it as <package>.%s", typeName, strings.Join(packages, ", "), name)
 	}
 	
-	return allCallSites, nil
+	var methods []*Function
+	for _, fns := range byPackage {
+		methods = fns
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Name != methods[j].Name {
+			return methods[i].Name < methods[j].Name
+		}
+		return methods[i].Key() < methods[j].Key()
+	})
+	return methods, nil
+}
+
+// receiverType returns the bare type name of a receiver, dropping the
+// pointer and any type parameters: "*List[T]" gives "List".
+func receiverType(receiver string) string {
+	receiver = strings.TrimPrefix(receiver, "*")
+	if i := strings.Index(receiver, "["); i >= 0 {
+		receiver = receiver[:i]
+	}
+	return receiver
+}
+
+// TransitiveCallers returns every function that calls fn directly or
+// indirectly, keyed by function key.
+func (a *Analyzer) TransitiveCallers(fn *Function, excludeTests bool) map[string]*Function {
+	callers := make(map[string]*Function)
+	queue := []*Function{fn}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, cs := range a.GetCallersOf(current) {
+			if excludeTests && cs.Caller.IsTest {
+				continue
+			}
+			key := a.getFunctionKey(cs.Caller)
+			if _, seen := callers[key]; seen {
+				continue
+			}
+			callers[key] = cs.Caller
+			queue = append(queue, cs.Caller)
+		}
+	}
+	return callers
 }
 
 func (a *Analyzer) matchesSignature(fn *Function, targetSignature string) bool {
+	if !a.matchesReceiverKind(fn) {
+		return false
+	}
+	
 	fnSig := a.normalizeSignature(fn.Signature)
 	targetSig := a.normalizeSignature(targetSignature)
 	
@@ -86,6 +174,18 @@ func (a *Analyzer) matchesSignature(fn *Function, targetSignature string) bool {
 	return true
 }
 
+// matchesReceiverKind reports whether fn passes the WithReceiverKind
+// restriction.
+func (a *Analyzer) matchesReceiverKind(fn *Function) bool {
+	switch a.receiverKind {
+	case ReceiverPointer:
+		return fn.Receiver != "" && fn.PointerRecv
+	case ReceiverValue:
+		return fn.Receiver != "" && !fn.PointerRecv
+	}
+	return true
+}
+
 type signatureParts struct {
 	receiver string
 	name     string
@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// expectedMethod returns the method call sets an expectation on, as in
+// m.EXPECT().Get(key) with gomock or mockery's expecters, or
+// m.On("Get", key) with mockery's testify mocks, or "".
+func expectedMethod(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if inner, ok := sel.X.(*ast.CallExpr); ok && len(inner.Args) == 0 {
+		if expect, ok := inner.Fun.(*ast.SelectorExpr); ok && expect.Sel.Name == "EXPECT" {
+			return sel.Sel.Name
+		}
+	}
+	if sel.Sel.Name == "On" {
+		return stringArg(call, 0)
+	}
+	return ""
+}
+
+// isMockHelper reports whether typ is one of the types a mock generator
+// declares next to the mock: gomock's recorders, mockery's expecters and
+// call types.
+func isMockHelper(typ string) bool {
+	return strings.HasSuffix(typ, "MockRecorder") || strings.HasSuffix(typ, "_Expecter") || strings.Contains(typ, "_Call")
+}
+
+// recordExpectation links a test setting an expectation on a mock's
+// method with call to the methods of that name of the generated mocks,
+// with CallExpectation calls, so the test shows up among the callers of
+// an interface method the mocks implement.
+func (a *Analyzer) recordExpectation(fset *token.FileSet, locks []lockRegion, call *ast.CallExpr, caller *Function) {
+	if !caller.IsTest {
+		return
+	}
+	method := expectedMethod(call)
+	if method == "" {
+		return
+	}
+
+	var mocks []*Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.IsMock && fn.Name == method && fn.Receiver != "" && !isMockHelper(receiverType(fn.Receiver)) {
+			mocks = append(mocks, fn)
+		}
+		return true
+	})
+	sort.Slice(mocks, func(i, j int) bool {
+		return mocks[i].Key() < mocks[j].Key()
+	})
+
+	sel := call.Fun.(*ast.SelectorExpr)
+	pos := siteAt(fset, locks, sel.Sel.Pos())
+	pos.kind, pos.via = CallExpectation, types.ExprString(sel)
+	for _, fn := range mocks {
+		a.addCallSite(caller, fn, pos)
+	}
+}
@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// moduleDir is a directory of a module found by LoadPackages: the one of
+// its go.mod file, or the analyzed directory when the go.mod is above it.
+type moduleDir struct {
+	module     string // module path
+	importPath string // import path of the directory
+}
+
+// recordModule remembers the module declared by the go.mod file at path,
+// for the files of its directory tree.
+func (a *Analyzer) recordModule(path string, data []byte) {
+	if module := modulePath(data); module != "" {
+		a.modules[filepath.Dir(path)] = moduleDir{module: module, importPath: module}
+	}
+}
+
+// modulePath returns the path in the module directive of a go.mod file.
+func modulePath(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		module := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if i := strings.Index(module, "//"); i >= 0 {
+			module = strings.TrimSpace(module[:i])
+		}
+		if unquoted, err := strconv.Unquote(module); err == nil {
+			module = unquoted
+		}
+		return module
+	}
+	return ""
+}
+
+// findEnclosingModule records the module containing dir when its go.mod
+// is in a parent directory, as when a subdirectory of a module is
+// analyzed. It only looks on the local disk.
+func (a *Analyzer) findEnclosingModule(dir string) {
+	if a.fsys != nil {
+		return
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	for child, parent := abs, filepath.Dir(abs); parent != child; child, parent = parent, filepath.Dir(parent) {
+		data, err := os.ReadFile(filepath.Join(parent, "go.mod"))
+		if err != nil {
+			continue
+		}
+		if module := modulePath(data); module != "" {
+			rel, _ := filepath.Rel(parent, abs)
+			a.modules[dir] = moduleDir{module: module, importPath: path.Join(module, filepath.ToSlash(rel))}
+		}
+		return
+	}
+}
+
+// moduleOf returns the path of the module a file belongs to and the import
+// path of its package, or "" for both when no go.mod was found for it.
+func (a *Analyzer) moduleOf(filePath string) (module, importPath string) {
+	if len(a.modules) == 0 {
+		return "", ""
+	}
+	dir := filepath.Dir(filePath)
+	for rel := "."; ; {
+		if m, ok := a.modules[dir]; ok {
+			return m.module, path.Join(m.importPath, filepath.ToSlash(rel))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		rel = filepath.Join(filepath.Base(dir), rel)
+		dir = parent
+	}
+}
+
+// recordImports remembers the packages file imports by the name they are
+// used with, so calls such as lib.Helper() can be resolved.
+func (a *Analyzer) recordImports(filePath string, file *ast.File) {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." {
+			imports[name] = importPath
+		}
+	}
+	a.imports.Store(filePath, imports)
+}
+
+// importName returns the name a package is used with when imported without
+// one: the last element of its path, skipping a major version suffix such
+// as /v2.
+func importName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	return name
+}
+
+// qualifiedCallee returns the function a call such as lib.Helper() in
+// filePath refers to, when pkgName names an imported package of the
+// analyzed modules.
+func (a *Analyzer) qualifiedCallee(filePath, pkgName, name string) *Function {
+	imports, ok := a.imports.Load(filePath)
+	if !ok {
+		return nil
+	}
+	importPath, ok := imports.(map[string]string)[pkgName]
+	if !ok {
+		return nil
+	}
+	var match *Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Name == name && fn.Receiver == "" && fn.importPath == importPath {
+			if match == nil || key.(string) < a.getFunctionKey(match) {
+				match = fn
+			}
+		}
+		return true
+	})
+	return match
+}
+
+// dependencyOf returns the import path of the third-party package a file
+// belongs to: a package below a vendor directory, or one of a module
+// copied from the module cache, whose directory is named module@version.
+// It is "" for first-party code.
+func dependencyOf(relPath, importPath string) string {
+	elems := strings.Split(filepath.ToSlash(filepath.Dir(relPath)), "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		if elems[i] == "vendor" {
+			if i == len(elems)-1 {
+				return ""
+			}
+			return strings.Join(elems[i+1:], "/")
+		}
+	}
+	for _, elem := range elems {
+		if strings.Contains(elem, "@") {
+			return importPath
+		}
+	}
+	return ""
+}
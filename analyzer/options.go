@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"io"
+	"io/fs"
+	"log/slog"
+)
+
+// Option configures an Analyzer at construction time.
+type Option func(*Analyzer)
+
+// WithPrefilter enables the Phase 2 pre-filter for the given target
+// signatures. Files whose bytes mention neither a target's identifier nor
+// any caller discovered so far are never parsed for calls.
+func WithPrefilter(targetSignatures ...string) Option {
+	return func(a *Analyzer) {
+		a.prefilterSigs = targetSignatures
+	}
+}
+
+// WithFS makes the analyzer read sources from fsys instead of the local
+// disk. The directory passed to LoadPackages is then a path within fsys.
+func WithFS(fsys fs.FS) Option {
+	return func(a *Analyzer) {
+		a.fsys = fsys
+	}
+}
+
+// WithOverlay supplies in-memory file contents, keyed by the path the file
+// has (or would have) during the walk. Overlay contents take precedence over
+// the filesystem, and overlay files missing from it are analyzed as well.
+// This lets editors analyze unsaved buffers.
+func WithOverlay(overlay map[string][]byte) Option {
+	return func(a *Analyzer) {
+		a.overlay = overlay
+	}
+}
+
+// DefaultSkipDirs are the directory names LoadPackages does not walk into
+// unless WithSkipDirs says otherwise.
+var DefaultSkipDirs = []string{"vendor", "testdata", ".git", ".work"}
+
+// WithSkipDirs replaces DefaultSkipDirs with names. Directories with one of
+// these names are not analyzed, wherever they appear below the analyzed
+// directory.
+func WithSkipDirs(names ...string) Option {
+	return func(a *Analyzer) {
+		a.skipDirs = names
+	}
+}
+
+// WithFiles restricts the analysis to the Go files whose path relative to
+// the analyzed directory matches one of patterns, as matched by path.Match
+// with ** standing for any number of directories, as in
+// "internal/**/*.go". Directories no pattern can match are not walked.
+func WithFiles(patterns ...string) Option {
+	return func(a *Analyzer) {
+		a.filePatterns = patterns
+	}
+}
+
+// WithSourceFiles makes LoadPackages analyze the given files, as listed
+// by a build system such as Bazel, instead of walking the analyzed
+// directory. Files may lie outside of it, as generated and external
+// sources do; their paths are then relative to it all the same.
+func WithSourceFiles(paths ...string) Option {
+	return func(a *Analyzer) {
+		a.sourceFiles = paths
+	}
+}
+
+// WithFollowSymlinks makes LoadPackages descend into symbolic links to
+// directories, as found in bazel and monorepo layouts. It has no effect
+// with WithFS.
+func WithFollowSymlinks() Option {
+	return func(a *Analyzer) {
+		a.symlinks = true
+	}
+}
+
+// WithAllPlatforms unites the call graphs of the files of every GOOS and
+// GOARCH: functions declared in platform specific files carry the
+// constraint in Constraint, and a call to a function with one variant per
+// platform reaches each variant that can be built with the caller.
+func WithAllPlatforms() Option {
+	return func(a *Analyzer) {
+		a.allPlatforms = true
+	}
+}
+
+// WithHostPlatform restricts the analysis to the files the host GOOS and
+// GOARCH build, as go build does. Other build tags, such as
+// "integration", do not exclude a file. It has no effect with
+// WithAllPlatforms.
+func WithHostPlatform() Option {
+	return func(a *Analyzer) {
+		a.hostOnly = true
+	}
+}
+
+// WithEntryPatterns recognizes the registrations described by patterns, in
+// addition to BuiltinEntryPatterns, so that the handlers registered with
+// in-house or less common frameworks are labeled as entry points.
+func WithEntryPatterns(patterns ...EntryPattern) Option {
+	return func(a *Analyzer) {
+		a.entryPatterns = append(a.entryPatterns[:len(a.entryPatterns):len(a.entryPatterns)], patterns...)
+	}
+}
+
+// WithResolvers adds resolvers to those registered with RegisterResolver,
+// such as the ones loaded from plugins.
+func WithResolvers(resolvers ...CallResolver) Option {
+	return func(a *Analyzer) {
+		a.resolvers = append(a.resolvers, resolvers...)
+	}
+}
+
+// Receiver kinds accepted by WithReceiverKind.
+const (
+	ReceiverPointer = "pointer"
+	ReceiverValue   = "value"
+)
+
+// WithReceiverKind restricts the functions FindFunction matches to methods
+// with a pointer (ReceiverPointer) or value (ReceiverValue) receiver, for
+// when a signature matches methods of several types.
+func WithReceiverKind(kind string) Option {
+	return func(a *Analyzer) {
+		a.receiverKind = kind
+	}
+}
+
+// WithLogger makes LoadPackages report its progress to logger instead of
+// slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *Analyzer) {
+		a.logger = logger
+	}
+}
+
+// WithProgress makes LoadPackages draw progress bars on w while it extracts
+// functions and builds the call graph. Bars redraw their line with a
+// carriage return, so w should be a terminal.
+func WithProgress(w io.Writer) Option {
+	return func(a *Analyzer) {
+		a.progress = w
+	}
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import "sort"
+
+// The analysis runs on several workers, so the order in which functions and
+// calls are discovered varies between runs. The helpers below make every
+// result independent of that order.
+
+// storeFunction records fn under its key. When two declarations share a
+// key, e.g. the same function in files with different build tags, the one
+// in the first file by path wins whichever worker stores it last.
+func (a *Analyzer) storeFunction(fn *Function) {
+	key := a.getFunctionKey(fn)
+	for {
+		existing, loaded := a.functions.LoadOrStore(key, fn)
+		if !loaded || existing.(*Function).FullPath <= fn.FullPath {
+			return
+		}
+		if a.functions.CompareAndSwap(key, existing, fn) {
+			return
+		}
+	}
+}
+
+// sortCallSites puts the call sites of every callee in source order: by
+// caller file, then line and column.
+func (a *Analyzer) sortCallSites() {
+	a.callGraph.Range(func(key, value interface{}) bool {
+		callSites := value.([]*CallSite)
+		sort.SliceStable(callSites, func(i, j int) bool {
+			return CallSiteLess(callSites[i], callSites[j])
+		})
+		return true
+	})
+}
+
+// CallSiteLess orders call sites by caller file, line and column, then by
+// caller and callee key for calls sharing a position.
+func CallSiteLess(a, b *CallSite) bool {
+	if a.Caller.FullPath != b.Caller.FullPath {
+		return a.Caller.FullPath < b.Caller.FullPath
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	if a.Column != b.Column {
+		return a.Column < b.Column
+	}
+	if ka, kb := a.Caller.Key(), b.Caller.Key(); ka != kb {
+		return ka < kb
+	}
+	return a.Callee.Key() < b.Callee.Key()
+}
@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// recordBodyFacts notes the properties of fn that audits ask about while
+// its body is walked for calls: panics, deferred recovers, and unsafe and
+// cgo uses.
+func (a *Analyzer) recordBodyFacts(n ast.Node, fn *Function) {
+	a.recordUnsafe(n, fn)
+	switch node := n.(type) {
+	case *ast.CallExpr:
+		if isBuiltinCall(node, "panic") {
+			a.panics.Store(a.getFunctionKey(fn), true)
+		}
+	case *ast.DeferStmt:
+		// Only a deferred literal calling recover stops a panic in fn
+		if lit, ok := node.Call.Fun.(*ast.FuncLit); ok && callsBuiltin(lit.Body, "recover") {
+			a.recovers.Store(a.getFunctionKey(fn), true)
+		}
+	}
+}
+
+// Panics reports whether fn calls panic directly.
+func (a *Analyzer) Panics(fn *Function) bool {
+	_, ok := a.panics.Load(a.getFunctionKey(fn))
+	return ok
+}
+
+// Recovers reports whether fn defers a function literal that calls
+// recover, so panics raised below it do not propagate to its callers.
+func (a *Analyzer) Recovers(fn *Function) bool {
+	_, ok := a.recovers.Load(a.getFunctionKey(fn))
+	return ok
+}
+
+// IsExported reports whether fn is part of its package's API: an exported
+// function, or an exported method of an exported type.
+func (fn *Function) IsExported() bool {
+	if !ast.IsExported(fn.Name) {
+		return false
+	}
+	if fn.Receiver == "" {
+		return true
+	}
+	recv := strings.TrimPrefix(fn.Receiver, "*")
+	if i := strings.Index(recv, "["); i >= 0 {
+		recv = recv[:i]
+	}
+	return ast.IsExported(recv)
+}
+
+func isBuiltinCall(call *ast.CallExpr, name string) bool {
+	ident, ok := call.Fun.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+func callsBuiltin(body *ast.BlockStmt, name string) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && isBuiltinCall(call, name) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
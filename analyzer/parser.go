@@ -1,11 +1,15 @@
 package analyzer
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
-	"go/parser"
+	godoc "go/doc"
 	"go/token"
+	"io"
 	"io/fs"
+	"log/slog"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -15,39 +19,102 @@ import (
 )
 
 type Function struct {
-	Name       string
-	Receiver   string
-	Signature  string
-	Package    string
-	File       string
-	Line       int
-	IsTest     bool
-	FullPath   string
-	Parameters string
+	Name        string
+	Receiver    string
+	PointerRecv bool // the receiver is a pointer, as in (*Service)
+	Signature   string
+	Package     string
+	File        string
+	Line        int // position of the func keyword
+	Column      int
+	EndLine     int // position of the last character, usually the closing brace
+	EndColumn   int
+	IsTest      bool
+	TestKind    string // one of the TestKind constants when IsTest
+	IsGenerated bool   // declared in a "Code generated ... DO NOT EDIT." file
+	IsMock      bool   // declared in a mock generated by mockgen or mockery
+	IsWrapper   bool   // only forwards its parameters to another call, see isWrapper
+	Complexity  int    // cyclomatic complexity of the body, see cyclomatic
+	Statements  int    // statements of the body, see countStatements
+	FullPath    string
+	Parameters  string
+	Results     string // result list as written, e.g. "error" or "(int, error)"; "" for none
+	Doc         string // first sentence of the doc comment, if any
+	Constraint  string // platform build constraint of the file, with WithAllPlatforms
+	Module      string // path of the module declaring it, "" without a go.mod
+	Dependency  string // import path of its third-party package, "" for first-party code
+	shape       string // parameter and result types, see funcShape
+	importPath  string // import path of the package, "" without a go.mod
 }
 
 type CallSite struct {
-	Caller *Function
-	Callee *Function
+	Caller       *Function
+	Callee       *Function
+	Line         int      // position of the callee name at the call, in Caller.FullPath
+	Column       int
+	HeldLock     string   // mutex locked around the call, e.g. "s.mu"
+	FreshContext int      // line of the context.Background or TODO call creating a context passed to the call, 0 for none
+	Kind         string   // how the call is made, one of the Call kind constants
+	Via          string   // for CallSynthetic, how the call is made as declared, e.g. "RPC"; for CallResolved, the resolver; for CallInjected, the container function
+	Confidence   float64  // for CallResolved, the resolver's confidence in the callee, from 0 to 1
+	Args         []string // argument expressions as written, once captured by Arguments
+	Constants    []string // value of each of Args known statically, as Go source, "" for the others
 }
 
 type Analyzer struct {
 	functions     sync.Map // thread-safe map[string]*Function
 	callGraph     sync.Map // thread-safe map[string][]*CallSite
 	callGraphMu   sync.Mutex // mutex for callGraph modifications
-	fileSet       *token.FileSet
+	panics        sync.Map   // keys of functions calling panic
+	recovers      sync.Map   // keys of functions deferring a recover
+	unsafeUses    sync.Map   // map[string]bool of unsafe and cgo uses by function key, see recordUnsafe
+	suppressions  sync.Map   // rules ignored by line, by file path, see recordSuppressions
+	tags          sync.Map   // tags by line, by file path, see recordTags
+	sources       sync.Map   // files parsed again after the analysis, by path
+	generated     sync.Map   // paths of files with a generated code header
+	mocks         sync.Map   // paths of the mocks generated by mockgen or mockery
+	interfaces    sync.Map   // names of the interface types declared
+	ifaceDecls    sync.Map   // *Interface by package path and name
+	dispatch      sync.Map   // implementations reached through an interface, see dispatchTargets
+	constraints   sync.Map   // platform build constraints by file path, with WithAllPlatforms
+	entryPoints   sync.Map   // []EntryPoint by function key, see recordRegistration
+	entryMu       sync.Mutex // guards appends to entryPoints
+	entryFuncs    sync.Map   // *Function by key, for the functions in entryPoints
+	constants     sync.Map   // constantDecl by package and name, see recordConstants
+	requests      sync.Map   // clientRequest by caller and position, see recordRequest
+	providerSets  sync.Map   // *Function holding a package variable, see recordProviderSets
+	entryPatterns []EntryPattern // BuiltinEntryPatterns and those of WithEntryPatterns
+	resolvers     []CallResolver // those of RegisterResolver and WithResolvers
 	baseDir       string
-	targetSig     string
+	fsys          fs.FS             // source filesystem, nil for the local disk
+	overlay       map[string][]byte // in-memory file contents by path
+	symlinks      bool              // walk into symbolic links to directories
+	allPlatforms  bool              // analyze files of every GOOS and GOARCH
+	hostOnly      bool              // skip files the host platform does not build
+	modules       map[string]moduleDir // modules by go.mod directory, filled by the walk
+	imports       sync.Map   // imported packages by name, by file path
+	skipDirs      []string          // directory names never walked into
+	filePatterns  []string          // globs of the files to analyze, see WithFiles
+	sourceFiles   []string          // files to analyze instead of walking, see WithSourceFiles
+	receiverKind  string            // ReceiverPointer or ReceiverValue to restrict matches to
+	prefilterSigs []string
 	targetFound   atomic.Bool
 	filesScanned  atomic.Int32
 	funcsFound    atomic.Int32
 	progressMu    sync.Mutex // mutex for progress bar updates
+	progress      io.Writer  // where progress bars are drawn, nil for none
+	logger        *slog.Logger
 }
 
-func NewAnalyzer() *Analyzer {
-	return &Analyzer{
-		fileSet: token.NewFileSet(),
+func NewAnalyzer(opts ...Option) *Analyzer {
+	a := &Analyzer{skipDirs: DefaultSkipDirs, entryPatterns: BuiltinEntryPatterns, resolvers: registeredResolvers()}
+	for _, opt := range opts {
+		opt(a)
 	}
+	if a.logger == nil {
+		a.logger = slog.Default()
+	}
+	return a
 }
 
 // renderProgressBar creates a visual progress bar
@@ -74,27 +141,62 @@ func renderProgressBar(current, total int, label string, width int) string {
 		label, bar, current, total, percentage*100)
 }
 
+// showProgress draws a progress bar on the WithProgress writer, if any.
+// The bar is ended with a newline once current reaches total.
+func (a *Analyzer) showProgress(current, total int, label string) {
+	if a.progress == nil {
+		return
+	}
+	a.progressMu.Lock()
+	defer a.progressMu.Unlock()
+	fmt.Fprint(a.progress, renderProgressBar(current, total, label, 40))
+	if current >= total {
+		fmt.Fprintln(a.progress)
+	}
+}
+
 func (a *Analyzer) LoadPackages(dir string) error {
 	a.baseDir = dir
+	a.modules = make(map[string]moduleDir)
+	a.findEnclosingModule(dir)
+	for _, pattern := range a.filePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file pattern %q", pattern)
+		}
+	}
 	
-	fmt.Println("Scanning for Go files...")
+	a.logger.Info("scanning for Go files", "dir", dir)
 	
 	var allFiles []string
-	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+	walk := a.walkDir
+	if a.sourceFiles != nil {
+		walk = a.walkSourceFiles
+	}
+	err := walk(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
 		
-		if strings.Contains(path, "vendor/") || strings.Contains(path, ".git/") || 
-		   strings.Contains(path, "testdata/") || strings.Contains(path, ".work/") {
-			return filepath.SkipDir
+		// Skip by directory name only: a file never ends the walk of its
+		// directory, and the analyzed directory itself is never skipped
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			if path != dir && (a.skipsDir(d.Name()) || !a.includesDir(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 		
-		if strings.HasSuffix(path, ".go") && 
-		   !strings.HasSuffix(path, ".pb.go") && 
-		   !strings.HasSuffix(path, "_gen.go") {
+		// Generated files are analyzed too; their functions are marked
+		// IsGenerated so output filters can decide
+		if strings.HasSuffix(path, ".go") && a.includesFile(rel) {
 			allFiles = append(allFiles, path)
 		}
+		if d.Name() == "go.mod" {
+			if data, err := a.readFile(path); err == nil {
+				a.recordModule(path, data)
+			}
+		}
 		
 		return nil
 	})
@@ -102,12 +204,13 @@ func (a *Analyzer) LoadPackages(dir string) error {
 	if err != nil {
 		return err
 	}
+	allFiles = append(allFiles, a.overlayFiles(dir, allFiles)...)
 	
-	fmt.Printf("Found %d Go files to analyze\n", len(allFiles))
+	a.logger.Info("found Go files to analyze", "files", len(allFiles))
 	
 	// Phase 1: Parse all function definitions in parallel
 	numWorkers := runtime.NumCPU() * 2
-	fmt.Printf("Phase 1: Extracting functions with %d workers...\n", numWorkers)
+	a.logger.Info("phase 1: extracting functions", "workers", numWorkers)
 	
 	fileChan := make(chan string, len(allFiles))
 	var wg sync.WaitGroup
@@ -118,13 +221,14 @@ func (a *Analyzer) LoadPackages(dir string) error {
 		go func(workerID int) {
 			defer wg.Done()
 			for filePath := range fileChan {
-				a.parseFileFunctionDefs(filePath)
+				// Each file gets its own FileSet so parsing never contends on
+				// a shared one and memory does not grow with the repository;
+				// positions are resolved to lines before the set is dropped.
+				a.parseFileFunctionDefs(token.NewFileSet(), filePath)
 				count := int(a.filesScanned.Add(1))
 				// Update progress bar more frequently for smoother animation
-				if count%10 == 0 || count == len(allFiles) {
-					a.progressMu.Lock()
-					fmt.Printf("\r%s", renderProgressBar(count, len(allFiles), "  Extracting", 40))
-					a.progressMu.Unlock()
+				if count%10 == 0 && count < len(allFiles) {
+					a.showProgress(count, len(allFiles), "  Extracting")
 				}
 			}
 		}(i)
@@ -140,52 +244,135 @@ func (a *Analyzer) LoadPackages(dir string) error {
 	wg.Wait()
 	
 	// Final progress bar at 100%
-	fmt.Printf("\r%s", renderProgressBar(len(allFiles), len(allFiles), "  Extracting", 40))
-	fmt.Println() // New line after progress bar
-	fmt.Printf("Phase 1 complete: %d functions found\n", a.funcsFound.Load())
+	a.showProgress(len(allFiles), len(allFiles), "  Extracting")
+	a.logger.Info("phase 1 complete", "functions", a.funcsFound.Load())
 	
 	// Phase 2: Build call graph in parallel
-	fmt.Printf("Phase 2: Building call graph with %d workers...\n", numWorkers)
+	a.logger.Info("phase 2: building call graph", "workers", numWorkers)
+	
+	if len(a.prefilterSigs) > 0 {
+		a.buildCallGraphFiltered(allFiles, numWorkers)
+	} else {
+		a.parseCallGraphFiles(allFiles, numWorkers)
+	}
+	a.sortCallSites()
 	
+	return nil
+}
+
+// parseCallGraphFiles runs the Phase 2 workers over the given files.
+func (a *Analyzer) parseCallGraphFiles(files []string, numWorkers int) {
 	a.filesScanned.Store(0) // Reset counter
-	fileChan2 := make(chan string, len(allFiles))
+	fileChan := make(chan string, len(files))
+	var wg sync.WaitGroup
 	
 	// Start workers for call graph building
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			for filePath := range fileChan2 {
-				a.parseFileCallGraph(filePath)
+			for filePath := range fileChan {
+				a.parseFileCallGraph(token.NewFileSet(), filePath)
 				count := int(a.filesScanned.Add(1))
 				// Update progress bar more frequently for smoother animation
-				if count%10 == 0 || count == len(allFiles) {
-					a.progressMu.Lock()
-					fmt.Printf("\r%s", renderProgressBar(count, len(allFiles), "  Building", 40))
-					a.progressMu.Unlock()
+				if count%10 == 0 && count < len(files) {
+					a.showProgress(count, len(files), "  Building")
 				}
 			}
 		}(i)
 	}
 	
-	// Send files to workers again
-	for _, file := range allFiles {
-		fileChan2 <- file
+	for _, file := range files {
+		fileChan <- file
 	}
-	close(fileChan2)
+	close(fileChan)
 	
 	// Wait for all workers to finish
 	wg.Wait()
 	
 	// Final progress bar at 100%
-	fmt.Printf("\r%s", renderProgressBar(len(allFiles), len(allFiles), "  Building", 40))
-	fmt.Println() // New line after progress bar
+	a.showProgress(len(files), len(files), "  Building")
+}
+
+// buildCallGraphFiltered builds the call graph in rounds, only parsing files
+// that mention an identifier of the current frontier. The frontier starts at
+// the target functions and grows with the callers found in each round, until
+// no unparsed file mentions any of them.
+func (a *Analyzer) buildCallGraphFiltered(files []string, numWorkers int) {
+	traced := make(map[string]bool)
+	var frontier []string
+	for _, sig := range a.prefilterSigs {
+		name := a.parseSignature(a.normalizeSignature(sig)).name
+		if !traced[name] {
+			traced[name] = true
+			frontier = append(frontier, name)
+		}
+	}
+	pending := files
 	
-	return nil
+	for round := 1; len(frontier) > 0 && len(pending) > 0; round++ {
+		var matched, rest []string
+		for _, file := range pending {
+			if a.fileMentions(file, frontier) {
+				matched = append(matched, file)
+			} else {
+				rest = append(rest, file)
+			}
+		}
+		a.logger.Info("pre-filter round", "round", round, "matched", len(matched),
+			"remaining", len(pending), "identifiers", len(frontier))
+		if len(matched) == 0 {
+			break
+		}
+		
+		a.parseCallGraphFiles(matched, numWorkers)
+		pending = rest
+		frontier = a.expandCallerNames(traced)
+	}
+}
+
+// expandCallerNames adds to traced the names of every function that calls a
+// traced function, and returns the newly added identifiers. Anonymous
+// functions are followed through but never returned since they cannot be
+// searched for by name.
+func (a *Analyzer) expandCallerNames(traced map[string]bool) []string {
+	var added []string
+	for changed := true; changed; {
+		changed = false
+		a.callGraph.Range(func(key, value interface{}) bool {
+			for _, cs := range value.([]*CallSite) {
+				if !traced[cs.Callee.Name] || traced[cs.Caller.Name] {
+					continue
+				}
+				traced[cs.Caller.Name] = true
+				changed = true
+				if !strings.Contains(cs.Caller.Name, " ") {
+					added = append(added, cs.Caller.Name)
+				}
+			}
+			return true
+		})
+	}
+	sort.Strings(added)
+	return added
+}
+
+// fileMentions reports whether the file contains any of the identifiers.
+func (a *Analyzer) fileMentions(filePath string, identifiers []string) bool {
+	data, err := a.readFile(filePath)
+	if err != nil {
+		return false
+	}
+	for _, ident := range identifiers {
+		if bytes.Contains(data, []byte(ident)) {
+			return true
+		}
+	}
+	return false
 }
 
-func (a *Analyzer) parseFileFunctionDefs(filePath string) {
-	src, err := parser.ParseFile(a.fileSet, filePath, nil, 0)
+func (a *Analyzer) parseFileFunctionDefs(fset *token.FileSet, filePath string) {
+	src, err := a.parseFile(fset, filePath)
 	if err != nil {
 		return
 	}
@@ -193,21 +380,29 @@ func (a *Analyzer) parseFileFunctionDefs(filePath string) {
 	packagePath := a.getPackagePath(filePath)
 	relPath, _ := filepath.Rel(a.baseDir, filePath)
 	
+	a.recordInterfaces(fset, src, packagePath, relPath)
+	a.recordImports(filePath, src)
+	a.recordConstants(src, packagePath, filePath)
+	a.recordProviderSets(fset, src, packagePath, relPath)
+	a.recordSuppressions(fset, src, filePath, relPath)
+	a.recordTags(fset, src, filePath, relPath)
+	
 	// Extract all function definitions
 	for _, decl := range src.Decls {
 		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-			fn := a.createFunction(funcDecl, packagePath, relPath)
+			fn := a.createFunction(fset, funcDecl, packagePath, relPath)
 			if fn != nil {
-				key := a.getFunctionKey(fn)
-				a.functions.Store(key, fn)
+				fn.IsGenerated = a.isGeneratedFile(filePath)
+				fn.IsMock = a.isMockFile(filePath)
+				a.storeFunction(fn)
 				a.funcsFound.Add(1)
 			}
 		}
 	}
 }
 
-func (a *Analyzer) parseFileCallGraph(filePath string) {
-	src, err := parser.ParseFile(a.fileSet, filePath, nil, 0)
+func (a *Analyzer) parseFileCallGraph(fset *token.FileSet, filePath string) {
+	src, err := a.parseFile(fset, filePath)
 	if err != nil {
 		return
 	}
@@ -219,8 +414,10 @@ func (a *Analyzer) parseFileCallGraph(filePath string) {
 	var localFunctions []*Function
 	for _, decl := range src.Decls {
 		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-			fn := a.createFunction(funcDecl, packagePath, relPath)
+			fn := a.createFunction(fset, funcDecl, packagePath, relPath)
 			if fn != nil {
+				fn.IsGenerated = a.isGeneratedFile(filePath)
+				fn.IsMock = a.isMockFile(filePath)
 				localFunctions = append(localFunctions, fn)
 			}
 		}
@@ -229,12 +426,16 @@ func (a *Analyzer) parseFileCallGraph(filePath string) {
 	// Analyze function bodies for calls
 	for _, decl := range src.Decls {
 		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-			caller := a.createFunction(funcDecl, packagePath, relPath)
+			caller := a.createFunction(fset, funcDecl, packagePath, relPath)
 			if caller != nil {
-				a.analyzeFunctionBody(funcDecl, caller, localFunctions)
+				caller.IsGenerated = a.isGeneratedFile(filePath)
+				caller.IsMock = a.isMockFile(filePath)
+				a.analyzeFunctionBody(fset, funcDecl, caller, localFunctions)
 			}
 		}
 	}
+	a.recordPackageCommands(fset, src, packagePath, relPath, localFunctions)
+	a.recordPackageInjections(fset, src, packagePath, localFunctions)
 }
 
 func (a *Analyzer) getPackagePath(filePath string) string {
@@ -251,27 +452,49 @@ func (a *Analyzer) getPackagePath(filePath string) string {
 	return relPath
 }
 
-func (a *Analyzer) createFunction(fn *ast.FuncDecl, packagePath, relPath string) *Function {
+func (a *Analyzer) createFunction(fset *token.FileSet, fn *ast.FuncDecl, packagePath, relPath string) *Function {
 	if fn == nil {
 		return nil
 	}
 	
-	pos := a.fileSet.Position(fn.Pos())
+	pos := fset.Position(fn.Pos())
+	end := fset.Position(fn.End() - 1)
 	
 	f := &Function{
 		Name:       fn.Name.Name,
 		Package:    packagePath,
 		File:       filepath.Base(relPath),
 		Line:       pos.Line,
+		Column:     pos.Column,
+		EndLine:    end.Line,
+		EndColumn:  end.Column,
 		IsTest:     a.isTestFunction(fn, relPath),
 		FullPath:   relPath,
 		Parameters: a.extractParameters(fn),
+		Results:    a.extractResults(fn.Type),
+		Constraint: a.fileConstraintOf(pos.Filename),
+		shape:      a.funcShape(fn.Type),
+		Doc:        docSynopsis(fn.Doc),
+		IsWrapper:  isWrapper(fn),
+		Complexity: cyclomatic(fn.Body),
+		Statements: countStatements(fn.Body),
+	}
+	
+	f.Module, f.importPath = a.moduleOf(pos.Filename)
+	if f.Dependency = dependencyOf(relPath, f.importPath); f.Dependency != "" {
+		// Vendored packages are imported by their own path
+		f.importPath = f.Dependency
+	}
+	
+	if f.IsTest {
+		f.TestKind = classifyTest(fn)
 	}
 	
 	// Extract receiver
 	if fn.Recv != nil && len(fn.Recv.List) > 0 {
 		recv := fn.Recv.List[0]
 		f.Receiver = a.formatType(recv.Type)
+		_, f.PointerRecv = recv.Type.(*ast.StarExpr)
 	}
 	
 	// Build signature
@@ -280,6 +503,14 @@ func (a *Analyzer) createFunction(fn *ast.FuncDecl, packagePath, relPath string)
 	return f
 }
 
+// docSynopsis returns the first sentence of a doc comment, or "".
+func docSynopsis(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return new(godoc.Package).Synopsis(doc.Text())
+}
+
 func (a *Analyzer) extractParameters(fn *ast.FuncDecl) string {
 	var params []string
 	if fn.Type.Params != nil {
@@ -314,6 +545,32 @@ func (a *Analyzer) extractParametersFromFuncLit(fn *ast.FuncLit) string {
 	return fmt.Sprintf("(%s)", strings.Join(params, ", "))
 }
 
+// extractResults formats the result list of a function type the way it
+// is written: bare for a single unnamed result, parenthesized otherwise.
+func (a *Analyzer) extractResults(ft *ast.FuncType) string {
+	if ft.Results == nil || len(ft.Results.List) == 0 {
+		return ""
+	}
+	
+	var results []string
+	named := false
+	for _, field := range ft.Results.List {
+		resultType := a.formatType(field.Type)
+		if len(field.Names) > 0 {
+			named = true
+			for _, name := range field.Names {
+				results = append(results, fmt.Sprintf("%s %s", name.Name, resultType))
+			}
+		} else {
+			results = append(results, resultType)
+		}
+	}
+	if len(results) == 1 && !named {
+		return results[0]
+	}
+	return fmt.Sprintf("(%s)", strings.Join(results, ", "))
+}
+
 func (a *Analyzer) buildSignature(fn *ast.FuncDecl) string {
 	var parts []string
 	parts = append(parts, "func")
@@ -372,82 +629,150 @@ func (a *Analyzer) buildSignature(fn *ast.FuncDecl) string {
 	return strings.Join(parts, " ")
 }
 
-func (a *Analyzer) analyzeFunctionBody(fn *ast.FuncDecl, caller *Function, localFuncs []*Function) {
+func (a *Analyzer) analyzeFunctionBody(fset *token.FileSet, fn *ast.FuncDecl, caller *Function, localFuncs []*Function) {
 	if fn.Body == nil {
 		return
 	}
 	
+	locks := lockedRegions(fn.Body)
+	kinds := callKinds(fn.Body)
+	fresh := freshContextArgs(fset, fn.Body)
+	a.recordCommands(fset, fn.Body, caller, localFuncs)
 	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		a.recordBodyFacts(n, caller)
 		switch node := n.(type) {
 		case *ast.CallExpr:
-			a.processCallExpr(node, caller, localFuncs)
+			a.processCallExpr(fset, locks, kinds[node], fresh[node], node, caller, localFuncs)
 		case *ast.FuncLit:
-			anonFunc := a.createAnonymousFunction(node, caller)
+			anonFunc := a.createAnonymousFunction(fset, node, caller)
 			if anonFunc != nil {
-				a.addCallSite(caller, anonFunc)
-				a.analyzeAnonFunctionBody(node, anonFunc, localFuncs)
+				// A literal registered as a handler is called by the
+				// framework, not by the registering function
+				if !a.servedByFramework(anonFunc) {
+					pos := siteAt(fset, locks, node.Pos())
+					pos.kind = kinds[node]
+					a.addCallSite(caller, anonFunc, pos.as(CallCallback))
+				}
+				a.analyzeAnonFunctionBody(fset, node, anonFunc, localFuncs)
 			}
+			// Calls inside the literal belong to the anonymous function only:
+			// the enclosing function reaches them through the call site to
+			// the literal, and crediting it as well would count each of
+			// them twice now that every call site is recorded
+			return false
 		}
 		return true
 	})
 }
 
-func (a *Analyzer) analyzeAnonFunctionBody(fn *ast.FuncLit, caller *Function, localFuncs []*Function) {
+func (a *Analyzer) analyzeAnonFunctionBody(fset *token.FileSet, fn *ast.FuncLit, caller *Function, localFuncs []*Function) {
+	locks := lockedRegions(fn.Body)
+	kinds := callKinds(fn.Body)
+	fresh := freshContextArgs(fset, fn.Body)
+	a.recordCommands(fset, fn.Body, caller, localFuncs)
 	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		a.recordBodyFacts(n, caller)
 		switch node := n.(type) {
 		case *ast.CallExpr:
-			a.processCallExpr(node, caller, localFuncs)
+			a.processCallExpr(fset, locks, kinds[node], fresh[node], node, caller, localFuncs)
 		case *ast.FuncLit:
-			anonFunc := a.createAnonymousFunction(node, caller)
+			anonFunc := a.createAnonymousFunction(fset, node, caller)
 			if anonFunc != nil {
-				a.addCallSite(caller, anonFunc)
-				a.analyzeAnonFunctionBody(node, anonFunc, localFuncs)
+				// A literal registered as a handler is called by the
+				// framework, not by the registering function
+				if !a.servedByFramework(anonFunc) {
+					pos := siteAt(fset, locks, node.Pos())
+					pos.kind = kinds[node]
+					a.addCallSite(caller, anonFunc, pos.as(CallCallback))
+				}
+				a.analyzeAnonFunctionBody(fset, node, anonFunc, localFuncs)
 			}
+			// Calls inside the literal belong to the anonymous function only:
+			// the enclosing function reaches them through the call site to
+			// the literal, and crediting it as well would count each of
+			// them twice now that every call site is recorded
+			return false
 		}
 		return true
 	})
 }
 
-func (a *Analyzer) processCallExpr(call *ast.CallExpr, caller *Function, localFuncs []*Function) {
+// processCallExpr records the calls made by call. kind is CallGo or
+// CallDefer for the call of a go or defer statement, "" otherwise.
+// freshContext is the line of the context.Background or TODO call creating
+// a context that call is passed, 0 for none.
+func (a *Analyzer) processCallExpr(fset *token.FileSet, locks []lockRegion, kind string, freshContext int, call *ast.CallExpr, caller *Function, localFuncs []*Function) {
 	switch fun := call.Fun.(type) {
 	case *ast.Ident:
 		// Direct function call
 		targetName := fun.Name
+		pos := siteAt(fset, locks, fun.Pos())
+		pos.kind = kind
+		pos.freshContext = freshContext
 		
 		// First check local functions in same file
 		found := false
 		for _, fn := range localFuncs {
 			if fn.Name == targetName && fn.Receiver == "" {
-				a.addCallSite(caller, fn)
+				a.addCallSite(caller, fn, pos.as(CallDirect))
 				found = true
 				break
 			}
 		}
 		
-		// If not found locally, search globally
+		// If not found locally, search globally, taking the smallest key
+		// so the choice does not depend on map order
 		if !found {
+			var match *Function
 			a.functions.Range(func(key, value interface{}) bool {
 				fn := value.(*Function)
 				if fn.Name == targetName && fn.Receiver == "" {
-					a.addCallSite(caller, fn)
-					return false // Stop searching after first match
+					if match == nil || key.(string) < a.getFunctionKey(match) {
+						match = fn
+					}
 				}
 				return true
 			})
+			if match != nil && match.Constraint != "" {
+				a.addPlatformVariants(caller, match, pos.as(CallDirect))
+			} else if match != nil {
+				a.addCallSite(caller, match, pos.as(CallDirect))
+			}
 		}
 		
 	case *ast.SelectorExpr:
 		// Method call: receiver.method()
 		methodName := fun.Sel.Name
+		pos := siteAt(fset, locks, fun.Sel.Pos())
+		pos.kind = kind
+		pos.freshContext = freshContext
 		
 		// Try to identify receiver type more precisely
 		receiverVar := ""
 		receiverFieldAccess := false
+		dispatched := false
 		
 		switch x := fun.X.(type) {
 		case *ast.Ident:
 			// Simple case: r.method()
 			receiverVar = x.Name
+			if fn := a.qualifiedCallee(pos.Filename, x.Name, methodName); fn != nil {
+				// Package-qualified call: pkg.Func()
+				if fn.Constraint != "" {
+					a.addPlatformVariants(caller, fn, pos.as(CallDirect))
+				} else {
+					a.addCallSite(caller, fn, pos.as(CallDirect))
+				}
+				dispatched = true
+			} else if a.interfaceParam(caller, x.Name) {
+				pos = pos.as(CallInterface)
+				// Calls through an interface parameter reach every
+				// implementation of the method
+				for _, fn := range a.dispatchTargets(caller, x.Name, methodName) {
+					a.addCallSite(caller, fn, pos)
+					dispatched = true
+				}
+			}
 		case *ast.SelectorExpr:
 			// Field access: r.field.method()
 			// This is common for embedded structs or field access
@@ -461,18 +786,21 @@ func (a *Analyzer) processCallExpr(call *ast.CallExpr, caller *Function, localFu
 		
 		
 		// Check local functions for matching methods first
-		found := false
+		found := dispatched
 		for _, fn := range localFuncs {
+			if dispatched {
+				break
+			}
 			if fn.Name == methodName && fn.Receiver != "" {
 				// If we have a receiver variable, try to match it
 				if receiverVar != "" {
 					if a.couldBeReceiver(receiverVar, fn.Receiver) {
-						a.addCallSite(caller, fn)
+						a.addCallSite(caller, fn, pos.as(CallMethod))
 						found = true
 					}
 				} else if receiverFieldAccess {
 					// For field access, be more lenient
-					a.addCallSite(caller, fn)
+					a.addCallSite(caller, fn, pos.as(CallHeuristic))
 					found = true
 				}
 			}
@@ -501,14 +829,14 @@ func (a *Analyzer) processCallExpr(call *ast.CallExpr, caller *Function, localFu
 				
 				// If we found exactly one candidate, use it
 				if len(candidates) == 1 {
-					a.addCallSite(caller, candidates[0])
+					a.addCallSite(caller, candidates[0], pos.as(CallMethod))
 					found = true
 				} else if len(candidates) > 1 {
 					// Multiple candidates - try to be more selective
 					// Prefer candidates from the same package
 					for _, fn := range candidates {
 						if fn.Package == caller.Package {
-							a.addCallSite(caller, fn)
+							a.addCallSite(caller, fn, pos.as(CallMethod))
 							found = true
 							break
 						}
@@ -516,7 +844,7 @@ func (a *Analyzer) processCallExpr(call *ast.CallExpr, caller *Function, localFu
 					
 					// If still not found, pick the first one (better than nothing)
 					if !found && len(candidates) > 0 {
-						a.addCallSite(caller, candidates[0])
+						a.addCallSite(caller, candidates[0], pos.as(CallHeuristic))
 						found = true
 					}
 				}
@@ -540,7 +868,7 @@ func (a *Analyzer) processCallExpr(call *ast.CallExpr, caller *Function, localFu
 				// Be selective - prefer methods in same or related packages
 				for _, fn := range candidates {
 					if fn.Package == caller.Package {
-						a.addCallSite(caller, fn)
+						a.addCallSite(caller, fn, pos.as(CallHeuristic))
 						found = true
 						break
 					}
@@ -549,7 +877,7 @@ func (a *Analyzer) processCallExpr(call *ast.CallExpr, caller *Function, localFu
 				// If not found in same package, look for commonly related types
 				if !found && len(candidates) == 1 {
 					// Only one candidate - probably the right one
-					a.addCallSite(caller, candidates[0])
+					a.addCallSite(caller, candidates[0], pos.as(CallHeuristic))
 					found = true
 				}
 			}
@@ -560,17 +888,29 @@ func (a *Analyzer) processCallExpr(call *ast.CallExpr, caller *Function, localFu
 	
 	// Process arguments to detect method values
 	// This handles cases like LaunchThread(b.pollForL1PriceData) where pollForL1PriceData is passed as a method value
+	registered := a.recordRegistration(fset, call, caller, localFuncs)
+	if registered == nil {
+		registered = a.recordInjection(fset, locks, call, caller, localFuncs)
+	}
+	a.recordRequest(fset, call, caller)
+	a.recordExpectation(fset, locks, call, caller)
+	a.resolveCall(fset, locks, call, caller)
 	for _, arg := range call.Args {
-		a.processMethodValue(arg, caller, localFuncs)
+		if registered[arg] {
+			continue
+		}
+		a.processMethodValue(fset, locks, arg, caller, localFuncs)
 	}
 }
 
 // processMethodValue handles method values passed as arguments (e.g., b.method in func(b.method))
-func (a *Analyzer) processMethodValue(expr ast.Expr, caller *Function, localFuncs []*Function) {
+func (a *Analyzer) processMethodValue(fset *token.FileSet, locks []lockRegion, expr ast.Expr, caller *Function, localFuncs []*Function) {
 	switch v := expr.(type) {
 	case *ast.SelectorExpr:
 		// This could be a method value: receiver.method (without parentheses)
 		methodName := v.Sel.Name
+		pos := siteAt(fset, locks, v.Sel.Pos())
+		pos.kind = CallCallback
 		
 		// Try to identify receiver type
 		receiverVar := ""
@@ -594,11 +934,11 @@ func (a *Analyzer) processMethodValue(expr ast.Expr, caller *Function, localFunc
 			if fn.Name == methodName && fn.Receiver != "" {
 				if receiverVar != "" {
 					if a.couldBeReceiver(receiverVar, fn.Receiver) {
-						a.addCallSite(caller, fn)
+						a.addCallSite(caller, fn, pos)
 						found = true
 					}
 				} else if receiverFieldAccess {
-					a.addCallSite(caller, fn)
+					a.addCallSite(caller, fn, pos)
 					found = true
 				}
 			}
@@ -625,13 +965,13 @@ func (a *Analyzer) processMethodValue(expr ast.Expr, caller *Function, localFunc
 				
 				// If we found exactly one candidate, use it
 				if len(candidates) == 1 {
-					a.addCallSite(caller, candidates[0])
+					a.addCallSite(caller, candidates[0], pos)
 					found = true
 				} else if len(candidates) > 1 {
 					// Prefer candidates from the same package
 					for _, fn := range candidates {
 						if fn.Package == caller.Package {
-							a.addCallSite(caller, fn)
+							a.addCallSite(caller, fn, pos)
 							found = true
 							break
 						}
@@ -639,7 +979,7 @@ func (a *Analyzer) processMethodValue(expr ast.Expr, caller *Function, localFunc
 					
 					// If still not found, pick the first one
 					if !found && len(candidates) > 0 {
-						a.addCallSite(caller, candidates[0])
+						a.addCallSite(caller, candidates[0], pos)
 						found = true
 					}
 				}
@@ -660,14 +1000,14 @@ func (a *Analyzer) processMethodValue(expr ast.Expr, caller *Function, localFunc
 				// Prefer methods in same package
 				for _, fn := range candidates {
 					if fn.Package == caller.Package {
-						a.addCallSite(caller, fn)
+						a.addCallSite(caller, fn, pos)
 						found = true
 						break
 					}
 				}
 				
 				if !found && len(candidates) == 1 {
-					a.addCallSite(caller, candidates[0])
+					a.addCallSite(caller, candidates[0], pos)
 					found = true
 				}
 			}
@@ -806,17 +1146,30 @@ func splitCamelCase(s string) []string {
 	return result
 }
 
-func (a *Analyzer) createAnonymousFunction(fn *ast.FuncLit, parent *Function) *Function {
-	pos := a.fileSet.Position(fn.Pos())
+func (a *Analyzer) createAnonymousFunction(fset *token.FileSet, fn *ast.FuncLit, parent *Function) *Function {
+	pos := fset.Position(fn.Pos())
+	end := fset.Position(fn.End() - 1)
 	
 	f := &Function{
-		Name:       fmt.Sprintf("func(...) in %s", parent.FullPath),
-		Package:    parent.Package,
-		File:       parent.File,
-		Line:       pos.Line,
-		IsTest:     parent.IsTest,
-		FullPath:   parent.FullPath,
-		Parameters: a.extractParametersFromFuncLit(fn),
+		Name:        fmt.Sprintf("func(...) in %s", parent.FullPath),
+		Package:     parent.Package,
+		File:        parent.File,
+		Line:        pos.Line,
+		Column:      pos.Column,
+		EndLine:     end.Line,
+		EndColumn:   end.Column,
+		IsTest:      parent.IsTest,
+		TestKind:    parent.TestKind,
+		IsGenerated: parent.IsGenerated,
+		Constraint:  parent.Constraint,
+		Module:      parent.Module,
+		importPath:  parent.importPath,
+		Dependency:  parent.Dependency,
+		FullPath:    parent.FullPath,
+		Parameters:  a.extractParametersFromFuncLit(fn),
+		Results:     a.extractResults(fn.Type),
+		Complexity:  cyclomatic(fn.Body),
+		Statements:  countStatements(fn.Body),
 	}
 	
 	// Build anonymous function signature
@@ -848,13 +1201,12 @@ func (a *Analyzer) createAnonymousFunction(fn *ast.FuncLit, parent *Function) *F
 	return f
 }
 
-func (a *Analyzer) addCallSite(caller, callee *Function) {
+func (a *Analyzer) addCallSite(caller, callee *Function, pos callContext) {
 	if caller == nil || callee == nil {
 		return
 	}
 	
 	calleeKey := a.getFunctionKey(callee)
-	callerKey := a.getFunctionKey(caller)
 	
 	// Lock to ensure atomic read-modify-write
 	a.callGraphMu.Lock()
@@ -866,17 +1218,18 @@ func (a *Analyzer) addCallSite(caller, callee *Function) {
 		callSites = existing.([]*CallSite)
 	}
 	
-	// Check if this call site already exists
-	for _, cs := range callSites {
-		if a.getFunctionKey(cs.Caller) == callerKey {
-			return
-		}
-	}
-	
-	// Add new call site
+	// Every call is recorded so repeated calls from one caller show up as
+	// its usage count
 	callSites = append(callSites, &CallSite{
-		Caller: caller,
-		Callee: callee,
+		Caller:       caller,
+		Callee:       callee,
+		Line:         pos.Line,
+		Column:       pos.Column,
+		HeldLock:     pos.heldLock,
+		FreshContext: pos.freshContext,
+		Kind:         pos.kind,
+		Via:          pos.via,
+		Confidence:   pos.confidence,
 	})
 	
 	a.callGraph.Store(calleeKey, callSites)
@@ -917,10 +1270,33 @@ func (a *Analyzer) isTestFunction(fn *ast.FuncDecl, filename string) bool {
 }
 
 func (a *Analyzer) getFunctionKey(fn *Function) string {
+	return fn.Key()
+}
+
+// Key identifies the function within an analysis. Platform variants of a
+// function, declared in files with different constraints, have distinct
+// keys.
+func (fn *Function) Key() string {
+	key := fmt.Sprintf("%s#%s#%d", fn.Package, fn.Name, fn.Line)
 	if fn.Receiver != "" {
-		return fmt.Sprintf("%s#%s.%s#%d", fn.Package, fn.Receiver, fn.Name, fn.Line)
+		key = fmt.Sprintf("%s#%s.%s#%d", fn.Package, fn.Receiver, fn.Name, fn.Line)
+	}
+	if fn.Constraint != "" {
+		key += "#" + fn.Constraint
+	}
+	return key
+}
+
+// InPackage reports whether fn belongs to pkg, given either as a directory
+// relative to the analyzed root, optionally starting with "./", or as an
+// import path ending in that directory.
+func (fn *Function) InPackage(pkg string) bool {
+	pkg = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pkg), "./"), "/")
+	own := filepath.ToSlash(fn.Package)
+	if own == pkg {
+		return true
 	}
-	return fmt.Sprintf("%s#%s#%d", fn.Package, fn.Name, fn.Line)
+	return own != "." && (strings.HasSuffix(pkg, "/"+own) || strings.HasSuffix(own, "/"+pkg))
 }
 
 func (a *Analyzer) normalizeSignature(sig string) string {
@@ -0,0 +1,196 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// EntryPattern describes a call registering a callback with a framework,
+// such as a message queue consumer or a cron job, written as
+//
+//	<kind> <receiver>.<Func>(<args>)
+//
+// for example "queue *.Subscribe(topic, handler)". The receiver is the
+// identifier the function is called on, or "*" for any; it is left out for
+// unqualified calls. Each argument is one of:
+//
+//	handler         the function registered
+//	handler.Method  a value whose Method is registered, as in cron's AddJob
+//	_               ignored
+//	...             any further arguments
+//	name            anything else names the entry point, as a topic or schedule
+//
+// Without a handler argument, the function making the call is the entry
+// point, as for a loop reading messages.
+type EntryPattern struct {
+	Kind     string
+	Receiver string
+	Func     string
+	Args     []string
+}
+
+// BuiltinEntryPatterns are the registrations of common libraries that
+// are always recognized: kafka-go readers, asynq handlers and robfig/cron
+// jobs.
+var BuiltinEntryPatterns = mustParseEntryPatterns(`
+# segmentio/kafka-go: the functions reading messages are the consumers
+Kafka *.ReadMessage(_)
+Kafka *.FetchMessage(_)
+# hibiken/asynq
+asynq *.HandleFunc(task, handler)
+asynq *.Handle(task, handler.ProcessTask)
+# robfig/cron
+cron *.AddFunc(spec, handler)
+cron *.AddJob(spec, handler.Run)
+`)
+
+// ParseEntryPatterns reads entry patterns, one per line. Blank lines and
+// lines starting with # are ignored.
+func ParseEntryPatterns(r io.Reader) ([]EntryPattern, error) {
+	var patterns []EntryPattern
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := parseEntryPattern(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, scanner.Err()
+}
+
+func mustParseEntryPatterns(text string) []EntryPattern {
+	patterns, err := ParseEntryPatterns(strings.NewReader(text))
+	if err != nil {
+		panic(err)
+	}
+	return patterns
+}
+
+// parseEntryPattern parses one line of a pattern file.
+func parseEntryPattern(line string) (EntryPattern, error) {
+	kind, call, ok := strings.Cut(line, " ")
+	call = strings.TrimSpace(call)
+	open := strings.Index(call, "(")
+	if !ok || open < 0 || !strings.HasSuffix(call, ")") {
+		return EntryPattern{}, fmt.Errorf("want <kind> <receiver>.<Func>(<args>), got %q", line)
+	}
+
+	p := EntryPattern{Kind: kind, Func: call[:open]}
+	if i := strings.LastIndex(p.Func, "."); i >= 0 {
+		p.Receiver, p.Func = p.Func[:i], p.Func[i+1:]
+	}
+	if !token.IsIdentifier(p.Func) || p.Receiver != "" && p.Receiver != "*" && !token.IsIdentifier(p.Receiver) {
+		return EntryPattern{}, fmt.Errorf("invalid function %q", call[:open])
+	}
+
+	if args := strings.TrimSpace(call[open+1 : len(call)-1]); args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			p.Args = append(p.Args, strings.TrimSpace(arg))
+		}
+	}
+	for i, arg := range p.Args {
+		if arg == "..." && i != len(p.Args)-1 {
+			return EntryPattern{}, fmt.Errorf("... must be the last argument")
+		}
+	}
+	return p, nil
+}
+
+// matches reports whether call has the function and arguments of p.
+func (p EntryPattern) matches(call *ast.CallExpr) bool {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		if p.Receiver != "" || fun.Name != p.Func {
+			return false
+		}
+	case *ast.SelectorExpr:
+		if p.Receiver == "" || fun.Sel.Name != p.Func {
+			return false
+		}
+		if x, ok := fun.X.(*ast.Ident); p.Receiver != "*" && (!ok || x.Name != p.Receiver) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	if n := len(p.Args); n > 0 && p.Args[n-1] == "..." {
+		return len(call.Args) >= n-1
+	}
+	return len(call.Args) == len(p.Args)
+}
+
+// recordPatternRegistration records the entry points of call when it
+// matches one of the entry patterns, and returns the arguments that are
+// registered handlers.
+func (a *Analyzer) recordPatternRegistration(fset *token.FileSet, call *ast.CallExpr, caller *Function, localFuncs []*Function) map[ast.Expr]bool {
+	for _, p := range a.entryPatterns {
+		if !p.matches(call) {
+			continue
+		}
+
+		at := EntryPoint{Kind: p.Kind, File: caller.FullPath, Line: fset.Position(call.Pos()).Line}
+		var handler ast.Expr
+		var method string
+		for i, arg := range p.Args {
+			switch name, m, _ := strings.Cut(arg, "."); {
+			case name == "handler":
+				handler, method = call.Args[i], m
+			case arg == "_" || arg == "...":
+			case at.Route == "":
+				at.Route = argumentText(call.Args[i])
+			}
+		}
+
+		if handler == nil {
+			// The caller itself consumes, as in a kafka-go read loop
+			if at.Route == "" {
+				at.Route = types.ExprString(call.Fun)
+			}
+			a.addEntryPoint(caller, at)
+			return nil
+		}
+
+		registered := make(map[ast.Expr]bool)
+		var handlers []*Function
+		if method == "" {
+			handlers = a.handlerFunctions(fset, handler, caller, localFuncs)
+		} else {
+			for _, fn := range a.typeMethods(handler, caller, fset.Position(call.Pos()).Filename) {
+				if fn.Name == method {
+					handlers = append(handlers, fn)
+				}
+			}
+		}
+		for _, fn := range handlers {
+			a.addEntryPoint(fn, at)
+			registered[handler] = true
+		}
+		if len(registered) > 0 {
+			return registered
+		}
+	}
+	return nil
+}
+
+// argumentText returns a string literal argument unquoted, or the source
+// of other arguments, such as a topic constant.
+func argumentText(arg ast.Expr) string {
+	if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			return s
+		}
+	}
+	return types.ExprString(arg)
+}
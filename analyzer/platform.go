@@ -0,0 +1,212 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"go/build"
+	"go/build/constraint"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// errOtherPlatform is returned by parseFile for files excluded by their
+// build constraint on the host platform.
+var errOtherPlatform = errors.New("file is not built on this platform")
+
+// knownOS and knownArch are the GOOS and GOARCH values recognized in file
+// name suffixes and build constraints, as listed by "go tool dist list".
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+		"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "arm": true, "arm64": true, "loong64": true, "mips": true,
+		"mipsle": true, "mips64": true, "mips64le": true, "ppc64": true, "ppc64le": true,
+		"riscv64": true, "s390x": true, "wasm": true,
+	}
+	// unixOS are the systems satisfying the "unix" constraint.
+	unixOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"hurd": true, "illumos": true, "ios": true, "linux": true, "netbsd": true,
+		"openbsd": true, "solaris": true,
+	}
+)
+
+// fileConstraint returns the build constraint of a source file: its
+// //go:build line combined with the GOOS and GOARCH implied by a name such
+// as foo_linux_amd64.go. It is nil for files built everywhere.
+func fileConstraint(filePath string, data []byte) constraint.Expr {
+	var expr constraint.Expr
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if constraint.IsGoBuild(line) {
+			if parsed, err := constraint.Parse(line); err == nil {
+				expr = parsed
+			}
+			break
+		}
+		// Build constraints must appear before the package clause
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+
+	for _, tag := range fileNameTags(filePath) {
+		if expr == nil {
+			expr = &constraint.TagExpr{Tag: tag}
+		} else {
+			expr = &constraint.AndExpr{X: expr, Y: &constraint.TagExpr{Tag: tag}}
+		}
+	}
+	return expr
+}
+
+// fileNameTags returns the GOOS and GOARCH a file name restricts it to.
+func fileNameTags(filePath string) []string {
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filePath), ".go"), "_test")
+	parts := strings.Split(name, "_")
+	if len(parts) < 2 {
+		return nil
+	}
+	last := parts[len(parts)-1]
+	if knownArch[last] {
+		if len(parts) >= 3 && knownOS[parts[len(parts)-2]] {
+			return []string{parts[len(parts)-2], last}
+		}
+		return []string{last}
+	}
+	if knownOS[last] {
+		return []string{last}
+	}
+	return nil
+}
+
+// platformSpecific reports whether expr mentions a GOOS or GOARCH.
+func platformSpecific(expr constraint.Expr) bool {
+	specific := false
+	expr.Eval(func(tag string) bool {
+		if knownOS[tag] || knownArch[tag] || tag == "unix" {
+			specific = true
+		}
+		return true
+	})
+	return specific
+}
+
+// buildsOnHost reports whether a file constrained by expr can be built for
+// the host GOOS and GOARCH. Other tags, such as "integration", do not
+// exclude a file: it is kept when expr holds with all of them set or with
+// none of them set.
+func buildsOnHost(expr constraint.Expr) bool {
+	if expr == nil {
+		return true
+	}
+	goos, goarch := build.Default.GOOS, build.Default.GOARCH
+	return expr.Eval(platformTags(goos, goarch, true)) || expr.Eval(platformTags(goos, goarch, false))
+}
+
+// recordConstraint evaluates the build constraint of a file read by
+// parseFile. With WithAllPlatforms the constraint is remembered when it
+// is platform specific, so functions of the file carry it. With
+// WithHostPlatform files the host cannot build are reported with
+// errOtherPlatform. Otherwise every file is analyzed as it is.
+func (a *Analyzer) recordConstraint(filePath string, data []byte) error {
+	if !a.allPlatforms && !a.hostOnly {
+		return nil
+	}
+	expr := fileConstraint(filePath, data)
+	if expr == nil {
+		return nil
+	}
+	if a.allPlatforms {
+		if platformSpecific(expr) {
+			a.constraints.Store(filePath, expr.String())
+		}
+		return nil
+	}
+	if !buildsOnHost(expr) {
+		return errOtherPlatform
+	}
+	return nil
+}
+
+// fileConstraintOf returns the platform constraint recorded for a file,
+// or "".
+func (a *Analyzer) fileConstraintOf(filePath string) string {
+	if expr, ok := a.constraints.Load(filePath); ok {
+		return expr.(string)
+	}
+	return ""
+}
+
+// addPlatformVariants adds call sites from caller to callee and to its
+// variants declared for other platforms, keeping those that can be built
+// together with caller.
+func (a *Analyzer) addPlatformVariants(caller, callee *Function, pos callContext) {
+	var variants, compatible []*Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Name == callee.Name && fn.Receiver == callee.Receiver && fn.Package == callee.Package && fn.Constraint != "" {
+			variants = append(variants, fn)
+			if buildTogether(caller.Constraint, fn.Constraint) {
+				compatible = append(compatible, fn)
+			}
+		}
+		return true
+	})
+	if len(compatible) > 0 {
+		variants = compatible
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].Key() < variants[j].Key()
+	})
+	for _, fn := range variants {
+		a.addCallSite(caller, fn, pos)
+	}
+}
+
+// buildTogether reports whether some GOOS and GOARCH satisfy both
+// constraints, as recorded in Function.Constraint.
+func buildTogether(x, y string) bool {
+	if x == "" || y == "" {
+		return true
+	}
+	ex, err1 := constraint.Parse("//go:build " + x)
+	ey, err2 := constraint.Parse("//go:build " + y)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	both := &constraint.AndExpr{X: ex, Y: ey}
+	for goos := range knownOS {
+		for goarch := range knownArch {
+			for _, others := range []bool{true, false} {
+				if both.Eval(platformTags(goos, goarch, others)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// platformTags returns the tag evaluator of a build for goos and goarch,
+// with every other tag set to others.
+func platformTags(goos, goarch string, others bool) func(tag string) bool {
+	return func(tag string) bool {
+		switch {
+		case knownOS[tag]:
+			return tag == goos || tag == "linux" && goos == "android" || tag == "solaris" && goos == "illumos" || tag == "darwin" && goos == "ios"
+		case knownArch[tag]:
+			return tag == goarch
+		case tag == "unix":
+			return unixOS[goos]
+		}
+		return others
+	}
+}
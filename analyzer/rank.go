@@ -0,0 +1,74 @@
+package analyzer
+
+import "math"
+
+// Centrality damping factor and convergence threshold, as in PageRank
+const (
+	centralityDamping   = 0.85
+	centralityTolerance = 1e-10
+	centralityMaxRounds = 100
+)
+
+// Centrality returns a PageRank score for every function of the call
+// graph, keyed by function key. Each function passes its score on to the
+// functions it calls, so a function scores high when it is called by many
+// functions that are themselves called a lot: changing it touches a large
+// part of the program. Scores sum to 1.
+func (a *Analyzer) Centrality() map[string]float64 {
+	// callees lists the distinct functions each function calls
+	callees := make(map[string]map[string]bool)
+	for key := range a.GetFunctions() {
+		callees[key] = nil
+	}
+	for calleeKey, sites := range a.GetCallGraph() {
+		if _, ok := callees[calleeKey]; !ok {
+			callees[calleeKey] = nil
+		}
+		for _, cs := range sites {
+			callerKey := cs.Caller.Key()
+			if callees[callerKey] == nil {
+				callees[callerKey] = make(map[string]bool)
+			}
+			callees[callerKey][calleeKey] = true
+		}
+	}
+
+	n := float64(len(callees))
+	rank := make(map[string]float64, len(callees))
+	for key := range callees {
+		rank[key] = 1 / n
+	}
+	for round := 0; round < centralityMaxRounds; round++ {
+		// Functions calling nothing share their score with every function
+		var dangling float64
+		for key, out := range callees {
+			if len(out) == 0 {
+				dangling += rank[key]
+			}
+		}
+		base := (1-centralityDamping)/n + centralityDamping*dangling/n
+		next := make(map[string]float64, len(callees))
+		for key := range callees {
+			next[key] = base
+		}
+		for key, out := range callees {
+			if len(out) == 0 {
+				continue
+			}
+			share := centralityDamping * rank[key] / float64(len(out))
+			for callee := range out {
+				next[callee] += share
+			}
+		}
+
+		var delta float64
+		for key := range callees {
+			delta += math.Abs(next[key] - rank[key])
+		}
+		rank = next
+		if delta < centralityTolerance {
+			break
+		}
+	}
+	return rank
+}
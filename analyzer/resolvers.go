@@ -0,0 +1,74 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"sync"
+)
+
+// CallResolver finds the callees of calls the analysis cannot see through,
+// such as the constructors an in-house dependency injection framework
+// calls or the code a generator dispatches to. Resolve is called for every
+// call of the analyzed code once all functions are known, possibly from
+// several goroutines at once.
+type CallResolver interface {
+	// Name labels the calls found, shown as [via <name>].
+	Name() string
+	Resolve(call ResolverCall) []Candidate
+}
+
+// ResolverCall is a call handed to a CallResolver.
+type ResolverCall struct {
+	Expr     *ast.CallExpr
+	Fset     *token.FileSet
+	File     string // path of the file making the call
+	Caller   *Function
+	Analyzer *Analyzer // to look callees up, as with FindFunction
+}
+
+// Candidate is a function a call may reach, with the resolver's confidence
+// that it does, from 0 to 1.
+type Candidate struct {
+	Callee     *Function
+	Confidence float64
+}
+
+var (
+	resolversMu sync.Mutex
+	resolvers   []CallResolver
+)
+
+// RegisterResolver makes r resolve calls for every Analyzer created
+// afterwards. It is meant to be called from the init function of a
+// package imported for its side effect by a custom build of gogotrace.
+func RegisterResolver(r CallResolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers = append(resolvers, r)
+}
+
+// registeredResolvers returns the resolvers of RegisterResolver.
+func registeredResolvers() []CallResolver {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	return append([]CallResolver(nil), resolvers...)
+}
+
+// resolveCall records the callees the resolvers find for call, as calls
+// of kind CallResolved labeled with the resolver's name.
+func (a *Analyzer) resolveCall(fset *token.FileSet, locks []lockRegion, call *ast.CallExpr, caller *Function) {
+	if len(a.resolvers) == 0 {
+		return
+	}
+	rc := ResolverCall{Expr: call, Fset: fset, File: fset.Position(call.Pos()).Filename, Caller: caller, Analyzer: a}
+	for _, r := range a.resolvers {
+		for _, c := range r.Resolve(rc) {
+			if c.Callee == nil {
+				continue
+			}
+			pos := siteAt(fset, locks, call.Pos())
+			pos.kind, pos.via, pos.confidence = CallResolved, r.Name(), c.Confidence
+			a.addCallSite(caller, c.Callee, pos)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of EntryPoint.
+const (
+	EntryHTTP = "HTTP"
+	EntryGRPC = "gRPC"
+	EntryCLI  = "CLI"
+)
+
+// EntryPoint is the registration of a function as a handler called from
+// outside the program, such as an HTTP route, a gRPC method or a CLI
+// command. The callers of HTTP and gRPC handlers are the framework's, so
+// their registration is recorded instead of a call from the registering
+// function; CLI command handlers keep a CallCommand call from the function
+// declaring the command.
+type EntryPoint struct {
+	Kind   string // EntryHTTP or EntryGRPC
+	Method string // HTTP method, "" for any; gRPC service
+	Route  string // HTTP path pattern or gRPC method
+	File   string // file of the registration
+	Line   int
+}
+
+// String describes the entry point, as in
+// "HTTP GET /users (registered in routes.go:42)".
+func (e EntryPoint) String() string {
+	name := e.Route
+	switch {
+	case e.Kind == EntryGRPC:
+		name = e.Method + "/" + e.Route
+	case e.Method != "":
+		name = e.Method + " " + e.Route
+	}
+	return fmt.Sprintf("%s %s (registered in %s:%d)", e.Kind, name, e.File, e.Line)
+}
+
+// httpMethods are the route registration methods of gin and echo, and
+// those of chi once upper-cased.
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true,
+	"HEAD": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// grpcRegistration matches the service registration functions generated by
+// protoc-gen-go-grpc, as in RegisterUsersServer(s, &server{}).
+var grpcRegistration = regexp.MustCompile(`^Register(\w+)Server$`)
+
+// recordRegistration recognizes call as the registration of handlers with
+// a framework and records their entry points. It returns the arguments of
+// call that are registered handlers.
+func (a *Analyzer) recordRegistration(fset *token.FileSet, call *ast.CallExpr, caller *Function, localFuncs []*Function) map[ast.Expr]bool {
+	if registered := a.recordServerRegistration(fset, call, caller, localFuncs); len(registered) > 0 {
+		return registered
+	}
+	return a.recordPatternRegistration(fset, call, caller, localFuncs)
+}
+
+// recordServerRegistration recognizes the registration of handlers with
+// net/http, gin, echo, chi or gRPC.
+func (a *Analyzer) recordServerRegistration(fset *token.FileSet, call *ast.CallExpr, caller *Function, localFuncs []*Function) map[ast.Expr]bool {
+	pos := fset.Position(call.Pos())
+	at := EntryPoint{Kind: EntryHTTP, File: caller.FullPath, Line: pos.Line}
+	var name string
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		name = fun.Sel.Name
+	case *ast.Ident:
+		// Only gRPC services are registered by a function of the package
+		if m := grpcRegistration.FindStringSubmatch(fun.Name); m != nil && len(call.Args) == 2 {
+			return a.recordService(m[1], call.Args[1], at, caller, pos.Filename)
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	var handlers []ast.Expr
+	switch {
+	case httpMethods[name] || name == "Any":
+		// gin and echo: r.GET("/users", middleware..., handler)
+		if at.Route = stringArg(call, 0); !isRoute(at.Route) {
+			return nil
+		}
+		if name != "Any" {
+			at.Method = name
+		}
+		handlers = call.Args[1:]
+	case httpMethods[strings.ToUpper(name)] && name[1:] == strings.ToLower(name[1:]):
+		// chi: r.Get("/users", handler)
+		if at.Route = stringArg(call, 0); !isRoute(at.Route) || len(call.Args) != 2 {
+			return nil
+		}
+		at.Method = strings.ToUpper(name)
+		handlers = call.Args[1:]
+	case name == "Handle" || name == "HandleFunc" || name == "Method" || name == "MethodFunc" || name == "Add":
+		if method, route := stringArg(call, 0), stringArg(call, 1); httpMethods[method] && isRoute(route) {
+			// chi r.Method, echo e.Add and gin r.Handle: ("GET", "/users", handler)
+			at.Method, at.Route = method, route
+			handlers = call.Args[2:]
+			break
+		}
+		// net/http, chi and gorilla/mux: ("GET /users", handler) or ("/users", handler)
+		pattern := stringArg(call, 0)
+		if method, route, ok := strings.Cut(pattern, " "); ok && httpMethods[method] {
+			at.Method, pattern = method, strings.TrimSpace(route)
+		}
+		if !strings.Contains(pattern, "/") || len(call.Args) != 2 {
+			return nil
+		}
+		at.Route = pattern
+		handlers = call.Args[1:]
+	default:
+		m := grpcRegistration.FindStringSubmatch(name)
+		if m == nil || len(call.Args) != 2 {
+			return nil
+		}
+		return a.recordService(m[1], call.Args[1], at, caller, pos.Filename)
+	}
+
+	registered := make(map[ast.Expr]bool)
+	for _, arg := range handlers {
+		for _, fn := range a.handlerFunctions(fset, arg, caller, localFuncs) {
+			a.addEntryPoint(fn, at)
+			registered[arg] = true
+		}
+	}
+	return registered
+}
+
+// recordService records the methods of impl, a gRPC service
+// implementation, as entry points. The methods are those of the generated
+// <service>Server interface when it is analyzed, the exported methods of
+// the type otherwise.
+func (a *Analyzer) recordService(service string, impl ast.Expr, at EntryPoint, caller *Function, filePath string) map[ast.Expr]bool {
+	methods := a.typeMethods(impl, caller, filePath)
+	if len(methods) == 0 {
+		return nil
+	}
+	iface, err := a.FindInterface(service + "Server")
+	at.Kind, at.Method = EntryGRPC, service
+	for _, fn := range methods {
+		if err == nil {
+			if _, ok := iface.Methods[fn.Name]; !ok {
+				continue
+			}
+		} else if !fn.IsExported() {
+			continue
+		}
+		at.Route = fn.Name
+		a.addEntryPoint(fn, at)
+	}
+	return map[ast.Expr]bool{impl: true}
+}
+
+// handlerFunctions returns the functions a handler argument refers to: a
+// function or method value, a function literal, http.HandlerFunc(f), or a
+// composite literal of a type with a ServeHTTP method.
+func (a *Analyzer) handlerFunctions(fset *token.FileSet, arg ast.Expr, caller *Function, localFuncs []*Function) []*Function {
+	switch h := arg.(type) {
+	case *ast.FuncLit:
+		return []*Function{a.createAnonymousFunction(fset, h, caller)}
+	case *ast.Ident:
+		if fn := a.functionNamed(h.Name, localFuncs); fn != nil {
+			return []*Function{fn}
+		}
+	case *ast.SelectorExpr:
+		x, ok := h.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		if fn := a.qualifiedCallee(fset.Position(h.Pos()).Filename, x.Name, h.Sel.Name); fn != nil {
+			return []*Function{fn}
+		}
+		if fn := a.methodValue(x.Name, h.Sel.Name, caller); fn != nil {
+			return []*Function{fn}
+		}
+	case *ast.CallExpr:
+		// http.HandlerFunc(f) is a conversion
+		if sel, ok := h.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "HandlerFunc" && len(h.Args) == 1 {
+			return a.handlerFunctions(fset, h.Args[0], caller, localFuncs)
+		}
+	case *ast.CompositeLit, *ast.UnaryExpr:
+		for _, fn := range a.typeMethods(h, caller, fset.Position(h.Pos()).Filename) {
+			if fn.Name == "ServeHTTP" {
+				return []*Function{fn}
+			}
+		}
+	}
+	return nil
+}
+
+// functionNamed returns the function called name, preferring one of
+// localFuncs and otherwise taking the smallest key, as for direct calls.
+func (a *Analyzer) functionNamed(name string, localFuncs []*Function) *Function {
+	for _, fn := range localFuncs {
+		if fn.Name == name && fn.Receiver == "" {
+			return fn
+		}
+	}
+	var match *Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Name == name && fn.Receiver == "" {
+			if match == nil || key.(string) < a.getFunctionKey(match) {
+				match = fn
+			}
+		}
+		return true
+	})
+	return match
+}
+
+// methodValue returns the method a method value such as h.List refers to,
+// matching the receiver variable to the type of the parameter of caller it
+// names, or else by name, and preferring the package of caller.
+func (a *Analyzer) methodValue(receiverVar, name string, caller *Function) *Function {
+	typ, isParam := paramType(caller, receiverVar)
+	// A type from another package is qualified: *handlers.Users
+	typ = receiverType(typ)
+	typ = typ[strings.LastIndex(typ, ".")+1:]
+	var candidates []*Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Name != name || fn.Receiver == "" {
+			return true
+		}
+		if isParam && receiverType(fn.Receiver) == typ || !isParam && a.couldBeReceiver(receiverVar, fn.Receiver) {
+			candidates = append(candidates, fn)
+		}
+		return true
+	})
+	sort.Slice(candidates, func(i, j int) bool {
+		return a.getFunctionKey(candidates[i]) < a.getFunctionKey(candidates[j])
+	})
+	for _, fn := range candidates {
+		if fn.Package == caller.Package {
+			return fn
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// typeMethods returns the methods of the type of a composite literal such
+// as server{} or &handlers.Server{} in filePath, sorted by name.
+func (a *Analyzer) typeMethods(expr ast.Expr, caller *Function, filePath string) []*Function {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = u.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+
+	var typeName, importPath string
+	switch t := lit.Type.(type) {
+	case *ast.Ident:
+		typeName = t.Name
+	case *ast.SelectorExpr:
+		x, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		imports, ok := a.imports.Load(filePath)
+		if !ok {
+			return nil
+		}
+		typeName, importPath = t.Sel.Name, imports.(map[string]string)[x.Name]
+	default:
+		return nil
+	}
+
+	var methods []*Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Receiver == "" || receiverType(fn.Receiver) != typeName {
+			return true
+		}
+		if importPath == "" && fn.Package == caller.Package || importPath != "" && fn.importPath == importPath {
+			methods = append(methods, fn)
+		}
+		return true
+	})
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Name != methods[j].Name {
+			return methods[i].Name < methods[j].Name
+		}
+		return methods[i].Key() < methods[j].Key()
+	})
+	return methods
+}
+
+// addEntryPoint records that fn is registered as a handler at.
+func (a *Analyzer) addEntryPoint(fn *Function, at EntryPoint) {
+	a.entryMu.Lock()
+	defer a.entryMu.Unlock()
+
+	key := a.getFunctionKey(fn)
+	var entries []EntryPoint
+	if existing, ok := a.entryPoints.Load(key); ok {
+		entries = existing.([]EntryPoint)
+	}
+	a.entryPoints.Store(key, append(entries, at))
+	a.entryFuncs.Store(key, fn)
+}
+
+// servedByFramework reports whether fn is registered as an HTTP or gRPC
+// handler, which the registering function does not call.
+func (a *Analyzer) servedByFramework(fn *Function) bool {
+	for _, entry := range a.EntryPoints(fn) {
+		if entry.Kind != EntryCLI {
+			return true
+		}
+	}
+	return false
+}
+
+// EntryPoints returns the registrations of fn as an HTTP or gRPC handler,
+// sorted by location.
+func (a *Analyzer) EntryPoints(fn *Function) []EntryPoint {
+	entries, ok := a.entryPoints.Load(a.getFunctionKey(fn))
+	if !ok {
+		return nil
+	}
+	list := append([]EntryPoint(nil), entries.([]EntryPoint)...)
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].File != list[j].File {
+			return list[i].File < list[j].File
+		}
+		return list[i].Line < list[j].Line
+	})
+	return list
+}
+
+// stringArg returns argument i of call when it is a string literal.
+func stringArg(call *ast.CallExpr, i int) string {
+	if i >= len(call.Args) {
+		return ""
+	}
+	lit, ok := call.Args[i].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// isRoute reports whether s looks like a URL path pattern.
+func isRoute(s string) bool {
+	return strings.HasPrefix(s, "/")
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// typeQualifier matches the package qualifiers of the types in a shape, as in
+// "*pb.GetUserRequest".
+var typeQualifier = regexp.MustCompile(`\b\w+\.`)
+
+// StitchGRPC links the gRPC client methods generated by protoc-gen-go-grpc
+// to the server implementations of the same RPC found in the analyzed
+// code, with CallSynthetic calls labeled "gRPC", so that traces follow
+// calls from one service into another. A generated <Service>Client and
+// <Service>Server interface pair identifies a service; its server methods
+// are matched by name and request and response types, leaving out the
+// Unimplemented<Service>Server stubs. It returns the number of edges
+// added.
+func (a *Analyzer) StitchGRPC() int {
+	var servers []*Interface
+	a.ifaceDecls.Range(func(key, value interface{}) bool {
+		iface := value.(*Interface)
+		if strings.HasSuffix(iface.Name, "Server") {
+			servers = append(servers, iface)
+		}
+		return true
+	})
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Package+"."+servers[i].Name < servers[j].Package+"."+servers[j].Name
+	})
+
+	added := 0
+	for _, server := range servers {
+		service := strings.TrimSuffix(server.Name, "Server")
+		value, ok := a.ifaceDecls.Load(server.Package + "." + service + "Client")
+		if !ok {
+			continue
+		}
+		client := a.resolve(value.(*Interface), map[*Interface]bool{})
+		server = a.resolve(server, map[*Interface]bool{})
+
+		for _, impl := range a.Implementations(client) {
+			for _, stub := range impl.Methods {
+				shape, ok := server.Methods[stub.Name]
+				if !ok {
+					continue
+				}
+				at := callContext{Position: token.Position{Filename: stub.FullPath, Line: stub.Line, Column: stub.Column}, kind: CallSynthetic, via: "gRPC"}
+				for _, fn := range a.serverMethods(service, stub.Name, shape) {
+					a.addCallSite(stub, fn, at)
+					added++
+				}
+			}
+		}
+	}
+	return added
+}
+
+// serverMethods returns the methods implementing an RPC of service, with
+// the given name and shape, sorted by key.
+func (a *Analyzer) serverMethods(service, name, shape string) []*Function {
+	shape = typeQualifier.ReplaceAllString(shape, "")
+	var methods []*Function
+	a.functions.Range(func(key, value interface{}) bool {
+		fn := value.(*Function)
+		if fn.Name != name || fn.Receiver == "" {
+			return true
+		}
+		typ := receiverType(fn.Receiver)
+		if typ == "Unimplemented"+service+"Server" || typ == "Unsafe"+service+"Server" {
+			return true
+		}
+		if typeQualifier.ReplaceAllString(fn.shape, "") == shape {
+			methods = append(methods, fn)
+		}
+		return true
+	})
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Key() < methods[j].Key()
+	})
+	return methods
+}
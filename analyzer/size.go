@@ -0,0 +1,34 @@
+package analyzer
+
+import "go/ast"
+
+// Lines returns the number of lines fn spans, from the func keyword to the
+// closing brace.
+func (fn *Function) Lines() int {
+	if fn.EndLine < fn.Line {
+		return 1
+	}
+	return fn.EndLine - fn.Line + 1
+}
+
+// countStatements counts the statements of body, nested ones included and
+// blocks themselves not, leaving out the bodies of function literals,
+// which are functions of their own.
+func countStatements(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			return true
+		case ast.Stmt:
+			count++
+		}
+		return true
+	})
+	return count
+}
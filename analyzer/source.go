@@ -0,0 +1,264 @@
+package analyzer
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// walkDir walks dir on the analyzer's source filesystem: the fs.FS given
+// with WithFS, or the local disk otherwise.
+func (a *Analyzer) walkDir(dir string, fn fs.WalkDirFunc) error {
+	if a.fsys != nil {
+		return fs.WalkDir(a.fsys, dir, fn)
+	}
+	if a.symlinks {
+		visited := make(map[string]bool)
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			visited[real] = true
+		}
+		return walkFollowingSymlinks(dir, fn, visited)
+	}
+	return filepath.WalkDir(dir, fn)
+}
+
+// walkSourceFiles calls fn for each file given with WithSourceFiles, in
+// place of a walk of dir. Files that cannot be read are reported to fn
+// like walk errors.
+func (a *Analyzer) walkSourceFiles(dir string, fn fs.WalkDirFunc) error {
+	stat := os.Stat
+	if a.fsys != nil {
+		stat = func(name string) (fs.FileInfo, error) { return fs.Stat(a.fsys, name) }
+	}
+	for _, filePath := range a.sourceFiles {
+		info, err := stat(filePath)
+		if err != nil {
+			if err := fn(filePath, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(filePath, fs.FileInfoToDirEntry(info), nil); err != nil && err != filepath.SkipDir {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkFollowingSymlinks is filepath.WalkDir, except that symbolic links to
+// directories are walked as if they were the directory itself. Paths keep
+// the link's name; each real directory is walked once, which also breaks
+// link cycles.
+func walkFollowingSymlinks(root string, fn fs.WalkDirFunc, visited map[string]bool) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fn(path, d, err)
+		}
+		if d.IsDir() && path != root {
+			real, evalErr := filepath.EvalSymlinks(path)
+			if evalErr == nil && visited[real] {
+				return filepath.SkipDir
+			}
+			visited[real] = true
+			return fn(path, d, err)
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return fn(path, d, err)
+		}
+		info, statErr := os.Stat(path)
+		if statErr != nil || !info.IsDir() {
+			return fn(path, d, err)
+		}
+		real, evalErr := filepath.EvalSymlinks(path)
+		if evalErr != nil || visited[real] {
+			return nil
+		}
+		visited[real] = true
+		// The trailing separator makes WalkDir resolve the link; the callback
+		// sees the link as the root of that walk and may still skip it
+		return walkFollowingSymlinks(path+string(filepath.Separator), fn, visited)
+	})
+}
+
+// readFile returns the contents of a source file, preferring overlay
+// buffers over the analyzer's filesystem.
+func (a *Analyzer) readFile(filePath string) ([]byte, error) {
+	if data, ok := a.overlay[filePath]; ok {
+		return data, nil
+	}
+	if a.fsys != nil {
+		return fs.ReadFile(a.fsys, filePath)
+	}
+	return os.ReadFile(filePath)
+}
+
+// parseFile parses a source file read through readFile, with comments so
+// doc comments can be captured, and records whether it is generated and
+// its build constraint. Files the host platform does not build are skipped
+// with WithHostPlatform.
+func (a *Analyzer) parseFile(fset *token.FileSet, filePath string) (*ast.File, error) {
+	data, err := a.readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if isGeneratedSource(data) {
+		a.generated.Store(filePath, true)
+		if mockHeader.Match(data) {
+			a.mocks.Store(filePath, true)
+		}
+	}
+	if err := a.recordConstraint(filePath, data); err != nil {
+		return nil, err
+	}
+	return parser.ParseFile(fset, filePath, data, parser.ParseComments)
+}
+
+// isGeneratedFile reports whether a file parsed by parseFile carries the
+// generated code header.
+func (a *Analyzer) isGeneratedFile(filePath string) bool {
+	_, ok := a.generated.Load(filePath)
+	return ok
+}
+
+// isMockFile reports whether a file parsed by parseFile is a mock
+// generated by mockgen or mockery.
+func (a *Analyzer) isMockFile(filePath string) bool {
+	_, ok := a.mocks.Load(filePath)
+	return ok
+}
+
+// mockHeader is the generated code header of gomock's mockgen and of
+// mockery.
+var mockHeader = regexp.MustCompile(`(?m)^// Code generated by (MockGen|mockery)\b`)
+
+// generatedHeader is the comment that marks generated Go files, see
+// https://go.dev/s/generatedcode.
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGeneratedSource looks for the generated code header before the
+// package clause.
+func isGeneratedSource(data []byte) bool {
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if generatedHeader.Match(line) {
+			return true
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("package ")) {
+			return false
+		}
+	}
+	return false
+}
+
+// overlayFiles returns the overlay Go files located under dir that are not
+// already part of files, so unsaved new buffers are analyzed too.
+func (a *Analyzer) overlayFiles(dir string, files []string) []string {
+	known := make(map[string]bool, len(files))
+	for _, f := range files {
+		known[f] = true
+	}
+
+	var extra []string
+	for filePath := range a.overlay {
+		if known[filePath] || !strings.HasSuffix(filePath, ".go") {
+			continue
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || !a.includesFile(rel) {
+			continue
+		}
+		extra = append(extra, filePath)
+	}
+	sort.Strings(extra)
+	return extra
+}
+
+// skipsDir reports whether directories called name are left out of the walk.
+func (a *Analyzer) skipsDir(name string) bool {
+	for _, skip := range a.skipDirs {
+		if name == skip {
+			return true
+		}
+	}
+	return false
+}
+
+// includesFile reports whether the Go file at rel, relative to the
+// analyzed directory, matches the patterns of WithFiles, if any.
+func (a *Analyzer) includesFile(rel string) bool {
+	if len(a.filePatterns) == 0 {
+		return true
+	}
+	name := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range a.filePatterns {
+		if matchGlob(strings.Split(pattern, "/"), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// includesDir reports whether the directory at rel, relative to the
+// analyzed directory, may hold files matching the patterns of WithFiles.
+func (a *Analyzer) includesDir(rel string) bool {
+	if len(a.filePatterns) == 0 {
+		return true
+	}
+	dir := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range a.filePatterns {
+		elems := strings.Split(pattern, "/")
+		for i, elem := range dir {
+			if i >= len(elems)-1 {
+				break
+			}
+			if elems[i] == "**" {
+				return true
+			}
+			if ok, _ := path.Match(elems[i], elem); !ok {
+				break
+			}
+			if i == len(dir)-1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the elements of a slash-separated name match
+// those of pattern, where ** matches any number of elements and the others
+// are matched by path.Match.
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
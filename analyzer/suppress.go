@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Rules a //gogotrace:ignore comment can name, as in
+// "//gogotrace:ignore unsafe reviewed in #123". A comment naming no rule
+// suppresses all of them.
+const (
+	RuleTrace       = "trace"       // callers in the call trees
+	RuleUnreachable = "unreachable" // the unreachable audit
+	RulePanics      = "panics"      // the panics audit
+	RuleUnsafe      = "unsafe"      // the unsafe audit
+)
+
+const ignoreDirective = "//gogotrace:ignore"
+
+// recordSuppressions notes the rules that the //gogotrace:ignore comments
+// of file suppress, by the line they apply to.
+func (a *Analyzer) recordSuppressions(fset *token.FileSet, file *ast.File, filePath, relPath string) {
+	directives := a.directiveLines(fset, file, filePath, ignoreDirective)
+	if len(directives) == 0 {
+		return
+	}
+	lines := make(map[int]map[string]bool)
+	for line, occurrences := range directives {
+		lines[line] = make(map[string]bool)
+		for _, words := range occurrences {
+			// Only the first word names a rule, the rest explains
+			rule := ""
+			if len(words) > 0 {
+				rule = words[0]
+			}
+			lines[line][rule] = true
+		}
+	}
+	a.suppressions.Store(relPath, lines)
+}
+
+// suppressedAt reports whether a //gogotrace:ignore comment suppresses rule
+// on line of the file at path, relative to the analyzed root.
+func (a *Analyzer) suppressedAt(path string, line int, rule string) bool {
+	lines, ok := a.suppressions.Load(path)
+	if !ok {
+		return false
+	}
+	rules := lines.(map[int]map[string]bool)[line]
+	return rules[""] || rules[rule]
+}
+
+// Suppressed reports whether fn is excluded from rule by a
+// //gogotrace:ignore comment in its doc comment or at the end of the line
+// declaring it.
+func (a *Analyzer) Suppressed(fn *Function, rule string) bool {
+	return a.suppressedAt(fn.FullPath, fn.Line, rule)
+}
+
+// SuppressedCall reports whether cs is excluded from rule by a
+// //gogotrace:ignore comment at the end of its line or just above it.
+func (a *Analyzer) SuppressedCall(cs *CallSite, rule string) bool {
+	return a.suppressedAt(cs.Caller.FullPath, cs.Line, rule)
+}
@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+const tagDirective = "//gogotrace:tag"
+
+// recordTags notes the tags that the //gogotrace:tag comments of file, as
+// in "//gogotrace:tag payments critical", attach to the line they apply
+// to.
+func (a *Analyzer) recordTags(fset *token.FileSet, file *ast.File, filePath, relPath string) {
+	directives := a.directiveLines(fset, file, filePath, tagDirective)
+	if len(directives) == 0 {
+		return
+	}
+	lines := make(map[int][]string)
+	for line, occurrences := range directives {
+		for _, words := range occurrences {
+			lines[line] = append(lines[line], words...)
+		}
+	}
+	a.tags.Store(relPath, lines)
+}
+
+// Tags returns the tags given to fn by //gogotrace:tag comments in its doc
+// comment or at the end of the line declaring it, sorted.
+func (a *Analyzer) Tags(fn *Function) []string {
+	lines, ok := a.tags.Load(fn.FullPath)
+	if !ok {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var tags []string
+	for _, tag := range lines.(map[int][]string)[fn.Line] {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
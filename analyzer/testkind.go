@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"go/ast"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kinds of functions found in _test.go files, stored in Function.TestKind.
+const (
+	TestKindTest      = "test"
+	TestKindBenchmark = "benchmark"
+	TestKindFuzz      = "fuzz"
+	TestKindExample   = "example"
+	TestKindHelper    = "helper" // any other function in a test file
+)
+
+// classifyTest applies the go test naming rules: TestXxx(*testing.T),
+// TestMain(*testing.M), BenchmarkXxx(*testing.B), FuzzXxx(*testing.F) and
+// ExampleXxx() with no parameters or results.
+func classifyTest(fn *ast.FuncDecl) string {
+	if fn.Recv != nil {
+		return TestKindHelper
+	}
+	name := fn.Name.Name
+	switch {
+	case name == "TestMain" && takesTestingParam(fn, "M"):
+		return TestKindTest
+	case hasTestPrefix(name, "Test") && takesTestingParam(fn, "T"):
+		return TestKindTest
+	case hasTestPrefix(name, "Benchmark") && takesTestingParam(fn, "B"):
+		return TestKindBenchmark
+	case hasTestPrefix(name, "Fuzz") && takesTestingParam(fn, "F"):
+		return TestKindFuzz
+	case hasTestPrefix(name, "Example") && fn.Type.Params.NumFields() == 0 && fn.Type.Results.NumFields() == 0:
+		return TestKindExample
+	}
+	return TestKindHelper
+}
+
+// hasTestPrefix reports whether name is prefix followed by nothing or by a
+// character that is not a lower-case letter, as go test requires.
+func hasTestPrefix(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// takesTestingParam reports whether fn has a single *testing.X parameter
+// for one of the given type names.
+func takesTestingParam(fn *ast.FuncDecl, types ...string) bool {
+	params := fn.Type.Params
+	if params.NumFields() != 1 {
+		return false
+	}
+	star, ok := params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "testing" {
+		return false
+	}
+	for _, t := range types {
+		if sel.Sel.Name == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"go/ast"
+	"path/filepath"
+	"sort"
+)
+
+// unsafePointerOps are the functions and types of package unsafe that
+// handle raw pointers; Sizeof, Alignof and Offsetof are harmless.
+var unsafePointerOps = map[string]bool{
+	"Pointer": true, "Add": true, "Slice": true, "SliceData": true, "String": true, "StringData": true,
+}
+
+// recordUnsafe notes the raw pointer operations of package unsafe and the
+// cgo calls made by fn, as found in node of its body.
+func (a *Analyzer) recordUnsafe(node ast.Node, fn *Function) {
+	var sel *ast.SelectorExpr
+	switch n := node.(type) {
+	case *ast.SelectorExpr:
+		sel = n
+	case *ast.CallExpr:
+		// Only calls of C functions count for cgo, the selector itself
+		// may be a C type
+		if s, ok := n.Fun.(*ast.SelectorExpr); ok && a.importedAs(fn, s.X, "C") {
+			a.addUnsafeUse(fn, "C."+s.Sel.Name)
+		}
+		return
+	default:
+		return
+	}
+	if unsafePointerOps[sel.Sel.Name] && a.importedAs(fn, sel.X, "unsafe") {
+		a.addUnsafeUse(fn, "unsafe."+sel.Sel.Name)
+	}
+}
+
+// importedAs reports whether x names the package importPath in the file
+// of fn.
+func (a *Analyzer) importedAs(fn *Function, x ast.Expr, importPath string) bool {
+	ident, ok := x.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	imports, ok := a.imports.Load(filepath.Join(a.baseDir, fn.FullPath))
+	return ok && imports.(map[string]string)[ident.Name] == importPath
+}
+
+func (a *Analyzer) addUnsafeUse(fn *Function, use string) {
+	// A function's body is walked by one worker only
+	uses, _ := a.unsafeUses.LoadOrStore(a.getFunctionKey(fn), make(map[string]bool))
+	uses.(map[string]bool)[use] = true
+}
+
+// UnsafeUses returns the raw pointer operations of package unsafe, such as
+// "unsafe.Pointer", and the cgo calls, such as "C.free", that fn makes
+// directly, sorted.
+func (a *Analyzer) UnsafeUses(fn *Function) []string {
+	uses, ok := a.unsafeUses.Load(a.getFunctionKey(fn))
+	if !ok {
+		return nil
+	}
+	var sorted []string
+	for use := range uses.(map[string]bool) {
+		sorted = append(sorted, use)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
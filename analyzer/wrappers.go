@@ -0,0 +1,59 @@
+package analyzer
+
+import "go/ast"
+
+// isWrapper reports whether the body of fn is a single call forwarding all
+// of fn's parameters, in order, to another function and returning what it
+// returns. Functions with neither parameters nor receiver, such as a main
+// calling run(), are not wrappers. For example
+//
+//	func (s *Store) Get(key string) (string, error) { return s.db.Get(key) }
+func isWrapper(fn *ast.FuncDecl) bool {
+	if fn.Body == nil || len(fn.Body.List) != 1 {
+		return false
+	}
+	var call *ast.CallExpr
+	switch stmt := fn.Body.List[0].(type) {
+	case *ast.ReturnStmt:
+		if len(stmt.Results) == 1 {
+			call, _ = stmt.Results[0].(*ast.CallExpr)
+		}
+	case *ast.ExprStmt:
+		if fn.Type.Results == nil || len(fn.Type.Results.List) == 0 {
+			call, _ = stmt.X.(*ast.CallExpr)
+		}
+	}
+	if call == nil {
+		return false
+	}
+	if _, ok := call.Fun.(*ast.FuncLit); ok {
+		return false
+	}
+	if fn.Recv == nil && (fn.Type.Params == nil || len(fn.Type.Params.List) == 0) {
+		return false
+	}
+
+	var params []string
+	variadic := false
+	if fn.Type.Params != nil {
+		for _, field := range fn.Type.Params.List {
+			if len(field.Names) == 0 {
+				return false
+			}
+			for _, name := range field.Names {
+				params = append(params, name.Name)
+			}
+			_, variadic = field.Type.(*ast.Ellipsis)
+		}
+	}
+	if len(call.Args) != len(params) || variadic != call.Ellipsis.IsValid() {
+		return false
+	}
+	for i, arg := range call.Args {
+		ident, ok := arg.(*ast.Ident)
+		if !ok || ident.Name != params[i] || ident.Name == "_" {
+			return false
+		}
+	}
+	return true
+}
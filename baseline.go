@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// auditBaseline compares the findings of an audit subcommand with those
+// recorded in a committed baseline file, so that CI only fails on new
+// findings while a legacy codebase catches up. One file holds the findings
+// of every audit, keyed by audit name. Findings are identified without
+// line numbers, like diff-graph does, so that unrelated edits do not turn
+// recorded findings into new ones.
+type auditBaseline struct {
+	audit  string
+	path   string
+	update bool
+}
+
+// addBaselineFlags registers -baseline and -update-baseline on the flags
+// of an audit.
+func addBaselineFlags(flags *flag.FlagSet, audit string) *auditBaseline {
+	b := &auditBaseline{audit: audit}
+	flags.StringVar(&b.path, "baseline", "", "Only fail on findings missing from this baseline file")
+	flags.BoolVar(&b.update, "update-baseline", false, "Record the current findings in the -baseline file instead")
+	return b
+}
+
+// check records findings in the baseline file when updating, and otherwise
+// prints the findings missing from it. It returns the exit status: 1 when
+// there are new findings or the file cannot be used, 0 otherwise and
+// without -baseline.
+func (b *auditBaseline) check(findings []string) int {
+	if b.path == "" {
+		if b.update {
+			fmt.Fprintln(os.Stderr, "Error: -update-baseline needs -baseline")
+			return 1
+		}
+		return 0
+	}
+
+	recorded := make(map[string][]string)
+	data, err := os.ReadFile(b.path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &recorded); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading baseline %s: %v\n", b.path, err)
+			return 1
+		}
+	case b.update && errors.Is(err, fs.ErrNotExist):
+		// Created below
+	default:
+		fmt.Fprintf(os.Stderr, "Error reading baseline: %v\n", err)
+		return 1
+	}
+
+	if b.update {
+		recorded[b.audit] = uniqueSorted(findings)
+		data, err := json.MarshalIndent(recorded, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(b.path, append(data, '\n'), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+			return 1
+		}
+		fmt.Printf("\nBaseline %s updated with %d %s findings\n", b.path, len(recorded[b.audit]), b.audit)
+		return 0
+	}
+
+	known := make(map[string]bool)
+	for _, finding := range recorded[b.audit] {
+		known[finding] = true
+	}
+	current := make(map[string]bool)
+	var added []string
+	for _, finding := range uniqueSorted(findings) {
+		current[finding] = true
+		if !known[finding] {
+			added = append(added, finding)
+		}
+	}
+	fixed := 0
+	for finding := range known {
+		if !current[finding] {
+			fixed++
+		}
+	}
+
+	fmt.Printf("\nFindings not in baseline %s (%d of %d):\n", b.path, len(added), len(current))
+	for _, finding := range added {
+		fmt.Printf("  %s\n", finding)
+	}
+	if fixed > 0 {
+		fmt.Printf("%d baseline findings are gone; run with -update-baseline to drop them\n", fixed)
+	}
+	if len(added) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// functionFindings identifies functions as findings.
+func functionFindings(fns ...[]*analyzer.Function) []string {
+	var findings []string
+	for _, list := range fns {
+		for _, fn := range list {
+			findings = append(findings, stableID(fn))
+		}
+	}
+	return findings
+}
+
+func uniqueSorted(items []string) []string {
+	seen := make(map[string]bool)
+	list := []string{}
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			list = append(list, item)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
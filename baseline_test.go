@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAuditBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	panics := &auditBaseline{audit: "panics", path: path}
+	updatePanics := &auditBaseline{audit: "panics", path: path, update: true}
+	updateUnsafe := &auditBaseline{audit: "unsafe", path: path, update: true}
+
+	// Reading a baseline that does not exist yet is an error
+	if status := panics.check([]string{"a"}); status != 1 {
+		t.Errorf("check without a baseline file = %d, want 1", status)
+	}
+
+	// Updating creates the file, and each audit keeps its own findings
+	if status := updatePanics.check([]string{"b", "a", "a"}); status != 0 {
+		t.Fatalf("update = %d, want 0", status)
+	}
+	if status := updateUnsafe.check([]string{"x"}); status != 0 {
+		t.Fatalf("update = %d, want 0", status)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading baseline: %v", err)
+	}
+	var recorded map[string][]string
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatalf("parsing baseline: %v", err)
+	}
+	want := map[string][]string{"panics": {"a", "b"}, "unsafe": {"x"}}
+	if !reflect.DeepEqual(recorded, want) {
+		t.Errorf("baseline holds %v, want %v", recorded, want)
+	}
+
+	tests := []struct {
+		name     string
+		findings []string
+		want     int
+	}{
+		{"unchanged", []string{"b", "a"}, 0},
+		{"new finding", []string{"a", "b", "c"}, 1},
+		{"fixed finding", []string{"a"}, 0},
+		{"none left", nil, 0},
+		{"other audit's finding", []string{"a", "x"}, 1},
+	}
+	for _, tt := range tests {
+		if status := panics.check(tt.findings); status != tt.want {
+			t.Errorf("%s: check = %d, want %d", tt.name, status, tt.want)
+		}
+	}
+
+	// Without -baseline, nothing is compared but updating is a mistake
+	if status := (&auditBaseline{audit: "panics"}).check([]string{"a"}); status != 0 {
+		t.Errorf("check without -baseline = %d, want 0", status)
+	}
+	if status := (&auditBaseline{audit: "panics", update: true}).check([]string{"a"}); status != 1 {
+		t.Errorf("-update-baseline without -baseline = %d, want 1", status)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := panics.check([]string{"a"}); status != 1 {
+		t.Errorf("check with a corrupt baseline = %d, want 1", status)
+	}
+}
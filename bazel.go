@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// bazelQuery lists the sources of the Go rules of the workspace and of the
+// external repositories they depend on.
+const bazelQuery = `labels(srcs, kind("go_(library|binary|test) rule", deps(//...)))`
+
+// bazelSources returns the Go files of the Bazel workspace containing dir,
+// as its go_library, go_binary and go_test rules declare them: source files
+// of the workspace, files generated into bazel-bin and the sources of
+// external repositories under the output base.
+func bazelSources(dir string) ([]string, error) {
+	workspace, err := bazel(dir, "info", "workspace")
+	if err != nil {
+		return nil, err
+	}
+	bin, err := bazel(dir, "info", "bazel-bin")
+	if err != nil {
+		return nil, err
+	}
+	outputBase, err := bazel(dir, "info", "output_base")
+	if err != nil {
+		return nil, err
+	}
+	labels, err := bazel(dir, "query", bazelQuery, "--output=label")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, label := range strings.Split(labels, "\n") {
+		if !strings.HasSuffix(label, ".go") {
+			continue
+		}
+		if f := bazelLabelPath(label, workspace, bin, outputBase); f != "" {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("bazel query found no Go sources")
+	}
+	return files, nil
+}
+
+// bazelLabelPath returns the file a source label such as //pkg:file.go or
+// @repo//pkg:file.go stands for: a file of the workspace or of the external
+// repository, or the file generated into bazel-bin when there is none.
+func bazelLabelPath(label, workspace, bin, outputBase string) string {
+	repo, target, ok := strings.Cut(label, "//")
+	if !ok {
+		return ""
+	}
+	pkg, name, ok := strings.Cut(target, ":")
+	if !ok {
+		return ""
+	}
+	rel := filepath.Join(filepath.FromSlash(pkg), filepath.FromSlash(name))
+
+	if repo = strings.TrimLeft(repo, "@"); repo != "" {
+		return filepath.Join(outputBase, "external", repo, rel)
+	}
+	if f := filepath.Join(workspace, rel); fileExists(f) {
+		return f
+	}
+	return filepath.Join(bin, rel)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// bazel runs a bazel command in dir and returns its trimmed standard
+// output.
+func bazel(dir string, args ...string) (string, error) {
+	cmd := exec.Command("bazel", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if msg := lines[len(lines)-1]; msg != "" {
+			return "", fmt.Errorf("bazel %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("bazel %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,129 @@
+// Package blame looks up who last changed a source line using git blame.
+package blame
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Line is the last change to one line of a file.
+type Line struct {
+	Commit string    `json:"commit"`
+	Author string    `json:"author"`
+	Email  string    `json:"email,omitempty"`
+	Date   time.Time `json:"date"`
+}
+
+// Blamer runs git blame in a working tree, once per file.
+type Blamer struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string][]Line
+	errs  map[string]error
+}
+
+// New returns a Blamer for paths relative to dir, which must be inside a
+// git working tree.
+func New(dir string) *Blamer {
+	return &Blamer{
+		dir:   dir,
+		files: make(map[string][]Line),
+		errs:  make(map[string]error),
+	}
+}
+
+// Line returns the last change to line (1-based) of path, which is relative
+// to the Blamer's directory.
+func (b *Blamer) Line(path string, line int) (*Line, error) {
+	lines, err := b.file(path)
+	if err != nil {
+		return nil, err
+	}
+	if line < 1 || line > len(lines) {
+		return nil, fmt.Errorf("%s has no line %d", path, line)
+	}
+	return &lines[line-1], nil
+}
+
+func (b *Blamer) file(path string) ([]Line, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lines, ok := b.files[path]; ok {
+		return lines, nil
+	}
+	if err, ok := b.errs[path]; ok {
+		return nil, err
+	}
+
+	lines, err := b.run(path)
+	if err != nil {
+		b.errs[path] = err
+		return nil, err
+	}
+	b.files[path] = lines
+	return lines, nil
+}
+
+func (b *Blamer) run(path string) ([]Line, error) {
+	cmd := exec.Command("git", "blame", "--line-porcelain", "--", filepath.ToSlash(path))
+	cmd.Dir = b.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git blame %s: %s", path, msg)
+		}
+		return nil, fmt.Errorf("git blame %s: %w", path, err)
+	}
+	return parsePorcelain(out)
+}
+
+// parsePorcelain reads the output of git blame --line-porcelain, where
+// every line of the file comes with its full commit header.
+func parsePorcelain(out []byte) ([]Line, error) {
+	var lines []Line
+	var cur Line
+	header := true
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "\t") {
+			// The file content ends each record
+			lines = append(lines, cur)
+			cur = Line{}
+			header = true
+			continue
+		}
+		if header {
+			cur.Commit, _, _ = strings.Cut(text, " ")
+			header = false
+			continue
+		}
+
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			cur.Author = value
+		case "author-mail":
+			cur.Email = strings.Trim(value, "<>")
+		case "author-time":
+			sec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad author-time %q", value)
+			}
+			cur.Date = time.Unix(sec, 0).UTC()
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,37 @@
+package blame
+
+import "testing"
+
+const porcelain = "4b825dc642cb6eb9a060e54bf8d69288fbee4904 1 1 2\n" +
+	"author Jane Doe\n" +
+	"author-mail <jane@example.com>\n" +
+	"author-time 1700000000\n" +
+	"author-tz +0000\n" +
+	"summary first\n" +
+	"filename main.go\n" +
+	"\tpackage main\n" +
+	"4b825dc642cb6eb9a060e54bf8d69288fbee4904 2 2\n" +
+	"author Jane Doe\n" +
+	"author-mail <jane@example.com>\n" +
+	"author-time 1700000000\n" +
+	"author-tz +0000\n" +
+	"summary first\n" +
+	"filename main.go\n" +
+	"\t\n"
+
+func TestParsePorcelain(t *testing.T) {
+	lines, err := parsePorcelain([]byte(porcelain))
+	if err != nil {
+		t.Fatalf("parsePorcelain failed: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	l := lines[1]
+	if l.Author != "Jane Doe" || l.Email != "jane@example.com" || l.Date.Unix() != 1700000000 {
+		t.Errorf("Unexpected blame for line 2: %+v", l)
+	}
+	if l.Commit != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Errorf("Unexpected commit %q", l.Commit)
+	}
+}
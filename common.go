@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func init() {
+	subcommands["common"] = subcommand{
+		summary: "List functions that transitively call all of the given functions",
+		run:     runCommon,
+	}
+}
+
+func runCommon(args []string) int {
+	fs := newFlagSet("common")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	var signatures stringList
+	fs.Var(&signatures, "func", "Function signature that every result must reach (repeatable)")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace common -func \"<signature>\" -func \"<signature>\" [-func ...] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(signatures) < 2 {
+		fs.Usage()
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	var sets []map[string]*analyzer.Function
+	for _, sig := range signatures {
+		fn, err := a.FindFunction(sig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		callers := a.TransitiveCallers(fn, *noTests)
+		fmt.Printf("%s in %s: %d transitive callers\n", fn.Signature, fn.FullPath, len(callers))
+		sets = append(sets, callers)
+	}
+
+	printFunctionSet(fmt.Sprintf("Functions calling all %d targets", len(signatures)), commonFunctions(sets))
+	return 0
+}
+
+// commonFunctions returns the functions found in every set, keyed by
+// Function.Key, sorted.
+func commonFunctions(sets []map[string]*analyzer.Function) []*analyzer.Function {
+	var common []*analyzer.Function
+	if len(sets) == 0 {
+		return common
+	}
+	for key, fn := range sets[0] {
+		inAll := true
+		for _, set := range sets[1:] {
+			if _, ok := set[key]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			common = append(common, fn)
+		}
+	}
+	sortFunctions(common)
+	return common
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestCommonFunctions(t *testing.T) {
+	tests := []struct {
+		signatures []string
+		noTests    bool
+		want       string
+	}{
+		{[]string{"func A()", "func B()"}, false, "both top all TestAB"},
+		{[]string{"func A()", "func B()"}, true, "both top all"},
+		{[]string{"func B()", "func A()"}, true, "both top all"},
+		{[]string{"func A()", "func B()", "func C()"}, false, "all"},
+		{[]string{"func C()", "func A()"}, false, "all"},
+		{[]string{"func A()"}, true, "both onlyA top all"},
+	}
+	for _, tt := range tests {
+		got := functionNames(commonFunctions(transitiveCallers(t, tt.noTests, tt.signatures...)))
+		if got != tt.want {
+			t.Errorf("%v (noTests=%v): %q, want %q", tt.signatures, tt.noTests, got, tt.want)
+		}
+	}
+}
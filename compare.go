@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func init() {
+	subcommands["compare"] = subcommand{
+		summary: "Compare the transitive caller sets of two functions",
+		run:     runCompare,
+	}
+}
+
+func runCompare(args []string) int {
+	fs := newFlagSet("compare")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	var signatures stringList
+	fs.Var(&signatures, "func", "Function signature to compare (exactly two)")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace compare -func \"<signature A>\" -func \"<signature B>\" [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(signatures) != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	var sets [2]map[string]*analyzer.Function
+	for i, sig := range signatures {
+		fn, err := a.FindFunction(sig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		sets[i] = a.TransitiveCallers(fn, *noTests)
+		fmt.Printf("%s: %s in %s (%d transitive callers)\n", string(rune('A'+i)), fn.Signature, fn.FullPath, len(sets[i]))
+	}
+
+	both, onlyA, onlyB := compareSets(sets[0], sets[1])
+	fmt.Printf("\nUnion: %d callers\n", len(both)+len(onlyA)+len(onlyB))
+	printFunctionSet("Intersection (callers of both)", both)
+	printFunctionSet("Symmetric difference: only A", onlyA)
+	printFunctionSet("Symmetric difference: only B", onlyB)
+	return 0
+}
+
+// compareSets splits the functions of two sets, keyed by Function.Key, into
+// their intersection and the functions of each set only, each sorted.
+func compareSets(a, b map[string]*analyzer.Function) (both, onlyA, onlyB []*analyzer.Function) {
+	for key, fn := range a {
+		if _, ok := b[key]; ok {
+			both = append(both, fn)
+		} else {
+			onlyA = append(onlyA, fn)
+		}
+	}
+	for key, fn := range b {
+		if _, ok := a[key]; !ok {
+			onlyB = append(onlyB, fn)
+		}
+	}
+	sortFunctions(both)
+	sortFunctions(onlyA)
+	sortFunctions(onlyB)
+	return both, onlyA, onlyB
+}
+
+// printFunctionSet prints a titled, sorted list of functions.
+func printFunctionSet(title string, fns []*analyzer.Function) {
+	sortFunctions(fns)
+	fmt.Printf("\n%s (%d):\n", title, len(fns))
+	for _, fn := range fns {
+		fmt.Printf("  %s in %s:%d\n", fn.Signature, fn.FullPath, fn.Line)
+	}
+}
+
+// sortFunctions orders functions by path, line and name.
+func sortFunctions(fns []*analyzer.Function) {
+	sort.Slice(fns, func(i, j int) bool {
+		if fns[i].FullPath != fns[j].FullPath {
+			return fns[i].FullPath < fns[j].FullPath
+		}
+		if fns[i].Line != fns[j].Line {
+			return fns[i].Line < fns[j].Line
+		}
+		return fns[i].Name < fns[j].Name
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// callerSetsSource has callers of A, B and C in every combination, and a
+// test calling A and B.
+var callerSetsSource = fstest.MapFS{
+	"lib.go": {Data: []byte(`package lib
+
+func A() {}
+
+func B() {}
+
+func C() {}
+
+func both() {
+	A()
+	B()
+}
+
+func onlyA() { A() }
+
+func onlyB() { B() }
+
+func top() {
+	both()
+	onlyA()
+}
+
+func viaOnlyB() { onlyB() }
+
+func all() {
+	A()
+	B()
+	C()
+}
+`)},
+	"lib_test.go": {Data: []byte(`package lib
+
+import "testing"
+
+func TestAB(t *testing.T) {
+	A()
+	B()
+}
+`)},
+}
+
+// transitiveCallers returns the transitive callers of each signature in
+// callerSetsSource.
+func transitiveCallers(t *testing.T, noTests bool, signatures ...string) []map[string]*analyzer.Function {
+	t.Helper()
+	a := analyzer.NewAnalyzer(analyzer.WithFS(callerSetsSource))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	var sets []map[string]*analyzer.Function
+	for _, sig := range signatures {
+		fn, err := a.FindFunction(sig)
+		if err != nil {
+			t.Fatalf("FindFunction: %v", err)
+		}
+		sets = append(sets, a.TransitiveCallers(fn, noTests))
+	}
+	return sets
+}
+
+func functionNames(fns []*analyzer.Function) string {
+	names := make([]string, len(fns))
+	for i, fn := range fns {
+		names[i] = fn.Name
+	}
+	return strings.Join(names, " ")
+}
+
+func TestCompareSets(t *testing.T) {
+	tests := []struct {
+		noTests            bool
+		both, onlyA, onlyB string
+	}{
+		{false, "both top all TestAB", "onlyA", "onlyB viaOnlyB"},
+		{true, "both top all", "onlyA", "onlyB viaOnlyB"},
+	}
+	for _, tt := range tests {
+		sets := transitiveCallers(t, tt.noTests, "func A()", "func B()")
+		both, onlyA, onlyB := compareSets(sets[0], sets[1])
+		if got := functionNames(both); got != tt.both {
+			t.Errorf("noTests=%v: intersection %q, want %q", tt.noTests, got, tt.both)
+		}
+		if got := functionNames(onlyA); got != tt.onlyA {
+			t.Errorf("noTests=%v: only A %q, want %q", tt.noTests, got, tt.onlyA)
+		}
+		if got := functionNames(onlyB); got != tt.onlyB {
+			t.Errorf("noTests=%v: only B %q, want %q", tt.noTests, got, tt.onlyB)
+		}
+		// The three parts cover the union exactly once
+		union := len(sets[0]) + len(sets[1]) - len(both)
+		if n := len(both) + len(onlyA) + len(onlyB); n != union {
+			t.Errorf("noTests=%v: parts hold %d callers, union has %d", tt.noTests, n, union)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/output"
+	"github.com/gogotrace/gogotrace/tree"
+)
+
+func init() {
+	subcommands["completion"] = subcommand{
+		summary: "Print a shell completion script (bash, zsh or fish)",
+		run:     runCompletion,
+	}
+}
+
+// completionFlag describes how the value of a command-line flag completes.
+type completionFlag struct {
+	name   string
+	usage  string
+	kind   string   // one of the flag kinds below
+	values []string // the values of an enum flag
+}
+
+// Kinds of flag values, by how they complete.
+const (
+	flagBool = "bool" // takes no value
+	flagDir  = "dir"
+	flagFile = "file"
+	flagEnum = "enum" // one of the values listed in enumFlags
+	flagFree = ""     // free-form text or a number, not completed
+)
+
+// dirFlags and fileFlags take paths. Flags listed in neither, nor in
+// enumFlags, take free-form values, so that a new flag never completes
+// filenames by accident.
+var (
+	dirFlags = map[string]bool{
+		"dir":     true,
+		"package": true,
+		"tickets": true,
+	}
+	fileFlags = map[string]bool{
+		"func-file":      true,
+		"json":           true,
+		"html":           true,
+		"xml":            true,
+		"xlsx":           true,
+		"treemap":        true,
+		"perfetto":       true,
+		"folded":         true,
+		"flamegraph":     true,
+		"pb":             true,
+		"pbjson":         true,
+		"codeowners":     true,
+		"owners-map":     true,
+		"edges":          true,
+		"routes":         true,
+		"resolver":       true,
+		"entry-patterns": true,
+		"o":              true,
+		"history":        true,
+		"baseline":       true,
+		"pr-comment":     true,
+		"cpuprofile":     true,
+		"memprofile":     true,
+		"trace":          true,
+	}
+)
+
+// enumFlags holds the values of the flags taking one of a fixed set.
+var enumFlags = map[string][]string{
+	"sort":        tree.SortOrders,
+	"editor":      output.Editors,
+	"color-by":    output.ColorModes,
+	"json-format": output.JSONFormats,
+	"hyperlinks":  hyperlinkModes,
+	"receiver":    {analyzer.ReceiverPointer, analyzer.ReceiverValue},
+	"log-level":   {"debug", "info", "warn", "error"},
+	"log-format":  logFormats,
+	"format":      {"dot", "mermaid"},
+}
+
+func runCompletion(args []string) int {
+	fs := newFlagSet("completion")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace completion bash|zsh|fish")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	c := completion{
+		flags:        completionFlags(flag.CommandLine),
+		commands:     subcommandNames(),
+		commandFlags: make(map[string][]completionFlag),
+	}
+	for _, name := range c.commands {
+		if cfs := subcommandFlags(name); cfs != nil {
+			c.commandFlags[name] = completionFlags(cfs)
+		}
+	}
+
+	switch shell := fs.Arg(0); shell {
+	case "bash":
+		c.writeBash(os.Stdout)
+	case "zsh":
+		c.writeZsh(os.Stdout)
+	case "fish":
+		c.writeFish(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported shell: %s (want bash, zsh or fish)\n", shell)
+		return 1
+	}
+	return 0
+}
+
+// completion is what the completion scripts complete: the flags of plain
+// gogotrace runs, the subcommands and the flags of each of them.
+type completion struct {
+	flags        []completionFlag
+	commands     []string
+	commandFlags map[string][]completionFlag
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{name: f.Name, usage: f.Usage}
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+			cf.kind = flagBool
+		} else if dirFlags[f.Name] {
+			cf.kind = flagDir
+		} else if fileFlags[f.Name] {
+			cf.kind = flagFile
+		} else if values, ok := enumFlags[f.Name]; ok {
+			cf.kind, cf.values = flagEnum, values
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+func subcommandNames() []string {
+	var names []string
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func flagNames(flags []completionFlag) []string {
+	var names []string
+	for _, f := range flags {
+		names = append(names, "-"+f.name)
+	}
+	return names
+}
+
+// bashPatterns returns the case patterns matching "$cmd $prev" for the
+// flags of kind, cmd being empty outside subcommands.
+func bashPatterns(cmd string, flags []completionFlag, kind string) string {
+	var patterns []string
+	for _, f := range flags {
+		if f.kind == kind {
+			patterns = append(patterns, fmt.Sprintf(`"%s -%s"`, cmd, f.name))
+		}
+	}
+	return strings.Join(patterns, "|")
+}
+
+func writeBashValues(w io.Writer, cmd string, flags []completionFlag) {
+	if dirs := bashPatterns(cmd, flags, flagDir); dirs != "" {
+		fmt.Fprintf(w, "        %s) COMPREPLY=( $(compgen -d -- \"$cur\") ); return ;;\n", dirs)
+	}
+	if files := bashPatterns(cmd, flags, flagFile); files != "" {
+		fmt.Fprintf(w, "        %s) COMPREPLY=( $(compgen -f -- \"$cur\") ); return ;;\n", files)
+	}
+	for _, f := range flags {
+		if f.kind == flagEnum {
+			fmt.Fprintf(w, "        \"%s -%s\") COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") ); return ;;\n", cmd, f.name, strings.Join(f.values, " "))
+		}
+	}
+	if free := bashPatterns(cmd, flags, flagFree); free != "" {
+		fmt.Fprintf(w, "        %s) return ;;\n", free)
+	}
+}
+
+func (c completion) writeBash(w io.Writer) {
+	fmt.Fprintln(w, "# bash completion for gogotrace")
+	fmt.Fprintln(w, "# Load with: source <(gogotrace completion bash)")
+	fmt.Fprintln(w, "_gogotrace() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" cmd= words=`)
+	fmt.Fprintln(w, `    if [[ "${COMP_WORDS[1]}" == completion ]]; then`)
+	fmt.Fprintln(w, `        COMPREPLY=( $(compgen -W "bash zsh fish" -- "$cur") )`)
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintf(w, "    if [[ $COMP_CWORD -gt 1 && \" %s \" == *\" ${COMP_WORDS[1]} \"* ]]; then\n", strings.Join(c.commands, " "))
+	fmt.Fprintln(w, `        cmd="${COMP_WORDS[1]}"`)
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case "$cmd $prev" in`)
+	writeBashValues(w, "", c.flags)
+	for _, name := range c.commands {
+		writeBashValues(w, name, c.commandFlags[name])
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, `    if [[ $COMP_CWORD -eq 1 && "$cur" != -* ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", strings.Join(c.commands, " "))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	fmt.Fprintf(w, "        \"\") words=\"%s\" ;;\n", strings.Join(flagNames(c.flags), " "))
+	for _, name := range c.commands {
+		if flags := c.commandFlags[name]; len(flags) > 0 {
+			fmt.Fprintf(w, "        %s) words=\"%s\" ;;\n", name, strings.Join(flagNames(flags), " "))
+		}
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, `    COMPREPLY=( $(compgen -W "$words" -- "$cur") )`)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _gogotrace gogotrace")
+}
+
+// zshEscape escapes characters that are special in _arguments specs.
+func zshEscape(s string) string {
+	r := strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`, `'`, `'\''`)
+	return r.Replace(s)
+}
+
+// writeZshArguments writes an _arguments call completing flags, each line
+// indented by indent.
+func writeZshArguments(w io.Writer, indent string, flags []completionFlag) {
+	fmt.Fprintln(w, indent+"_arguments \\")
+	for _, f := range flags {
+		spec := fmt.Sprintf("-%s[%s]", f.name, zshEscape(f.usage))
+		switch f.kind {
+		case flagDir:
+			spec += ":directory:_files -/"
+		case flagFile:
+			spec += ":file:_files"
+		case flagEnum:
+			spec += fmt.Sprintf(":value:(%s)", strings.Join(f.values, " "))
+		case flagFree:
+			spec += ":value: "
+		}
+		fmt.Fprintf(w, "%s    '%s' \\\n", indent, spec)
+	}
+	fmt.Fprintln(w, indent+"    '*::'")
+}
+
+func (c completion) writeZsh(w io.Writer) {
+	fmt.Fprintln(w, "#compdef gogotrace")
+	fmt.Fprintln(w, "# Load with: source <(gogotrace completion zsh)")
+	fmt.Fprintln(w, "_gogotrace() {")
+	fmt.Fprintln(w, `    if [[ "${words[2]}" == completion ]]; then`)
+	fmt.Fprintln(w, "        (( CURRENT == 3 )) && _values 'shell' bash zsh fish")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    if (( CURRENT == 2 )) && [[ "${words[CURRENT]}" != -* ]]; then`)
+	fmt.Fprint(w, "        _values 'command'")
+	for _, name := range c.commands {
+		fmt.Fprintf(w, " '%s[%s]'", name, zshEscape(subcommands[name].summary))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case "${words[2]}" in`)
+	for _, name := range c.commands {
+		if name == "completion" {
+			continue
+		}
+		fmt.Fprintf(w, "        %s)\n", name)
+		if flags := c.commandFlags[name]; len(flags) > 0 {
+			// Complete the subcommand's arguments as a command of its own
+			fmt.Fprintln(w, "            shift words; (( CURRENT-- ))")
+			writeZshArguments(w, "            ", flags)
+		}
+		fmt.Fprintln(w, "            return ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	writeZshArguments(w, "    ", c.flags)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "compdef _gogotrace gogotrace")
+}
+
+func (c completion) writeFish(w io.Writer) {
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+	}
+	writeFlags := func(condition string, flags []completionFlag) {
+		for _, f := range flags {
+			line := fmt.Sprintf("complete -c gogotrace -n %s -o %s -d %s", quote(condition), f.name, quote(f.usage))
+			switch f.kind {
+			case flagDir:
+				line += " -r -a '(__fish_complete_directories)'"
+			case flagFile:
+				line += " -r -F"
+			case flagEnum:
+				line += " -r -a " + quote(strings.Join(f.values, " "))
+			case flagFree:
+				line += " -r"
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	fmt.Fprintln(w, "# fish completion for gogotrace")
+	fmt.Fprintln(w, "# Load with: gogotrace completion fish | source")
+	fmt.Fprintln(w, "complete -c gogotrace -f")
+	for _, name := range c.commands {
+		fmt.Fprintf(w, "complete -c gogotrace -n __fish_use_subcommand -a %s -d %s\n", name, quote(subcommands[name].summary))
+	}
+	fmt.Fprintln(w, "complete -c gogotrace -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'")
+	fmt.Fprintln(w, "function __gogotrace_no_subcommand")
+	fmt.Fprintf(w, "    not __fish_seen_subcommand_from %s\n", strings.Join(c.commands, " "))
+	fmt.Fprintln(w, "end")
+	writeFlags("__gogotrace_no_subcommand", c.flags)
+	for _, name := range c.commands {
+		writeFlags("__fish_seen_subcommand_from "+name, c.commandFlags[name])
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubcommandFlags(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"trend", []string{"-dir", "-history", "-last"}},
+		{"pkggraph", []string{"-dir", "-format", "-log-format", "-log-level", "-no-test", "-o"}},
+		{"schema", nil},
+	}
+	for _, tt := range tests {
+		fs := subcommandFlags(tt.command)
+		if fs == nil {
+			t.Fatalf("%s: no flag set", tt.command)
+		}
+		got := flagNames(completionFlags(fs))
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: flags %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	c := completion{
+		flags:    []completionFlag{{name: "dir", kind: flagDir}, {name: "sort", kind: flagEnum, values: []string{"alpha", "depth"}}},
+		commands: []string{"pkggraph", "schema"},
+		commandFlags: map[string][]completionFlag{
+			"pkggraph": {{name: "format", kind: flagEnum, values: []string{"dot", "mermaid"}}, {name: "o", kind: flagFile}},
+		},
+	}
+	var sb strings.Builder
+	c.writeBash(&sb)
+	script := sb.String()
+	for _, want := range []string{
+		`" -dir") COMPREPLY=( $(compgen -d -- "$cur") ); return ;;`,
+		`"pkggraph -format") COMPREPLY=( $(compgen -W "dot mermaid" -- "$cur") ); return ;;`,
+		`"pkggraph -o") COMPREPLY=( $(compgen -f -- "$cur") ); return ;;`,
+		`"") words="-dir -sort" ;;`,
+		`pkggraph) words="-format -o" ;;`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("bash script lacks %s", want)
+		}
+	}
+}
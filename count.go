@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// callerCounts holds the number of distinct direct and transitive callers
+// of a function.
+type callerCounts struct {
+	direct, transitive int
+}
+
+// printCounts writes the caller counts of each signature as tab-separated
+// lines for -count, optionally broken down by the callers' packages. In
+// batch mode each block starts with a "# signature" line.
+func printCounts(w io.Writer, a *analyzer.Analyzer, signatures []string, noTests, byPackage, batch bool) error {
+	for _, sig := range signatures {
+		fn, err := a.FindFunction(sig)
+		if err != nil {
+			return err
+		}
+
+		direct := make(map[string]*analyzer.Function)
+		for _, cs := range a.GetCallersOf(fn) {
+			if !noTests || !cs.Caller.IsTest {
+				direct[cs.Caller.Key()] = cs.Caller
+			}
+		}
+		transitive := a.TransitiveCallers(fn, noTests)
+
+		if batch {
+			fmt.Fprintf(w, "# %s\n", sig)
+		}
+		if !byPackage {
+			fmt.Fprintf(w, "direct\t%d\n", len(direct))
+			fmt.Fprintf(w, "transitive\t%d\n", len(transitive))
+			continue
+		}
+
+		perPackage := make(map[string]*callerCounts)
+		count := func(fns map[string]*analyzer.Function, add func(*callerCounts)) {
+			for _, caller := range fns {
+				c, ok := perPackage[caller.Package]
+				if !ok {
+					c = &callerCounts{}
+					perPackage[caller.Package] = c
+				}
+				add(c)
+			}
+		}
+		count(direct, func(c *callerCounts) { c.direct++ })
+		count(transitive, func(c *callerCounts) { c.transitive++ })
+
+		packages := make([]string, 0, len(perPackage))
+		for pkg := range perPackage {
+			packages = append(packages, pkg)
+		}
+		sort.Strings(packages)
+		fmt.Fprintln(w, "package\tdirect\ttransitive")
+		for _, pkg := range packages {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", pkg, perPackage[pkg].direct, perPackage[pkg].transitive)
+		}
+		fmt.Fprintf(w, "total\t%d\t%d\n", len(direct), len(transitive))
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func init() {
+	subcommands["diff-graph"] = subcommand{
+		summary: "Report functions and call edges added or removed between two git revisions",
+		run:     runDiffGraph,
+	}
+}
+
+// graphSnapshot is the whole call graph of one revision, keyed by
+// line-independent identities so that unrelated edits do not show up as
+// changes.
+type graphSnapshot struct {
+	analyzer  *analyzer.Analyzer
+	functions map[string]*analyzer.Function
+	edges     map[string]bool
+	callers   map[string]map[string]bool // callee → callers
+	entries   map[string]string          // entry point → what makes it one
+}
+
+func runDiffGraph(args []string) int {
+	fs := newFlagSet("diff-graph")
+	dir := fs.String("dir", ".", "Directory to analyze, inside a git repository")
+	base := fs.String("base", "", "Base revision (required)")
+	head := fs.String("head", "HEAD", "Head revision")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	prComment := fs.String("pr-comment", "", "Write a markdown impact summary for a pull request comment to this file")
+	var funcs stringList
+	fs.Var(&funcs, "func", "Limit the pull request comment to the callers of this function (repeatable)")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace diff-graph -base <rev> [-head <rev>] [-pr-comment out.md [-func X ...]] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *base == "" {
+		fs.Usage()
+		return 2
+	}
+
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path: %v\n", err)
+		return 1
+	}
+	root, err := git(absDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// Resolve symlinks so the subdirectory is relative to git's view of the root
+	if resolved, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = resolved
+	}
+	sub, err := filepath.Rel(root, absDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path: %v\n", err)
+		return 1
+	}
+
+	var snapshots [2]*graphSnapshot
+	for i, rev := range []string{*base, *head} {
+		slog.Info("analyzing revision", "rev", rev)
+		snapshot, err := snapshotRevision(root, rev, sub, *noTests)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		snapshots[i] = snapshot
+	}
+	before, after := snapshots[0], snapshots[1]
+
+	var added, deleted []*analyzer.Function
+	for id, fn := range after.functions {
+		if _, ok := before.functions[id]; !ok {
+			added = append(added, fn)
+		}
+	}
+	for id, fn := range before.functions {
+		if _, ok := after.functions[id]; !ok {
+			deleted = append(deleted, fn)
+		}
+	}
+
+	fmt.Printf("\nStructural changes from %s to %s\n", *base, *head)
+	printFunctionSet("New functions", added)
+	printFunctionSet("Deleted functions", deleted)
+	printEdgeSet("New call edges", edgeDifference(after.edges, before.edges))
+	printEdgeSet("Removed call edges", edgeDifference(before.edges, after.edges))
+
+	if *prComment != "" {
+		comment, err := impactComment(*base, *head, before, after, funcs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(*prComment, []byte(comment), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing pull request comment: %v\n", err)
+			return 1
+		}
+		slog.Info("wrote pull request comment", "file", *prComment)
+	}
+	return 0
+}
+
+// snapshotRevision checks rev out into a temporary worktree of the
+// repository at root and analyzes its sub directory.
+func snapshotRevision(root, rev, sub string, noTests bool) (*graphSnapshot, error) {
+	tmp, err := os.MkdirTemp("", "gogotrace-diff-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	if _, err := git(root, "worktree", "add", "--detach", tmp, rev); err != nil {
+		return nil, err
+	}
+	defer git(root, "worktree", "remove", "--force", tmp)
+
+	a, ok := loadAnalyzer(filepath.Join(tmp, sub))
+	if !ok {
+		return nil, fmt.Errorf("analyzing %s failed", rev)
+	}
+	return newGraphSnapshot(a, noTests), nil
+}
+
+// newGraphSnapshot takes the call graph of an analysis.
+func newGraphSnapshot(a *analyzer.Analyzer, noTests bool) *graphSnapshot {
+	snapshot := &graphSnapshot{
+		analyzer:  a,
+		functions: make(map[string]*analyzer.Function),
+		edges:     make(map[string]bool),
+		callers:   make(map[string]map[string]bool),
+		entries:   make(map[string]string),
+	}
+	for _, fn := range a.GetFunctions() {
+		if noTests && fn.IsTest {
+			continue
+		}
+		id := stableID(fn)
+		snapshot.functions[id] = fn
+		if entries := a.EntryPoints(fn); len(entries) > 0 {
+			snapshot.entries[id] = entries[0].String()
+		} else if fn.Name == "main" && fn.Receiver == "" {
+			snapshot.entries[id] = "main"
+		} else if fn.IsExported() && !fn.IsTest {
+			snapshot.entries[id] = "exported"
+		}
+	}
+	for _, callSites := range a.GetCallGraph() {
+		for _, cs := range callSites {
+			if noTests && (cs.Caller.IsTest || cs.Callee.IsTest) {
+				continue
+			}
+			caller, callee := stableID(cs.Caller), stableID(cs.Callee)
+			snapshot.edges[caller+" → "+callee] = true
+			if snapshot.callers[callee] == nil {
+				snapshot.callers[callee] = make(map[string]bool)
+			}
+			snapshot.callers[callee][caller] = true
+		}
+	}
+	return snapshot
+}
+
+// stableID identifies a function across revisions without its line number.
+// Anonymous functions are identified by their enclosing file and type, so
+// several literals of the same type in one file count as one.
+func stableID(fn *analyzer.Function) string {
+	name := fn.Name
+	if fn.Receiver != "" {
+		name = fmt.Sprintf("(%s).%s", fn.Receiver, fn.Name)
+	}
+	if fn.Package == "." {
+		return name
+	}
+	return fn.Package + "." + name
+}
+
+// edgeDifference returns the sorted edges of a that are not in b.
+func edgeDifference(a, b map[string]bool) []string {
+	var diff []string
+	for edge := range a {
+		if !b[edge] {
+			diff = append(diff, edge)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+func printEdgeSet(title string, edges []string) {
+	fmt.Printf("\n%s (%d):\n", title, len(edges))
+	for _, edge := range edges {
+		fmt.Printf("  %s\n", edge)
+	}
+}
+
+// git runs a git command in dir and returns its trimmed standard output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func TestStableID(t *testing.T) {
+	tests := []struct {
+		fn   analyzer.Function
+		want string
+	}{
+		{analyzer.Function{Name: "main", Package: ".", Line: 3}, "main"},
+		{analyzer.Function{Name: "Load", Package: "internal/config", Line: 10}, "internal/config.Load"},
+		{analyzer.Function{Name: "Run", Receiver: "*Server", Package: "server", Line: 42}, "server.(*Server).Run"},
+		{analyzer.Function{Name: "Run", Receiver: "Server", Package: "server"}, "server.(Server).Run"},
+		{analyzer.Function{Name: "func(...) in server/server.go", Package: "server", Line: 7}, "server.func(...) in server/server.go"},
+	}
+	for _, tt := range tests {
+		if got := stableID(&tt.fn); got != tt.want {
+			t.Errorf("stableID(%s) = %q, want %q", tt.fn.Name, got, tt.want)
+		}
+	}
+
+	// Moving a function does not change its identity
+	moved := analyzer.Function{Name: "Load", Package: "internal/config", Line: 99, FullPath: "internal/config/load.go"}
+	if stableID(&moved) != stableID(&tests[1].fn) {
+		t.Errorf("stableID depends on the position")
+	}
+}
+
+func TestEdgeDifference(t *testing.T) {
+	before := map[string]bool{"main → A": true, "A → B": true, "B → C": true}
+	after := map[string]bool{"main → A": true, "A → C": true, "B → C": true}
+
+	tests := []struct {
+		name string
+		a, b map[string]bool
+		want []string
+	}{
+		{"added", after, before, []string{"A → C"}},
+		{"removed", before, after, []string{"A → B"}},
+		{"same", before, before, nil},
+		{"from nothing", before, nil, []string{"A → B", "B → C", "main → A"}},
+	}
+	for _, tt := range tests {
+		if got := edgeDifference(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: edgeDifference = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// FunctionMetrics holds the structural metrics of a function, for
+// architecture dashboards to trend over time.
+type FunctionMetrics struct {
+	Function    *analyzer.Function `json:"-"`
+	Name        string             `json:"name"`
+	Receiver    string             `json:"receiver,omitempty"`
+	Package     string             `json:"package"`
+	File        string             `json:"file"`
+	Line        int                `json:"line"`
+	FanIn       int                `json:"fanIn"`         // distinct callers
+	FanOut      int                `json:"fanOut"`        // distinct callees
+	Depth       int                `json:"depthFromMain"` // fewest calls from a main function, -1 when not reached
+	Betweenness float64            `json:"betweenness"`   // share of shortest call paths going through the function, estimated
+	LOC         int                `json:"loc"`
+	Statements  int                `json:"statements"`
+	Complexity  int                `json:"complexity"`
+}
+
+// BetweennessSamples is the number of functions shortest paths are
+// computed from to estimate betweenness. Every function is used in
+// programs that have fewer.
+const BetweennessSamples = 256
+
+// Metrics computes the metrics of every function of a, sorted by package,
+// file and line.
+func Metrics(a *analyzer.Analyzer, noTests bool) []FunctionMetrics {
+	var fns []*analyzer.Function
+	for _, fn := range a.GetFunctions() {
+		if !noTests || !fn.IsTest {
+			fns = append(fns, fn)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool {
+		if fns[i].Package != fns[j].Package {
+			return fns[i].Package < fns[j].Package
+		}
+		if fns[i].FullPath != fns[j].FullPath {
+			return fns[i].FullPath < fns[j].FullPath
+		}
+		return fns[i].Line < fns[j].Line
+	})
+
+	// Distinct edges between the functions kept
+	index := make(map[string]int, len(fns))
+	for i, fn := range fns {
+		index[fn.Key()] = i
+	}
+	out := make([][]int, len(fns))
+	in := make([]int, len(fns))
+	for key, callees := range Callees(a, noTests) {
+		from, ok := index[key]
+		if !ok {
+			continue
+		}
+		seen := make(map[int]bool)
+		for _, callee := range callees {
+			to, ok := index[callee.Key()]
+			if !ok || seen[to] {
+				continue
+			}
+			seen[to] = true
+			out[from] = append(out[from], to)
+			in[to]++
+		}
+	}
+
+	var mains []int
+	for i, fn := range fns {
+		if fn.Name == "main" && fn.Receiver == "" && !fn.IsTest {
+			mains = append(mains, i)
+		}
+	}
+	depth := distances(out, mains)
+	between := betweenness(out, BetweennessSamples)
+
+	metrics := make([]FunctionMetrics, len(fns))
+	for i, fn := range fns {
+		metrics[i] = FunctionMetrics{
+			Function:    fn,
+			Name:        fn.Name,
+			Receiver:    fn.Receiver,
+			Package:     fn.Package,
+			File:        fn.FullPath,
+			Line:        fn.Line,
+			FanIn:       in[i],
+			FanOut:      len(out[i]),
+			Depth:       depth[i],
+			Betweenness: between[i],
+			LOC:         fn.Lines(),
+			Statements:  fn.Statements,
+			Complexity:  fn.Complexity,
+		}
+	}
+	return metrics
+}
+
+// distances returns the number of edges of the shortest path from any of
+// the sources to each node, -1 for the nodes not reached.
+func distances(out [][]int, sources []int) []int {
+	dist := make([]int, len(out))
+	for i := range dist {
+		dist[i] = -1
+	}
+	queue := make([]int, 0, len(out))
+	for _, s := range sources {
+		dist[s] = 0
+		queue = append(queue, s)
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range out[v] {
+			if dist[w] < 0 {
+				dist[w] = dist[v] + 1
+				queue = append(queue, w)
+			}
+		}
+	}
+	return dist
+}
+
+// betweenness estimates the normalized betweenness centrality of each node
+// with Brandes' algorithm run from at most samples sources, spread evenly
+// over the nodes so that the estimate is the same from run to run.
+func betweenness(out [][]int, samples int) []float64 {
+	n := len(out)
+	scores := make([]float64, n)
+	if n < 3 {
+		return scores
+	}
+	step := 1
+	if n > samples {
+		step = n / samples
+	}
+
+	sigma := make([]float64, n)
+	dist := make([]int, n)
+	delta := make([]float64, n)
+	preds := make([][]int, n)
+	used := 0
+	for s := 0; s < n; s += step {
+		used++
+		for i := range sigma {
+			sigma[i], dist[i], delta[i], preds[i] = 0, -1, 0, preds[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		order := []int{s}
+		for head := 0; head < len(order); head++ {
+			v := order[head]
+			for _, w := range out[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					order = append(order, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+		// Accumulate dependencies from the farthest nodes back
+		for i := len(order) - 1; i > 0; i-- {
+			w := order[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			scores[w] += delta[w]
+		}
+	}
+
+	// Scale up to all sources, then to the share of the (n-1)(n-2)
+	// ordered pairs of other nodes
+	scale := float64(n) / float64(used) / float64((n-1)*(n-2))
+	for i := range scores {
+		scores[i] *= scale
+	}
+	return scores
+}
@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistancesAndBetweenness(t *testing.T) {
+	// main → a → b → d and main → c → d: a, b and c lie on shortest
+	// paths, main and d only end them
+	out := [][]int{
+		0: {1, 3},
+		1: {2},
+		2: {4},
+		3: {4},
+		4: nil,
+	}
+
+	if got, want := distances(out, []int{0}), []int{0, 1, 2, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("distances() = %v, want %v", got, want)
+	}
+
+	scores := betweenness(out, BetweennessSamples)
+	if scores[0] != 0 || scores[4] != 0 {
+		t.Errorf("betweenness of the ends = %v and %v, want 0", scores[0], scores[4])
+	}
+	// a is on the shortest path main → b, b on a → d and c on main → d
+	if scores[1] <= 0 || scores[2] <= 0 || scores[3] <= 0 {
+		t.Errorf("betweenness = %v, want a positive score for nodes 1 to 3", scores)
+	}
+}
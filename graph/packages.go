@@ -0,0 +1,125 @@
+// Package graph derives whole-program views from an analyzed call graph.
+package graph
+
+import (
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// PackageEdge aggregates the calls from functions of one package into
+// another.
+type PackageEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Calls int    `json:"calls"`
+}
+
+// PackageEdges aggregates the call graph of a into package-to-package
+// edges, sorted by source then target. Calls within a package are left out.
+func PackageEdges(a *analyzer.Analyzer, noTests bool) []PackageEdge {
+	counts := make(map[[2]string]int)
+	for _, callSites := range a.GetCallGraph() {
+		for _, cs := range callSites {
+			if noTests && (cs.Caller.IsTest || cs.Callee.IsTest) {
+				continue
+			}
+			if cs.Caller.Package == cs.Callee.Package {
+				continue
+			}
+			counts[[2]string{cs.Caller.Package, cs.Callee.Package}]++
+		}
+	}
+
+	edges := make([]PackageEdge, 0, len(counts))
+	for pair, n := range counts {
+		edges = append(edges, PackageEdge{From: pair[0], To: pair[1], Calls: n})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// PackageCycles returns the groups of packages that depend on each other
+// through calls, each sorted, largest group first.
+func PackageCycles(edges []PackageEdge) [][]string {
+	adj := make(map[string][]string)
+	for _, e := range edges {
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+	var cycles [][]string
+	for _, scc := range stronglyConnected(adj) {
+		if len(scc) > 1 {
+			sort.Strings(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		if len(cycles[i]) != len(cycles[j]) {
+			return len(cycles[i]) > len(cycles[j])
+		}
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// stronglyConnected runs Tarjan's algorithm over adj.
+func stronglyConnected(adj map[string][]string) [][]string {
+	nodes := make([]string, 0, len(adj))
+	for n := range adj {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+	next := 0
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = next
+		low[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range adj[v] {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+
+		if low[v] == index[v] {
+			var scc []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			sccs = append(sccs, scc)
+		}
+	}
+
+	for _, n := range nodes {
+		if _, seen := index[n]; !seen {
+			visit(n)
+		}
+	}
+	return sccs
+}
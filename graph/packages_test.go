@@ -0,0 +1,21 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageCycles(t *testing.T) {
+	edges := []PackageEdge{
+		{From: "api", To: "store", Calls: 3},
+		{From: "store", To: "cache", Calls: 1},
+		{From: "cache", To: "store", Calls: 2},
+		{From: "cmd", To: "api", Calls: 1},
+	}
+
+	got := PackageCycles(edges)
+	want := [][]string{{"cache", "store"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageCycles() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// Callees inverts the call graph of a: it maps each function key to the
+// functions it calls.
+func Callees(a *analyzer.Analyzer, noTests bool) map[string][]*analyzer.Function {
+	callees := make(map[string][]*analyzer.Function)
+	for _, callSites := range a.GetCallGraph() {
+		for _, cs := range callSites {
+			if noTests && (cs.Caller.IsTest || cs.Callee.IsTest) {
+				continue
+			}
+			key := cs.Caller.Key()
+			callees[key] = append(callees[key], cs.Callee)
+		}
+	}
+	// The call graph is a map, so give each list a fixed order
+	for _, fns := range callees {
+		sort.Slice(fns, func(i, j int) bool { return fns[i].Key() < fns[j].Key() })
+	}
+	return callees
+}
+
+// Reachable returns every function reachable from the entry points,
+// including the entry points themselves, keyed by function key.
+func Reachable(a *analyzer.Analyzer, entries []*analyzer.Function, noTests bool) map[string]*analyzer.Function {
+	callees := Callees(a, noTests)
+	reached := make(map[string]*analyzer.Function)
+	queue := append([]*analyzer.Function(nil), entries...)
+	for _, fn := range entries {
+		reached[fn.Key()] = fn
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, callee := range callees[current.Key()] {
+			key := callee.Key()
+			if _, seen := reached[key]; seen {
+				continue
+			}
+			reached[key] = callee
+			queue = append(queue, callee)
+		}
+	}
+	return reached
+}
+
+// ReverseReach walks the call graph backwards from target. Functions for
+// which stop returns true are reached but not walked past. The returned
+// map holds every reached caller; next maps each reached key to the
+// function one step closer to target, so paths can be reconstructed with
+// PathTo.
+func ReverseReach(a *analyzer.Analyzer, target *analyzer.Function, noTests bool, stop func(*analyzer.Function) bool) (reached map[string]*analyzer.Function, next map[string]*analyzer.Function) {
+	reached = make(map[string]*analyzer.Function)
+	next = make(map[string]*analyzer.Function)
+	queue := []*analyzer.Function{target}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, cs := range a.GetCallersOf(current) {
+			if noTests && cs.Caller.IsTest {
+				continue
+			}
+			key := cs.Caller.Key()
+			if _, seen := reached[key]; seen || key == target.Key() {
+				continue
+			}
+			reached[key] = cs.Caller
+			next[key] = current
+			if stop == nil || !stop(cs.Caller) {
+				queue = append(queue, cs.Caller)
+			}
+		}
+	}
+	return reached, next
+}
+
+// PathTo follows next from fn to the target of a ReverseReach, returning
+// the functions on the way, fn first and target last.
+func PathTo(fn *analyzer.Function, next map[string]*analyzer.Function) []*analyzer.Function {
+	path := []*analyzer.Function{fn}
+	for {
+		step, ok := next[fn.Key()]
+		if !ok {
+			return path
+		}
+		path = append(path, step)
+		fn = step
+	}
+}
@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WriteDOT renders package edges as a Graphviz digraph. Edges inside a
+// dependency cycle are drawn in red.
+func WriteDOT(w io.Writer, edges []PackageEdge) error {
+	cyclic := cyclicEdges(edges)
+
+	fmt.Fprintln(w, "digraph packages {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box, fontname=\"Helvetica\"];")
+	for _, e := range edges {
+		attrs := fmt.Sprintf("label=\"%d\"", e.Calls)
+		if cyclic[[2]string{e.From, e.To}] {
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(w, "  %q -> %q [%s];\n", e.From, e.To, attrs)
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// WriteMermaid renders package edges as a Mermaid flowchart. Edges inside
+// a dependency cycle are drawn in red.
+func WriteMermaid(w io.Writer, edges []PackageEdge) error {
+	cyclic := cyclicEdges(edges)
+
+	var pkgs []string
+	ids := make(map[string]string)
+	for _, e := range edges {
+		for _, p := range []string{e.From, e.To} {
+			if _, ok := ids[p]; !ok {
+				ids[p] = ""
+				pkgs = append(pkgs, p)
+			}
+		}
+	}
+	sort.Strings(pkgs)
+
+	fmt.Fprintln(w, "flowchart LR")
+	for i, p := range pkgs {
+		ids[p] = fmt.Sprintf("p%d", i)
+		fmt.Fprintf(w, "  %s[\"%s\"]\n", ids[p], p)
+	}
+	var red []int
+	for i, e := range edges {
+		fmt.Fprintf(w, "  %s -->|%d| %s\n", ids[e.From], e.Calls, ids[e.To])
+		if cyclic[[2]string{e.From, e.To}] {
+			red = append(red, i)
+		}
+	}
+	for _, i := range red {
+		fmt.Fprintf(w, "  linkStyle %d stroke:red\n", i)
+	}
+	return nil
+}
+
+// cyclicEdges returns the edges whose ends lie in the same dependency
+// cycle.
+func cyclicEdges(edges []PackageEdge) map[[2]string]bool {
+	group := make(map[string]int)
+	for i, cycle := range PackageCycles(edges) {
+		for _, p := range cycle {
+			group[p] = i + 1
+		}
+	}
+	cyclic := make(map[[2]string]bool)
+	for _, e := range edges {
+		if g := group[e.From]; g != 0 && g == group[e.To] {
+			cyclic[[2]string{e.From, e.To}] = true
+		}
+	}
+	return cyclic
+}
@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// Tags propagates the //gogotrace:tag tags of a down the call graph: each
+// function gets its own tags and those of every function that can reach
+// it, so that code called from a "payments" handler is tagged "payments"
+// too. The result maps function keys to sorted tags and leaves out
+// untagged functions.
+func Tags(a *analyzer.Analyzer, noTests bool) map[string][]string {
+	tagged := make(map[string][]*analyzer.Function)
+	for _, fn := range a.GetFunctions() {
+		if noTests && fn.IsTest {
+			continue
+		}
+		for _, tag := range a.Tags(fn) {
+			tagged[tag] = append(tagged[tag], fn)
+		}
+	}
+
+	tags := make(map[string][]string)
+	for tag, fns := range tagged {
+		for key := range Reachable(a, fns, noTests) {
+			tags[key] = append(tags[key], tag)
+		}
+	}
+	for _, list := range tags {
+		sort.Strings(list)
+	}
+	return tags
+}
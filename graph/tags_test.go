@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func TestTags(t *testing.T) {
+	src := `package shop
+
+// Checkout charges the cart.
+//gogotrace:tag payments
+//gogotrace:tag critical
+func Checkout() { charge(); log() }
+
+func Refund() { charge() } //gogotrace:tag payments
+
+func charge() { log() }
+
+func log() {}
+
+func unrelated() { log() }
+`
+	a := analyzer.NewAnalyzer(analyzer.WithFS(fstest.MapFS{"shop.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	tags := Tags(a, false)
+	want := map[string]string{
+		"Checkout":  "critical payments",
+		"Refund":    "payments",
+		"charge":    "critical payments",
+		"log":       "critical payments",
+		"unrelated": "",
+	}
+	for _, fn := range a.GetFunctions() {
+		if got := strings.Join(tags[fn.Key()], " "); got != want[fn.Name] {
+			t.Errorf("%s tagged %q, want %q", fn.Name, got, want[fn.Name])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func init() {
+	subcommands["implements"] = subcommand{
+		summary: "List the types implementing an interface and the callers of their methods",
+		run:     runImplements,
+	}
+}
+
+func runImplements(args []string) int {
+	fs := newFlagSet("implements")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	name := fs.String("interface", "", "Interface to look for, e.g. io.Reader or store.Store")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace implements -interface <name> [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" {
+		fs.Usage()
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	iface, err := a.FindInterface(*name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	impls := a.Implementations(iface)
+	fmt.Printf("\n%s is implemented by %d types\n", *name, len(impls))
+
+	for _, impl := range impls {
+		typeName := impl.Type
+		if impl.Pointer {
+			typeName = "*" + typeName
+		}
+		fmt.Printf("\n%s in %s\n", typeName, impl.Package)
+		for _, method := range impl.Methods {
+			fmt.Printf("  %s in %s:%d\n", method.Signature, method.FullPath, method.Line)
+			var callers []*analyzer.Function
+			seen := make(map[string]bool)
+			for _, cs := range a.GetCallersOf(method) {
+				if (*noTests && cs.Caller.IsTest) || seen[cs.Caller.Key()] {
+					continue
+				}
+				seen[cs.Caller.Key()] = true
+				callers = append(callers, cs.Caller)
+			}
+			if len(callers) == 0 {
+				fmt.Println("    no callers")
+				continue
+			}
+			sortFunctions(callers)
+			for _, caller := range callers {
+				fmt.Printf("    ← %s in %s:%d\n", caller.Signature, caller.FullPath, caller.Line)
+			}
+		}
+	}
+	return 0
+}
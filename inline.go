@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func init() {
+	subcommands["inline"] = subcommand{
+		summary: "Report whether a function can be inlined at each of its call sites",
+		run:     runInline,
+	}
+}
+
+func runInline(args []string) int {
+	fs := newFlagSet("inline")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	signature := fs.String("func", "", "Function or method to inline")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace inline -func <signature> [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *signature == "" {
+		fs.Usage()
+		return 2
+	}
+
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path: %v\n", err)
+		return 1
+	}
+	a, ok := loadAnalyzer(absDir)
+	if !ok {
+		return 1
+	}
+	fn, err := a.FindFunction(*signature)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	in, err := a.Inlining(fn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", fn.FullPath, err)
+		return 1
+	}
+
+	verdict := "straightforward"
+	if !in.Straightforward() {
+		verdict = "not straightforward: " + strings.Join(in.Reasons, ", ")
+	}
+	fmt.Printf("Inlining %s (%s:%d, %d statements): %s\n", formatPath([]*analyzer.Function{fn}), fn.FullPath, fn.Line, in.Statements, verdict)
+
+	var sites []*analyzer.CallSite
+	seen := make(map[string]bool)
+	for _, cs := range a.GetCallersOf(fn) {
+		key := fmt.Sprintf("%s:%d:%d", cs.Caller.FullPath, cs.Line, cs.Column)
+		if (*noTests && cs.Caller.IsTest) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		sites = append(sites, cs)
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Caller.FullPath != sites[j].Caller.FullPath {
+			return sites[i].Caller.FullPath < sites[j].Caller.FullPath
+		}
+		if sites[i].Line != sites[j].Line {
+			return sites[i].Line < sites[j].Line
+		}
+		return sites[i].Column < sites[j].Column
+	})
+
+	ready := 0
+	for _, cs := range sites {
+		line := fmt.Sprintf("  %s:%d:%d  %s", cs.Caller.FullPath, cs.Line, cs.Column, formatPath([]*analyzer.Function{cs.Caller}))
+		if reason := a.InlineSite(cs, in); reason != "" {
+			line += "  [" + reason + "]"
+		} else if in.Straightforward() {
+			ready++
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("%d of %d call sites can be inlined as is\n", ready, len(sites))
+	return 0
+}
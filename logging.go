@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// Progress and diagnostics are logged to stderr, leaving stdout to the
+// results so that they can be piped or redirected as they are.
+var (
+	logLevel  slog.Level
+	logFormat = "text"
+)
+
+var logFormats = []string{"text", "json"}
+
+// addLogFlags registers -log-level and -log-format on flags.
+func addLogFlags(flags *flag.FlagSet) {
+	flags.TextVar(&logLevel, "log-level", slog.LevelInfo, "Log progress to stderr at this level and above: debug, info, warn or error")
+	flags.Func("log-format", "Format of the log lines on stderr: text or json (default text)", func(s string) error {
+		if !oneOf(s, logFormats) {
+			return fmt.Errorf("want text or json")
+		}
+		logFormat = s
+		return nil
+	})
+}
+
+// setupLogging makes the default logger write to stderr as the log flags
+// say, and returns the analyzer options to match. Progress bars are only
+// drawn when stderr is a terminal showing text logs at info level.
+func setupLogging() []analyzer.Option {
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if logFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	if logFormat == "text" && logLevel <= slog.LevelInfo && isTerminal(os.Stderr) {
+		return []analyzer.Option{analyzer.WithProgress(os.Stderr)}
+	}
+	return nil
+}
@@ -1,18 +1,63 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/blame"
+	"github.com/gogotrace/gogotrace/graph"
 	"github.com/gogotrace/gogotrace/output"
+	"github.com/gogotrace/gogotrace/owners"
 	"github.com/gogotrace/gogotrace/tree"
+	"github.com/gogotrace/gogotrace/version"
 )
 
+// subcommand is a gogotrace mode invoked as "gogotrace <name> [args]".
+type subcommand struct {
+	summary string
+	run     func(args []string) int
+}
+
+// subcommands maps each subcommand name to its implementation. Subcommands
+// register themselves from init functions.
+var subcommands = map[string]subcommand{}
+
+// collectFlagSet, when set, is handed every flag set newFlagSet creates.
+var collectFlagSet func(*flag.FlagSet)
+
+// newFlagSet creates the flag set of a subcommand, which parses its
+// arguments with it before doing anything else.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if collectFlagSet != nil {
+		fs.SetOutput(io.Discard)
+		collectFlagSet(fs)
+	}
+	return fs
+}
+
+// subcommandFlags returns the flag set of a subcommand, by running it with
+// -h: it stops at parsing without doing or printing anything.
+func subcommandFlags(name string) *flag.FlagSet {
+	var fs *flag.FlagSet
+	collectFlagSet = func(f *flag.FlagSet) { fs = f }
+	defer func() { collectFlagSet = nil }()
+	subcommands[name].run([]string{"-h"})
+	return fs
+}
+
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
 	var (
 		targetDir  string
 		signature  string
@@ -26,103 +71,708 @@ func main() {
 
 	flag.StringVar(&targetDir, "dir", ".", "Directory to analyze")
 	flag.StringVar(&signature, "func", "", "Function signature to trace (required)")
+	var funcFile string
+	flag.StringVar(&funcFile, "func-file", "", "Trace every signature listed in file, one per line (- for stdin)")
 	flag.StringVar(&jsonOutput, "json", "", "Output results to JSON file")
+	var jsonFormat string
+	flag.StringVar(&jsonFormat, "json-format", "tree", "Shape of the JSON output: tree or edges")
 	flag.StringVar(&htmlOutput, "html", "", "Output results to HTML file")
+	var xmlOutput string
+	flag.StringVar(&xmlOutput, "xml", "", "Output results to XML file")
+	var xlsxOutput string
+	flag.StringVar(&xlsxOutput, "xlsx", "", "Output the calls and a summary per package and owner to an XLSX spreadsheet")
+	var treemapOutput string
+	flag.StringVar(&treemapOutput, "treemap", "", "Output an HTML treemap of the calling packages sized by call sites")
+	var perfettoOutput string
+	flag.StringVar(&perfettoOutput, "perfetto", "", "Output the call trees to a Chrome trace file for the flame view of ui.perfetto.dev")
+	var foldedOutput, flamegraphOutput string
+	flag.StringVar(&foldedOutput, "folded", "", "Output the caller paths to a file as folded stacks, the input of flamegraph.pl")
+	flag.StringVar(&flamegraphOutput, "flamegraph", "", "Output the caller paths to an SVG flame graph")
+	var pbOutput, pbJSONOutput string
+	flag.StringVar(&pbOutput, "pb", "", "Output the call graph to a binary protobuf file (Graph message of output/graph.proto)")
+	flag.StringVar(&pbJSONOutput, "pbjson", "", "Output the call graph to a file in the protobuf JSON mapping")
+	var openHTML bool
+	flag.BoolVar(&openHTML, "open", false, "Open the HTML report in the browser once written")
 	flag.BoolVar(&noTests, "no-test", false, "Exclude test functions from results")
+	var noBench, noFuzz, noExamples, onlyTests bool
+	flag.BoolVar(&noBench, "no-bench", false, "Exclude benchmarks from results")
+	flag.BoolVar(&noFuzz, "no-fuzz", false, "Exclude fuzz targets from results")
+	flag.BoolVar(&noExamples, "no-examples", false, "Exclude example functions from results")
+	var noGenerated bool
+	flag.BoolVar(&noGenerated, "no-generated", false, "Exclude functions from generated files (Code generated ... DO NOT EDIT.)")
+	flag.BoolVar(&onlyTests, "only-tests", false, "Keep only call paths that end in a test, benchmark, fuzz target or example")
+	var minUsages int
+	flag.IntVar(&minUsages, "min-usages", 0, "Drop callers with fewer call sites than N")
+	var minLOC int
+	flag.IntVar(&minLOC, "min-loc", 0, "Drop callers spanning fewer lines than N, such as trivial getters")
 	flag.BoolVar(&help, "help", false, "Show help message")
+	var showVersion bool
+	flag.BoolVar(&showVersion, "version", false, "Print version and build information")
 	flag.StringVar(&listFuncs, "list", "", "List functions matching pattern")
-	flag.BoolVar(&showParams, "params", false, "Show function parameters in output")
+	flag.BoolVar(&showParams, "params", false, "Show function parameters and results in output")
+	var maxDepth, maxNodes int
+	flag.IntVar(&maxDepth, "max-depth", tree.DefaultMaxDepth, "Stop expanding callers deeper than N levels")
+	flag.IntVar(&maxNodes, "max-nodes", 0, "Emit at most N caller nodes per tree, 0 for no limit")
+	var focusPkg string
+	flag.StringVar(&focusPkg, "focus", "", "Keep only call paths passing through package (directory or import path)")
+	var tagFilter string
+	flag.StringVar(&tagFilter, "tag", "", "Keep only call paths passing through a function tagged so by a //gogotrace:tag comment")
+	var exportedOnly bool
+	flag.BoolVar(&exportedOnly, "exported-only", false, "Show only exported functions and methods, linked through unexported ones")
+	var mocks bool
+	flag.BoolVar(&mocks, "mocks", false, "Include the tests setting expectations on gomock or mockery mocks of the traced method")
+	var uniqueCallers bool
+	flag.BoolVar(&uniqueCallers, "unique-callers", false, "Show each caller once, at its shallowest occurrence, with a count of its other paths")
+	var seeThroughWrappers bool
+	flag.BoolVar(&seeThroughWrappers, "see-through-wrappers", false, "Show the callers of wrapper functions in their place")
+	var collapseDeps bool
+	flag.BoolVar(&collapseDeps, "collapse-deps", false, "Summarize callers from each third-party package in one node")
+	var auditContext bool
+	flag.BoolVar(&auditContext, "audit-context", false, "Flag calls that drop a context.Context or pass a fresh one mid-chain")
+	var auditErrors bool
+	flag.BoolVar(&auditErrors, "audit-errors", false, "Show whether each caller propagates, wraps, logs or swallows returned errors")
+	var showLocks bool
+	flag.BoolVar(&showLocks, "locks", false, "Flag callers that make the call while holding a mutex")
+	var codeOwners string
+	flag.StringVar(&codeOwners, "codeowners", "", "Label callers with their owners from a CODEOWNERS file (auto to search the analyzed directory and its parents)")
+	var ownersMap string
+	flag.StringVar(&ownersMap, "owners-map", "", "Label callers with the teams of a file mapping path prefixes to owners, when there is no CODEOWNERS")
+	var ticketsDir string
+	flag.StringVar(&ticketsDir, "tickets", "", "Write one markdown checklist per owning team of the direct call sites to directory (needs -codeowners or -owners-map)")
+	var captureArgs bool
+	flag.BoolVar(&captureArgs, "args", false, "Record the argument expressions passed at each call site (JSON and HTML)")
+	var blameCallers bool
+	flag.BoolVar(&blameCallers, "blame", false, "Attach the last author and commit date of each call site (JSON and HTML)")
+	var sortBy string
+	flag.StringVar(&sortBy, "sort", "package", "Order callers by usages, depth, alpha or package")
+	var absPaths bool
+	flag.BoolVar(&absPaths, "abs-paths", false, "Print absolute file paths with line and column")
+	var editor string
+	flag.StringVar(&editor, "editor", "", "Link locations to an editor: vscode, goland or vim (console and HTML)")
+	var showDocs bool
+	flag.BoolVar(&showDocs, "docs", false, "Show the first sentence of each function's doc comment in console output")
+	var showComplexity bool
+	flag.BoolVar(&showComplexity, "show-complexity", false, "Show the cyclomatic complexity of each function (console, HTML and -list)")
+	var colorBy string
+	flag.StringVar(&colorBy, "color-by", "", "Color console function names by depth or package")
+	var guides bool
+	flag.BoolVar(&guides, "guides", false, "Color console tree guides by depth")
+	var hyperlinks string
+	flag.StringVar(&hyperlinks, "hyperlinks", "auto", "Make console locations clickable file links: auto, always or never")
+	var count, countPackages bool
+	flag.BoolVar(&count, "count", false, "Print only the number of direct and transitive callers")
+	flag.BoolVar(&countPackages, "count-packages", false, "With -count, break the numbers down by package")
+	var top int
+	flag.IntVar(&top, "top", 0, "Print only the N callers most central in the call graph (PageRank), the first to migrate")
+	var noPager bool
+	flag.BoolVar(&noPager, "no-pager", false, "Do not page console output through $PAGER")
+	var collapseChains bool
+	flag.BoolVar(&collapseChains, "collapse-chains", false, "Fold single-caller chains into one line (console and HTML)")
 	var debug bool
-	flag.BoolVar(&debug, "debug", false, "Show debug information")
+	flag.BoolVar(&debug, "debug", false, "Log debug information, as -log-level debug does")
+	var skipDirs string
+	flag.StringVar(&skipDirs, "skip-dirs", strings.Join(analyzer.DefaultSkipDirs, ","), "Comma-separated directory names not to analyze")
+	var includeDirs string
+	flag.StringVar(&includeDirs, "include-dirs", "", "Comma-separated directory names to analyze even though -skip-dirs lists them")
+	var includeTestdata bool
+	flag.BoolVar(&includeTestdata, "include-testdata", false, "Analyze testdata directories")
+	var typeName string
+	flag.StringVar(&typeName, "type", "", "Trace every method of a type as one tree (e.g. Service or server.Service)")
+	var packageDir string
+	flag.StringVar(&packageDir, "package", "", "Trace the external callers of every function of a package (e.g. ./internal/auth)")
+	var receiver string
+	flag.StringVar(&receiver, "receiver", "", "Only match methods with a pointer or value receiver")
+	var allPlatforms bool
+	flag.BoolVar(&allPlatforms, "all-platforms", false, "Unite the call graphs of every GOOS/GOARCH and tag platform-specific callers")
+	var hostOnly bool
+	flag.BoolVar(&hostOnly, "host-only", false, "Only analyze the files the host GOOS/GOARCH builds, as go build does")
+	var edgesFile string
+	flag.StringVar(&edgesFile, "edges", "", "Merge the calls declared in a YAML file into the call graph")
+	var stitchGRPC bool
+	flag.BoolVar(&stitchGRPC, "stitch-grpc", false, "Link generated gRPC client methods to the server methods they call")
+	var stitchHTTP bool
+	flag.BoolVar(&stitchHTTP, "stitch-http", false, "Link HTTP requests to the handlers registered for their paths")
+	var routesFile string
+	flag.StringVar(&routesFile, "routes", "", "With -stitch-http, also link requests to the routes of a route file or OpenAPI spec")
+	var resolverPlugins stringList
+	flag.Var(&resolverPlugins, "resolver", "Resolve calls with the CallResolver of a Go plugin (repeatable)")
+	var entryPatterns string
+	flag.StringVar(&entryPatterns, "entry-patterns", "", "Label the handlers of the callback registrations described in file as entry points")
+	var files stringList
+	flag.Var(&files, "files", "Only analyze the Go files matching a glob such as 'internal/**/*.go' (repeatable)")
+	var useBazel bool
+	flag.BoolVar(&useBazel, "bazel", false, "Analyze the Go sources of the Bazel workspace's rules, found with bazel query")
+	var followSymlinks bool
+	flag.BoolVar(&followSymlinks, "follow-symlinks", false, "Descend into symbolic links to directories")
+	var prefilter bool
+	flag.BoolVar(&prefilter, "prefilter", false, "Skip parsing files that cannot reach the traced function")
+	var cpuProfile, memProfile, traceFile string
+	flag.StringVar(&cpuProfile, "cpuprofile", "", "Write a CPU profile of gogotrace to file")
+	flag.StringVar(&memProfile, "memprofile", "", "Write a heap profile of gogotrace to file")
+	flag.StringVar(&traceFile, "trace", "", "Write an execution trace of gogotrace to file")
+	addLogFlags(flag.CommandLine)
+
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			return cmd.run(os.Args[2:])
+		}
+	}
 
 	flag.Parse()
 
-	if help || (signature == "" && listFuncs == "") {
+	if showVersion {
+		fmt.Println(version.Get())
+		return 0
+	}
+
+	if help || (signature == "" && funcFile == "" && listFuncs == "" && typeName == "" && packageDir == "") {
 		printUsage()
-		os.Exit(0)
+		return 0
+	}
+
+	if !oneOf(sortBy, tree.SortOrders) {
+		fmt.Fprintf(os.Stderr, "Invalid -sort value %q (want one of: %s)\n", sortBy, strings.Join(tree.SortOrders, ", "))
+		return 1
+	}
+	if !oneOf(hyperlinks, hyperlinkModes) {
+		fmt.Fprintf(os.Stderr, "Invalid -hyperlinks value %q (want one of: %s)\n", hyperlinks, strings.Join(hyperlinkModes, ", "))
+		return 1
+	}
+	if typeName != "" && (signature != "" || funcFile != "" || count || top > 0) {
+		fmt.Fprintln(os.Stderr, "-type cannot be combined with -func, -func-file, -count or -top")
+		return 1
 	}
+	if packageDir != "" && (signature != "" || funcFile != "" || typeName != "" || count || top > 0) {
+		fmt.Fprintln(os.Stderr, "-package cannot be combined with -func, -func-file, -type, -count or -top")
+		return 1
+	}
+	if allPlatforms && hostOnly {
+		fmt.Fprintln(os.Stderr, "-all-platforms cannot be combined with -host-only")
+		return 1
+	}
+	if top < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid -top value %d (want a positive number)\n", top)
+		return 1
+	}
+	if receiver != "" && !oneOf(receiver, []string{analyzer.ReceiverPointer, analyzer.ReceiverValue}) {
+		fmt.Fprintf(os.Stderr, "Invalid -receiver value %q (want one of: %s, %s)\n", receiver, analyzer.ReceiverPointer, analyzer.ReceiverValue)
+		return 1
+	}
+	if colorBy != "" && !oneOf(colorBy, output.ColorModes) {
+		fmt.Fprintf(os.Stderr, "Invalid -color-by value %q (want one of: %s)\n", colorBy, strings.Join(output.ColorModes, ", "))
+		return 1
+	}
+	if !oneOf(jsonFormat, output.JSONFormats) {
+		fmt.Fprintf(os.Stderr, "Invalid -json-format value %q (want one of: %s)\n", jsonFormat, strings.Join(output.JSONFormats, ", "))
+		return 1
+	}
+	if editor != "" && !oneOf(editor, output.Editors) {
+		fmt.Fprintf(os.Stderr, "Invalid -editor value %q (want one of: %s)\n", editor, strings.Join(output.Editors, ", "))
+		return 1
+	}
+
+	if debug {
+		logLevel = slog.LevelDebug
+	}
+	logOpts := setupLogging()
 
-	targetDir, err := filepath.Abs(targetDir)
+	stopProfiling, err := startProfiling(cpuProfile, memProfile, traceFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting profiling: %v\n", err)
+		return 1
+	}
+	defer stopProfiling()
+
+	targetDir, err = filepath.Abs(targetDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error resolving directory path: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
 		fmt.Fprintf(os.Stderr, "Directory does not exist: %s\n", targetDir)
-		os.Exit(1)
+		return 1
+	}
+
+	if ticketsDir != "" && codeOwners == "" && ownersMap == "" {
+		fmt.Fprintln(os.Stderr, "-tickets needs -codeowners or -owners-map to group the call sites by team")
+		return 1
+	}
+	var ownership owners.Source
+	if codeOwners != "" {
+		path := codeOwners
+		if path == "auto" {
+			path, err = owners.Locate(targetDir)
+		}
+		var co *owners.CODEOWNERS
+		if err == nil {
+			co, err = owners.Load(path)
+		}
+		// Without a CODEOWNERS file, auto falls back on -owners-map
+		switch {
+		case err == nil:
+			ownership = co
+		case codeOwners != "auto" || ownersMap == "":
+			fmt.Fprintf(os.Stderr, "Error reading CODEOWNERS: %v\n", err)
+			return 1
+		}
+	}
+	if ownership == nil && ownersMap != "" {
+		mapping, err := owners.LoadMapping(ownersMap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading owners map: %v\n", err)
+			return 1
+		}
+		ownership = mapping
+	}
+
+	var signatures []string
+	if signature != "" {
+		signatures = append(signatures, signature)
+	}
+	if funcFile != "" {
+		fileSignatures, err := readSignatures(funcFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading function list: %v\n", err)
+			return 1
+		}
+		signatures = append(signatures, fileSignatures...)
 	}
+	batch := funcFile != "" || packageDir != ""
 
-	fmt.Printf("Analyzing directory: %s\n", targetDir)
-	fmt.Printf("Looking for function: %s\n", signature)
+	slog.Info("analyzing directory", "dir", targetDir)
+	if typeName != "" {
+		slog.Info("looking for methods of type", "type", typeName)
+	} else if packageDir != "" {
+		slog.Info("looking for functions of package", "package", packageDir)
+	} else if batch {
+		slog.Info("looking for functions", "count", len(signatures))
+	} else {
+		slog.Info("looking for function", "signature", signature)
+	}
 	if noTests {
-		fmt.Println("Excluding test functions")
+		slog.Info("excluding test functions")
 	}
-	fmt.Println()
 
-	a := analyzer.NewAnalyzer()
+	opts := logOpts
+	if prefilter && len(signatures) > 0 {
+		opts = append(opts, analyzer.WithPrefilter(signatures...))
+	}
+	if followSymlinks {
+		opts = append(opts, analyzer.WithFollowSymlinks())
+	}
+	if len(files) > 0 {
+		opts = append(opts, analyzer.WithFiles(files...))
+	}
+	if useBazel {
+		sources, err := bazelSources(targetDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing Bazel sources: %v\n", err)
+			return 1
+		}
+		opts = append(opts, analyzer.WithSourceFiles(sources...))
+	}
+	if allPlatforms {
+		opts = append(opts, analyzer.WithAllPlatforms())
+	}
+	if hostOnly {
+		opts = append(opts, analyzer.WithHostPlatform())
+	}
+	if receiver != "" {
+		opts = append(opts, analyzer.WithReceiverKind(receiver))
+	}
+	if entryPatterns != "" {
+		patterns, err := readEntryPatterns(entryPatterns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading entry patterns: %v\n", err)
+			return 1
+		}
+		opts = append(opts, analyzer.WithEntryPatterns(patterns...))
+	}
+	included := make(map[string]bool)
+	for _, name := range strings.Split(includeDirs, ",") {
+		included[strings.TrimSpace(name)] = true
+	}
+	if includeTestdata {
+		included["testdata"] = true
+	}
+	for _, path := range resolverPlugins {
+		r, err := loadResolver(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading resolver: %v\n", err)
+			return 1
+		}
+		opts = append(opts, analyzer.WithResolvers(r))
+	}
+	var skipNames []string
+	for _, name := range strings.Split(skipDirs, ",") {
+		if name = strings.TrimSpace(name); name != "" && !included[name] {
+			skipNames = append(skipNames, name)
+		}
+	}
+	opts = append(opts, analyzer.WithSkipDirs(skipNames...))
+	a := analyzer.NewAnalyzer(opts...)
 
 	if err := a.LoadPackages(targetDir); err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+	if edgesFile != "" {
+		edges, err := readEdges(edgesFile)
+		if err == nil {
+			err = a.AddEdges(edges)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading edges: %s: %v\n", edgesFile, err)
+			return 1
+		}
+	}
+	if stitchGRPC {
+		slog.Info("linked gRPC client methods to their servers", "methods", a.StitchGRPC())
+	}
+	if stitchHTTP || routesFile != "" {
+		var routes []analyzer.Route
+		var err error
+		if routesFile != "" {
+			routes, err = readRoutes(routesFile)
+		}
+		var n int
+		if err == nil {
+			n, err = a.StitchHTTP(routes)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading routes: %s: %v\n", routesFile, err)
+			return 1
+		}
+		slog.Info("linked HTTP requests to their handlers", "requests", n)
 	}
-
-	fmt.Println()
 
 	if listFuncs != "" {
 		fmt.Println("Functions matching pattern:")
+		var matches []*analyzer.Function
 		for _, fn := range a.GetFunctions() {
 			if strings.Contains(fn.Signature, listFuncs) || strings.Contains(fn.Name, listFuncs) {
+				matches = append(matches, fn)
+			}
+		}
+		sortFunctions(matches)
+		for _, fn := range matches {
+			if showComplexity {
+				fmt.Printf("  %s in %s (complexity %d)\n", fn.Signature, fn.FullPath, fn.Complexity)
+			} else {
 				fmt.Printf("  %s in %s\n", fn.Signature, fn.FullPath)
 			}
 		}
-		return
+		return 0
 	}
 
-	callTree := tree.NewCallTree(a, noTests)
-	if err := callTree.Build(signature); err != nil {
-		fmt.Fprintf(os.Stderr, "Error building call tree: %v\n", err)
-		os.Exit(1)
+	if count {
+		if err := printCounts(os.Stdout, a, signatures, noTests, countPackages, batch); err != nil {
+			fmt.Fprintf(os.Stderr, "Error counting callers: %v\n", err)
+			return 1
+		}
+		return 0
 	}
 
-	if debug {
-		fmt.Println("\nDebug: Call graph analysis")
-		fmt.Printf("Root function: %s\n", callTree.Root.Function.Name)
-		fmt.Printf("Root has %d direct children\n", len(callTree.Root.Children))
+	if top > 0 {
+		if err := printTop(os.Stdout, a, signatures, noTests, top, targetDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error ranking callers: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	formatOpts := output.Options{
+		ShowParams:     showParams,
+		CollapseChains: collapseChains,
+		Dir:            targetDir,
+		AbsPaths:       absPaths,
+		Editor:         editor,
+		Hyperlinks:     useHyperlinks(hyperlinks),
+		ColorBy:        colorBy,
+		Guides:         guides,
+		ShowDocs:       showDocs,
+		ShowComplexity: showComplexity,
+		JSONFormat:     jsonFormat,
+	}
+
+	build, targets := (*tree.CallTree).Build, signatures
+	if typeName != "" {
+		build, targets = (*tree.CallTree).BuildType, []string{typeName}
+	}
+	// Package mode traces each function of the package, keyed by
+	// function since signatures may be ambiguous across packages
+	var packageFuncs map[string]*analyzer.Function
+	if packageDir != "" {
+		packageFuncs = make(map[string]*analyzer.Function)
+		var funcs []*analyzer.Function
+		for _, fn := range a.GetFunctions() {
+			if fn.InPackage(packageDir) && !fn.IsTest && !strings.HasPrefix(fn.Name, "func(") {
+				funcs = append(funcs, fn)
+			}
+		}
+		sortFunctions(funcs)
+		targets = nil
+		for _, fn := range funcs {
+			packageFuncs[fn.Key()] = fn
+			targets = append(targets, fn.Key())
+		}
+		build = func(ct *tree.CallTree, key string) error {
+			return ct.BuildFunction(packageFuncs[key])
+		}
+		if len(targets) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no functions found in package %s\n", packageDir)
+			return 1
+		}
+	}
+
+	// Tags flow from each tagged function to everything it reaches
+	tags := graph.Tags(a, noTests)
+
+	var callTrees []*tree.CallTree
+	blamer := blame.New(targetDir)
+	for _, target := range targets {
+		callTree := tree.NewCallTree(a, noTests)
+		callTree.MinUsages = minUsages
+		callTree.SortBy = sortBy
+		callTree.MaxDepth = maxDepth
+		callTree.MaxNodes = maxNodes
+		callTree.Focus = focusPkg
+		callTree.Tag = tagFilter
+		callTree.ExportedOnly = exportedOnly
+		callTree.CollapseDeps = collapseDeps
+		callTree.Mocks = mocks
+		callTree.SeeThroughWrappers = seeThroughWrappers
+		callTree.UniqueCallers = uniqueCallers
+		callTree.OnlyTests = onlyTests
+		if noBench || noFuzz || noExamples || noGenerated || minLOC > 0 || packageDir != "" {
+			callTree.Filter = func(fn *analyzer.Function) bool {
+				if noGenerated && fn.IsGenerated {
+					return false
+				}
+				if fn.Lines() < minLOC {
+					return false
+				}
+				// Only callers from outside the package are of interest
+				if packageDir != "" && fn.InPackage(packageDir) {
+					return false
+				}
+				switch fn.TestKind {
+				case analyzer.TestKindBenchmark:
+					return !noBench
+				case analyzer.TestKindFuzz:
+					return !noFuzz
+				case analyzer.TestKindExample:
+					return !noExamples
+				}
+				return true
+			}
+		}
+		if err := build(callTree, target); err != nil {
+			if !batch {
+				fmt.Fprintf(os.Stderr, "Error building call tree: %v\n", err)
+				return 1
+			}
+			if packageDir == "" {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", target, err)
+			}
+			continue
+		}
+		callTree.AnnotateTags(func(fn *analyzer.Function) []string {
+			return tags[fn.Key()]
+		})
+		if ownership != nil {
+			callTree.AnnotateOwners(func(fn *analyzer.Function) []string {
+				return ownership.OwnersOf(filepath.Join(targetDir, fn.FullPath))
+			})
+		}
+		if auditContext {
+			if n := callTree.AuditContext(); n > 0 {
+				fmt.Printf("Context audit: %d findings for %s\n", n, callTree.Root.Function.Signature)
+			}
+		}
+		if auditErrors {
+			if n := callTree.AuditErrors(); n > 0 {
+				fmt.Printf("Error audit: %d callers swallow errors on the way to %s\n", n, callTree.Root.Function.Signature)
+			}
+		}
+		if showLocks {
+			if n := callTree.AnnotateLocks(); n > 0 {
+				fmt.Printf("Lock audit: %d callers hold a mutex while calling toward %s\n", n, callTree.Root.Function.Signature)
+			}
+		}
+		if captureArgs {
+			callTree.CaptureArguments()
+		}
+		if blameCallers {
+			if err := callTree.AnnotateBlame(func(cs *analyzer.CallSite) (*blame.Line, error) {
+				return blamer.Line(cs.Caller.FullPath, cs.Line)
+			}); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: some call sites have no blame: %v\n", err)
+			}
+		}
+		callTrees = append(callTrees, callTree)
+	}
+	if packageDir != "" {
+		fmt.Printf("%d of %d functions of %s have external callers\n", len(callTrees), len(targets), packageDir)
+	}
+	if len(callTrees) == 0 {
+		// A package nothing else calls is an answer, not a failure
+		if packageDir != "" {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, "Error building call tree: no traced function has callers")
+		return 1
+	}
+
+	for _, callTree := range callTrees {
+		slog.Debug("call tree", "root", callTree.Root.Function.Name, "children", len(callTree.Root.Children))
 		for i, child := range callTree.Root.Children {
-			fmt.Printf("  Child %d: %s (has %d children)\n", i+1, child.Function.Name, len(child.Children))
+			slog.Debug("call tree child", "index", i+1, "function", child.Function.Name, "children", len(child.Children))
 		}
 	}
 
 	if jsonOutput != "" {
-		fmt.Printf("Writing JSON output to: %s\n", jsonOutput)
-		formatter := output.NewJSONFormatter(jsonOutput)
-		if err := formatter.Format(callTree); err != nil {
+		slog.Info("writing output", "format", "JSON", "file", jsonOutput)
+		formatter := output.NewJSONFormatter(jsonOutput, formatOpts)
+		if batch {
+			err = formatter.FormatMulti(callTrees)
+		} else {
+			err = formatter.Format(callTrees[0])
+		}
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing JSON output: %v\n", err)
-			os.Exit(1)
+			return 1
+		}
+	}
+
+	if xmlOutput != "" {
+		slog.Info("writing output", "format", "XML", "file", xmlOutput)
+		formatter := output.NewXMLFormatter(xmlOutput, formatOpts)
+		if err := formatter.FormatMulti(callTrees); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing XML output: %v\n", err)
+			return 1
+		}
+	}
+
+	if xlsxOutput != "" {
+		slog.Info("writing output", "format", "XLSX", "file", xlsxOutput)
+		formatter := output.NewXLSXFormatter(xlsxOutput, formatOpts)
+		if err := formatter.FormatMulti(callTrees); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing XLSX output: %v\n", err)
+			return 1
+		}
+	}
+
+	if ticketsDir != "" {
+		formatter := output.NewTicketFormatter(ticketsDir, formatOpts)
+		written, err := formatter.FormatMulti(callTrees)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing tickets: %v\n", err)
+			return 1
+		}
+		slog.Info("wrote migration tickets", "tickets", len(written), "dir", ticketsDir)
+	}
+
+	if treemapOutput != "" {
+		slog.Info("writing output", "format", "treemap", "file", treemapOutput)
+		formatter := output.NewTreemapFormatter(treemapOutput, formatOpts)
+		if err := formatter.FormatMulti(callTrees); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing treemap: %v\n", err)
+			return 1
+		}
+	}
+
+	if perfettoOutput != "" {
+		slog.Info("writing output", "format", "Perfetto", "file", perfettoOutput)
+		formatter := output.NewPerfettoFormatter(perfettoOutput, formatOpts)
+		if err := formatter.FormatMulti(callTrees); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing Perfetto trace: %v\n", err)
+			return 1
+		}
+	}
+
+	for _, flame := range []struct {
+		path   string
+		asSVG  bool
+		format string
+	}{{foldedOutput, false, "folded"}, {flamegraphOutput, true, "flame graph"}} {
+		if flame.path == "" {
+			continue
+		}
+		slog.Info("writing output", "format", flame.format, "file", flame.path)
+		formatter := output.NewFlamegraphFormatter(flame.path, formatOpts, flame.asSVG)
+		if err := formatter.FormatMulti(callTrees); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing flame graph: %v\n", err)
+			return 1
+		}
+	}
+
+	for _, pb := range []struct {
+		path   string
+		asJSON bool
+	}{{pbOutput, false}, {pbJSONOutput, true}} {
+		if pb.path == "" {
+			continue
+		}
+		slog.Info("writing output", "format", "protobuf", "file", pb.path)
+		formatter := output.NewProtobufFormatter(pb.path, formatOpts, pb.asJSON)
+		if err := formatter.FormatMulti(callTrees); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing protobuf output: %v\n", err)
+			return 1
 		}
 	}
 
 	if htmlOutput != "" {
-		fmt.Printf("Writing HTML output to: %s\n", htmlOutput)
-		formatter := output.NewHTMLFormatter(htmlOutput)
-		if err := formatter.Format(callTree); err != nil {
+		slog.Info("writing output", "format", "HTML", "file", htmlOutput)
+		formatter := output.NewHTMLFormatter(htmlOutput, formatOpts)
+		if batch {
+			err = formatter.FormatMulti(callTrees)
+		} else {
+			err = formatter.Format(callTrees[0])
+		}
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing HTML output: %v\n", err)
-			os.Exit(1)
+			return 1
 		}
+		if openHTML {
+			if err := openBrowser(htmlOutput); err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
+			}
+		}
+	} else if openHTML {
+		fmt.Fprintln(os.Stderr, "Warning: -open has no effect without -html")
 	}
 
-	if jsonOutput == "" && htmlOutput == "" {
-		fmt.Println("\n┌─ Reverse Call Graph")
-		fmt.Println("└───────────────────────────────────────────────────")
-		formatter := output.NewConsoleFormatter(os.Stdout, showParams)
-		if err := formatter.Format(callTree); err != nil {
-			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
-			os.Exit(1)
+	if jsonOutput == "" && htmlOutput == "" && xmlOutput == "" && xlsxOutput == "" && ticketsDir == "" && treemapOutput == "" && perfettoOutput == "" && foldedOutput == "" && flamegraphOutput == "" && pbOutput == "" && pbJSONOutput == "" {
+		// The trees are rendered first so they can be paged as a whole
+		var out bytes.Buffer
+		for _, callTree := range callTrees {
+			if batch {
+				fmt.Fprintf(&out, "\n┌─ Reverse Call Graph: %s\n", callTree.Root.Function.Signature)
+			} else {
+				fmt.Fprintln(&out, "\n┌─ Reverse Call Graph")
+			}
+			fmt.Fprintln(&out, "└───────────────────────────────────────────────────")
+			formatter := output.NewConsoleFormatter(&out, formatOpts)
+			if err := formatter.Format(callTree); err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+				return 1
+			}
+		}
+		if noPager {
+			_, err = os.Stdout.Write(out.Bytes())
+		} else {
+			err = page(out.Bytes())
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			return 1
 		}
 	}
 
-	fmt.Println("\nAnalysis complete!")
+	slog.Info("analysis complete")
+	return 0
 }
 
 func printUsage() {
@@ -130,20 +780,175 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  gogotrace -func \"<function signature>\" [options]")
+	fmt.Println("  gogotrace compare -func \"<signature A>\" -func \"<signature B>\" [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace common -func \"<signature>\" -func \"<signature>\" [-func ...] [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace diff-graph -base <rev> [-head <rev>] [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace pkggraph [-format dot|mermaid] [-o file] [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace unreachable [-entry pattern ...] [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace panics [-func \"<signature>\"] [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace query [-dir dir] '<expression>'")
+	fmt.Println("  gogotrace rpc [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace implements -interface <name> [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace rename-preview -func \"<signature>\" -to <name> [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace inline -func \"<signature>\" [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace schema")
+	fmt.Println("  gogotrace metrics [-json] [-min-loc N] [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace unsafe [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace snapshot [-func \"<signature>\" ...] [-entry pattern ...] [-history file] [-dir dir] [-no-test]")
+	fmt.Println("  gogotrace trend [-history file] [-last N] [-dir dir]")
+	fmt.Println("  gogotrace completion bash|zsh|fish")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -func string")
 	fmt.Println("        Function signature to trace (required)")
+	fmt.Println("  -package string")
+	fmt.Println("        Trace the external callers of every function of a package (e.g. ./internal/auth)")
+	fmt.Println("  -type string")
+	fmt.Println("        Trace every method of a type as one tree (e.g. Service or server.Service)")
+	fmt.Println("  -receiver string")
+	fmt.Println("        Only match methods with a pointer or value receiver")
+	fmt.Println("  -func-file string")
+	fmt.Println("        Trace every signature listed in file, one per line (- for stdin)")
 	fmt.Println("  -dir string")
 	fmt.Println("        Directory to analyze (default \".\")")
 	fmt.Println("  -json string")
 	fmt.Println("        Output results to JSON file")
+	fmt.Println("  -json-format string")
+	fmt.Println("        Shape of the JSON output: tree or edges (default \"tree\")")
 	fmt.Println("  -html string")
 	fmt.Println("        Output results to HTML file")
+	fmt.Println("  -xml string")
+	fmt.Println("        Output results to XML file")
+	fmt.Println("  -xlsx string")
+	fmt.Println("        Output the calls and a summary per package and owner to an XLSX spreadsheet")
+	fmt.Println("  -treemap string")
+	fmt.Println("        Output an HTML treemap of the calling packages sized by call sites")
+	fmt.Println("  -perfetto string")
+	fmt.Println("        Output the call trees to a Chrome trace file for the flame view of ui.perfetto.dev")
+	fmt.Println("  -folded string")
+	fmt.Println("        Output the caller paths to a file as folded stacks, the input of flamegraph.pl")
+	fmt.Println("  -flamegraph string")
+	fmt.Println("        Output the caller paths to an SVG flame graph")
+	fmt.Println("  -pb string")
+	fmt.Println("        Output the call graph to a binary protobuf file (Graph message of output/graph.proto)")
+	fmt.Println("  -pbjson string")
+	fmt.Println("        Output the call graph to a file in the protobuf JSON mapping")
+	fmt.Println("  -open")
+	fmt.Println("        Open the HTML report in the browser once written")
 	fmt.Println("  -no-test")
 	fmt.Println("        Exclude test functions from results")
+	fmt.Println("  -no-bench, -no-fuzz, -no-examples")
+	fmt.Println("        Exclude benchmarks, fuzz targets or example functions from results")
+	fmt.Println("  -no-generated")
+	fmt.Println("        Exclude functions from generated files (Code generated ... DO NOT EDIT.)")
+	fmt.Println("  -only-tests")
+	fmt.Println("        Keep only call paths that end in a test, benchmark, fuzz target or example")
+	fmt.Println("  -min-usages int")
+	fmt.Println("        Drop callers with fewer call sites than N")
+	fmt.Println("  -min-loc int")
+	fmt.Println("        Drop callers spanning fewer lines than N, such as trivial getters")
+	fmt.Println("  -max-depth int")
+	fmt.Println("        Stop expanding callers deeper than N levels (default 20)")
+	fmt.Println("  -max-nodes int")
+	fmt.Println("        Emit at most N caller nodes per tree, 0 for no limit")
+	fmt.Println("  -focus string")
+	fmt.Println("        Keep only call paths passing through package (directory or import path)")
+	fmt.Println("  -tag string")
+	fmt.Println("        Keep only call paths passing through a function tagged so by a //gogotrace:tag comment")
+	fmt.Println("  -exported-only")
+	fmt.Println("        Show only exported functions and methods, linked through unexported ones")
+	fmt.Println("  -mocks")
+	fmt.Println("        Include the tests setting expectations on gomock or mockery mocks of the traced method")
+	fmt.Println("  -unique-callers")
+	fmt.Println("        Show each caller once, at its shallowest occurrence, with a count of its other paths")
+	fmt.Println("  -see-through-wrappers")
+	fmt.Println("        Show the callers of wrapper functions in their place")
+	fmt.Println("  -collapse-deps")
+	fmt.Println("        Summarize callers from each third-party package in one node")
+	fmt.Println("  -audit-context")
+	fmt.Println("        Flag calls that drop a context.Context or pass a fresh one mid-chain")
+	fmt.Println("  -audit-errors")
+	fmt.Println("        Show whether each caller propagates, wraps, logs or swallows returned errors")
+	fmt.Println("  -locks")
+	fmt.Println("        Flag callers that make the call while holding a mutex")
+	fmt.Println("  -codeowners string")
+	fmt.Println("        Label callers with their owners from a CODEOWNERS file (auto to search the analyzed directory and its parents)")
+	fmt.Println("  -owners-map string")
+	fmt.Println("        Label callers with the teams of a file mapping path prefixes to owners, when there is no CODEOWNERS")
+	fmt.Println("  -tickets string")
+	fmt.Println("        Write one markdown checklist per owning team of the direct call sites to directory (needs -codeowners or -owners-map)")
+	fmt.Println("  -args")
+	fmt.Println("        Record the argument expressions passed at each call site (JSON and HTML)")
+	fmt.Println("  -blame")
+	fmt.Println("        Attach the last author and commit date of each call site (JSON and HTML)")
 	fmt.Println("  -params")
-	fmt.Println("        Show function parameters in output")
+	fmt.Println("        Show function parameters and results in output")
+	fmt.Println("  -docs")
+	fmt.Println("        Show the first sentence of each function's doc comment in console output")
+	fmt.Println("  -show-complexity")
+	fmt.Println("        Show the cyclomatic complexity of each function (console, HTML and -list)")
+	fmt.Println("  -sort string")
+	fmt.Println("        Order callers by usages, depth, alpha or package (default \"package\")")
+	fmt.Println("  -abs-paths")
+	fmt.Println("        Print absolute file paths with line and column")
+	fmt.Println("  -editor string")
+	fmt.Println("        Link locations to an editor: vscode, goland or vim (console and HTML)")
+	fmt.Println("  -color-by string")
+	fmt.Println("        Color console function names by depth or package")
+	fmt.Println("  -guides")
+	fmt.Println("        Color console tree guides by depth")
+	fmt.Println("  -hyperlinks string")
+	fmt.Println("        Make console locations clickable file links: auto, always or never (default \"auto\")")
+	fmt.Println("  -count")
+	fmt.Println("        Print only the number of direct and transitive callers")
+	fmt.Println("  -count-packages")
+	fmt.Println("        With -count, break the numbers down by package")
+	fmt.Println("  -top int")
+	fmt.Println("        Print only the N callers most central in the call graph (PageRank), the first to migrate")
+	fmt.Println("  -no-pager")
+	fmt.Println("        Do not page console output through $PAGER")
+	fmt.Println("  -collapse-chains")
+	fmt.Println("        Fold single-caller chains into one line (console and HTML)")
+	fmt.Println("  -skip-dirs string")
+	fmt.Println("        Comma-separated directory names not to analyze (default \"vendor,testdata,.git,.work\")")
+	fmt.Println("  -include-dirs string")
+	fmt.Println("        Comma-separated directory names to analyze even though -skip-dirs lists them")
+	fmt.Println("  -include-testdata")
+	fmt.Println("        Analyze testdata directories")
+	fmt.Println("  -all-platforms")
+	fmt.Println("        Unite the call graphs of every GOOS/GOARCH and tag platform-specific callers")
+	fmt.Println("  -host-only")
+	fmt.Println("        Only analyze the files the host GOOS/GOARCH builds, as go build does")
+	fmt.Println("  -edges string")
+	fmt.Println("        Merge the calls declared in a YAML file into the call graph")
+	fmt.Println("  -stitch-grpc")
+	fmt.Println("        Link generated gRPC client methods to the server methods they call")
+	fmt.Println("  -stitch-http")
+	fmt.Println("        Link HTTP requests to the handlers registered for their paths")
+	fmt.Println("  -routes string")
+	fmt.Println("        With -stitch-http, also link requests to the routes of a route file or OpenAPI spec")
+	fmt.Println("  -resolver plugin.so")
+	fmt.Println("        Resolve calls with the CallResolver of a Go plugin (repeatable)")
+	fmt.Println("  -entry-patterns string")
+	fmt.Println("        Label the handlers of the callback registrations described in file as entry points")
+	fmt.Println("  -files glob")
+	fmt.Println("        Only analyze the Go files matching a glob such as 'internal/**/*.go' (repeatable)")
+	fmt.Println("  -bazel")
+	fmt.Println("        Analyze the Go sources of the Bazel workspace's rules, found with bazel query")
+	fmt.Println("  -follow-symlinks")
+	fmt.Println("        Descend into symbolic links to directories")
+	fmt.Println("  -prefilter")
+	fmt.Println("        Skip parsing files that cannot reach the traced function")
+	fmt.Println("  -log-level string")
+	fmt.Println("        Log progress to stderr at this level and above: debug, info (default), warn or error")
+	fmt.Println("  -log-format string")
+	fmt.Println("        Format of the log lines on stderr: text (default) or json")
+	fmt.Println("  -debug")
+	fmt.Println("        Log debug information, as -log-level debug does")
+	fmt.Println("  -cpuprofile, -memprofile, -trace string")
+	fmt.Println("        Write pprof CPU/heap profiles or an execution trace of gogotrace itself")
+	fmt.Println("  -version")
+	fmt.Println("        Print version and build information")
 	fmt.Println("  -help")
 	fmt.Println("        Show this help message")
 	fmt.Println()
@@ -154,3 +959,39 @@ func printUsage() {
 	fmt.Println("  gogotrace -func \"func main()\" -html callgraph.html")
 	fmt.Println("  gogotrace -dir ~/myproject -func \"func Init()\" -no-test")
 }
+
+// oneOf reports whether value is one of values.
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAnalyzer resolves dir and runs both analysis phases on it, reporting
+// errors on stderr the same way the main command does.
+func loadAnalyzer(dir string, opts ...analyzer.Option) (*analyzer.Analyzer, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path: %v\n", err)
+		return nil, false
+	}
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Directory does not exist: %s\n", dir)
+		return nil, false
+	}
+
+	logOpts := setupLogging()
+	slog.Info("analyzing directory", "dir", dir)
+
+	a := analyzer.NewAnalyzer(append(logOpts, opts...)...)
+	if err := a.LoadPackages(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		return nil, false
+	}
+
+	return a, true
+}
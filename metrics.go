@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gogotrace/gogotrace/graph"
+	"github.com/gogotrace/gogotrace/version"
+)
+
+func init() {
+	subcommands["metrics"] = subcommand{
+		summary: "Print fan-in, fan-out, depth, betweenness, LOC and complexity of every function",
+		run:     runMetrics,
+	}
+}
+
+func runMetrics(args []string) int {
+	fs := newFlagSet("metrics")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	asJSON := fs.Bool("json", false, "Print the metrics as JSON")
+	minLOC := fs.Int("min-loc", 0, "Leave out functions spanning fewer lines than N")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace metrics [-json] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	metrics := make([]graph.FunctionMetrics, 0)
+	for _, m := range graph.Metrics(a, *noTests) {
+		if m.LOC >= *minLOC {
+			metrics = append(metrics, m)
+		}
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(struct {
+			Generator version.Info            `json:"generator"`
+			Functions []graph.FunctionMetrics `json:"functions"`
+		}{version.Get(), metrics}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding metrics: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Println("function\tfile\tfan-in\tfan-out\tdepth\tbetweenness\tloc\tstatements\tcomplexity")
+	for _, m := range metrics {
+		name := m.Name
+		if m.Receiver != "" {
+			name = fmt.Sprintf("(%s).%s", m.Receiver, m.Name)
+		}
+		if m.Package != "" && m.Package != "." {
+			name = m.Package + "." + name
+		}
+		fmt.Printf("%s\t%s:%d\t%d\t%d\t%d\t%.6f\t%d\t%d\t%d\n", name, m.File, m.Line, m.FanIn, m.FanOut, m.Depth, m.Betweenness, m.LOC, m.Statements, m.Complexity)
+	}
+	return 0
+}
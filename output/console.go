@@ -2,19 +2,22 @@ package output
 
 import (
 	"fmt"
+	"hash/fnv"
 	"io"
 	"strings"
 
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/owners"
 	"github.com/gogotrace/gogotrace/tree"
 )
 
 type ConsoleFormatter struct {
-	writer     io.Writer
-	showParams bool
+	writer io.Writer
+	opts   Options
 }
 
-func NewConsoleFormatter(w io.Writer, showParams bool) *ConsoleFormatter {
-	return &ConsoleFormatter{writer: w, showParams: showParams}
+func NewConsoleFormatter(w io.Writer, opts Options) *ConsoleFormatter {
+	return &ConsoleFormatter{writer: w, opts: opts}
 }
 
 func (cf *ConsoleFormatter) Format(callTree *tree.CallTree) error {
@@ -22,54 +25,237 @@ func (cf *ConsoleFormatter) Format(callTree *tree.CallTree) error {
 		return fmt.Errorf("call tree is empty")
 	}
 	
-	for i, child := range callTree.Root.Children {
-		isLast := i == len(callTree.Root.Children)-1
-		cf.printNode(child, "", isLast)
+	cf.printChildren(callTree.Root, "", 1)
+	
+	if summary := callTree.CallersByOwner(owners.Unowned); len(summary) > 0 {
+		fmt.Fprintln(cf.writer, "\nCallers by owner:")
+		fmt.Fprintf(cf.writer, "  %-30s %7s %7s\n", "Owner", "Callers", "Direct")
+		for _, oc := range summary {
+			fmt.Fprintf(cf.writer, "  %-30s %7d %7d\n", oc.Owner, oc.Callers, oc.Direct)
+		}
 	}
 	
+	cf.printSummary(callTree.Summary())
+	
 	return nil
 }
 
-func (cf *ConsoleFormatter) printNode(node *tree.CallNode, prefix string, isLast bool) {
+// printSummary prints the footer with the tree's aggregate counts.
+func (cf *ConsoleFormatter) printSummary(s tree.Summary) {
+	fmt.Fprintln(cf.writer, "\nSummary:")
+	fmt.Fprintf(cf.writer, "  %-12s %d (%d non-test, %d test)\n", "Callers", s.Callers, s.Callers-s.TestCallers, s.TestCallers)
+	fmt.Fprintf(cf.writer, "  %-12s %d\n", "Packages", s.Packages)
+	fmt.Fprintf(cf.writer, "  %-12s %d\n", "Max depth", s.MaxDepth)
+	fmt.Fprintf(cf.writer, "  %-12s %d\n", "Nodes", s.Nodes)
+	fmt.Fprintf(cf.writer, "  %-12s %d\n", "Truncated", s.Omitted)
+	if s.Suppressed > 0 {
+		fmt.Fprintf(cf.writer, "  %-12s %d call sites (//gogotrace:ignore)\n", "Suppressed", s.Suppressed)
+	}
+}
+
+// printNode prints node, depth levels below the root, and its callers.
+func (cf *ConsoleFormatter) printNode(node *tree.CallNode, prefix string, isLast bool, depth int) {
 	connector := "├── "
 	if isLast {
 		connector = "└── "
 	}
 	
-	line := cf.formatNodeLine(node)
-	fmt.Fprintf(cf.writer, "%s%s%s\n", prefix, connector, line)
+	line := cf.formatNodeLine(node, depth)
+	if cf.opts.CollapseChains {
+		if chain := collapseChain(node); len(chain) > 1 {
+			line = cf.formatChainLine(chain, depth)
+			node = chain[len(chain)-1]
+			depth += len(chain) - 1
+		}
+	}
+	fmt.Fprintf(cf.writer, "%s%s%s\n", prefix, cf.guide(connector, depth), line)
 	
 	childPrefix := prefix
 	if isLast {
 		childPrefix += "    "
 	} else {
-		childPrefix += "│   "
+		childPrefix += cf.guide("│   ", depth)
 	}
 	
+	cf.printChildren(node, childPrefix, depth+1)
+}
+
+// printChildren prints the callers of node, followed by a marker line when
+// some of them were cut by a budget.
+func (cf *ConsoleFormatter) printChildren(node *tree.CallNode, prefix string, depth int) {
 	for i, child := range node.Children {
-		isLastChild := i == len(node.Children)-1
-		cf.printNode(child, childPrefix, isLastChild)
+		isLast := i == len(node.Children)-1 && node.Omitted == 0
+		cf.printNode(child, prefix, isLast, depth)
+	}
+	
+	if marker := omittedMarker(node); marker != "" {
+		fmt.Fprintf(cf.writer, "%s%s\033[90m%s\033[0m\n", prefix, cf.guide("└── ", depth), marker)
+	}
+}
+
+// depthColors is the palette cycled through by ColorBy and Guides.
+var depthColors = []string{"33", "32", "36", "35", "34", "31"}
+
+// guide returns a tree guide segment, colored by depth with Guides so the
+// columns of deep trees can be followed by eye.
+func (cf *ConsoleFormatter) guide(segment string, depth int) string {
+	if !cf.opts.Guides {
+		return segment
+	}
+	return fmt.Sprintf("\033[%sm%s\033[0m", depthColors[depth%len(depthColors)], segment)
+}
+
+// nameColor returns the color of node's name: by depth or by package with
+// ColorBy, bold yellow otherwise.
+func (cf *ConsoleFormatter) nameColor(node *tree.CallNode, depth int) string {
+	switch cf.opts.ColorBy {
+	case "depth":
+		return "1;" + depthColors[depth%len(depthColors)]
+	case "package":
+		h := fnv.New32a()
+		h.Write([]byte(node.Function.Package))
+		return "1;" + depthColors[h.Sum32()%uint32(len(depthColors))]
 	}
+	return "1;33"
+}
+
+func (cf *ConsoleFormatter) formatNodeLine(node *tree.CallNode, depth int) string {
+	var sb strings.Builder
+	
+	cf.writeNodeName(&sb, node, depth)
+	
+	sb.WriteString(fmt.Sprintf(" \033[90m→\033[0m \033[34m%s\033[0m", cf.linkedLocation(node.Function)))
+	cf.writeOwners(&sb, node)
+	cf.writeTags(&sb, node)
+	cf.writeAnnotations(&sb, node)
+	cf.writeDoc(&sb, node)
+	
+	return sb.String()
 }
 
-func (cf *ConsoleFormatter) formatNodeLine(node *tree.CallNode) string {
+// formatChainLine renders a folded chain outermost caller first, e.g.
+// "A (a.go) → B (b.go) → C (c.go)". chain[0] is depth levels below the root.
+func (cf *ConsoleFormatter) formatChainLine(chain []*tree.CallNode, depth int) string {
 	var sb strings.Builder
 	
-	if node.Function.Receiver != "" {
-		sb.WriteString(fmt.Sprintf("\033[1;36m%s\033[0m.\033[1;33m%s\033[0m", node.Function.Receiver, node.Function.Name))
+	for i := len(chain) - 1; i >= 0; i-- {
+		cf.writeNodeName(&sb, chain[i], depth+i)
+		sb.WriteString(fmt.Sprintf(" \033[34m(%s)\033[0m", cf.linkedLocation(chain[i].Function)))
+		cf.writeOwners(&sb, chain[i])
+		cf.writeTags(&sb, chain[i])
+		cf.writeAnnotations(&sb, chain[i])
+		cf.writeDoc(&sb, chain[i])
+		if i > 0 {
+			sb.WriteString(" \033[90m→\033[0m ")
+		}
+	}
+	
+	return sb.String()
+}
+
+// linkedLocation returns the location of fn, wrapped in an OSC 8 terminal
+// hyperlink when there is something to link to.
+func (cf *ConsoleFormatter) linkedLocation(fn *analyzer.Function) string {
+	text := cf.opts.location(fn)
+	if uri := cf.opts.consoleURI(fn); uri != "" {
+		return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", uri, text)
+	}
+	return text
+}
+
+// writeNodeName writes the colored function name with its optional
+// parameters and usage count.
+func (cf *ConsoleFormatter) writeNodeName(sb *strings.Builder, node *tree.CallNode, depth int) {
+	color := cf.nameColor(node, depth)
+	if summary := node.DependencySummary(); summary != "" {
+		sb.WriteString(fmt.Sprintf("\033[%sm%s\033[0m", color, summary))
+	} else if node.Function.Receiver != "" {
+		sb.WriteString(fmt.Sprintf("\033[1;36m(%s)\033[0m.\033[%sm%s\033[0m", node.Function.Receiver, color, node.Function.Name))
 	} else {
-		sb.WriteString(fmt.Sprintf("\033[1;33m%s\033[0m", node.Function.Name))
+		sb.WriteString(fmt.Sprintf("\033[%sm%s\033[0m", color, node.Function.Name))
 	}
 	
-	if cf.showParams && node.Function.Parameters != "" {
+	if cf.opts.ShowParams && node.Function.Parameters != "" {
 		sb.WriteString(fmt.Sprintf("\033[35m%s\033[0m", node.Function.Parameters))
+		if node.Function.Results != "" {
+			sb.WriteString(fmt.Sprintf(" \033[35m%s\033[0m", node.Function.Results))
+		}
 	}
 	
 	if node.Usages > 1 {
 		sb.WriteString(fmt.Sprintf(" \033[90m(%d usages)\033[0m", node.Usages))
 	}
 	
-	sb.WriteString(fmt.Sprintf(" \033[90m→\033[0m \033[34m%s\033[0m", node.Function.File))
+	if node.Alternates > 0 {
+		sb.WriteString(fmt.Sprintf(" \033[90m(+%d other paths)\033[0m", node.Alternates))
+	}
 	
-	return sb.String()
+	if node.Function.IsGenerated {
+		sb.WriteString(" \033[90m[generated]\033[0m")
+	}
+	
+	if node.Function.IsWrapper {
+		sb.WriteString(" \033[90m[wrapper]\033[0m")
+	}
+	
+	if cf.opts.ShowComplexity {
+		sb.WriteString(fmt.Sprintf(" \033[33m[complexity %d]\033[0m", node.Function.Complexity))
+	}
+	
+	if through := node.ThroughLabel(); through != "" {
+		sb.WriteString(fmt.Sprintf(" \033[90m[%s]\033[0m", through))
+	}
+	
+	if node.Dispatch != "" {
+		sb.WriteString(fmt.Sprintf(" \033[35m[%s]\033[0m", node.Dispatch))
+	}
+	
+	for _, via := range node.Via() {
+		if via == "" {
+			via = analyzer.CallSynthetic
+		} else {
+			via = "via " + via
+		}
+		sb.WriteString(fmt.Sprintf(" \033[35m[%s]\033[0m", via))
+	}
+	
+	if node.Function.Constraint != "" {
+		sb.WriteString(fmt.Sprintf(" \033[36m[%s]\033[0m", node.Function.Constraint))
+	}
+	
+	if node.CrossModule() {
+		sb.WriteString(fmt.Sprintf(" \033[1;33m[module %s]\033[0m", node.Function.Module))
+	}
+	
+	for _, entry := range node.EntryPoints {
+		sb.WriteString(fmt.Sprintf(" \033[1;32m[%s]\033[0m", entry))
+	}
+}
+
+// writeOwners writes the CODEOWNERS owners of node, if any.
+func (cf *ConsoleFormatter) writeOwners(sb *strings.Builder, node *tree.CallNode) {
+	if len(node.Owners) > 0 {
+		sb.WriteString(fmt.Sprintf(" \033[32m[%s]\033[0m", strings.Join(node.Owners, " ")))
+	}
+}
+
+// writeTags writes the //gogotrace:tag tags of node as "#tag", if any.
+func (cf *ConsoleFormatter) writeTags(sb *strings.Builder, node *tree.CallNode) {
+	if len(node.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf(" \033[35m#%s\033[0m", strings.Join(node.Tags, " #")))
+	}
+}
+
+// writeAnnotations writes the audit findings attached to node.
+func (cf *ConsoleFormatter) writeAnnotations(sb *strings.Builder, node *tree.CallNode) {
+	for _, note := range node.Annotations {
+		sb.WriteString(fmt.Sprintf(" \033[1;31m[%s]\033[0m", note))
+	}
+}
+
+// writeDoc writes the doc comment synopsis of node with ShowDocs.
+func (cf *ConsoleFormatter) writeDoc(sb *strings.Builder, node *tree.CallNode) {
+	if cf.opts.ShowDocs && node.Function.Doc != "" {
+		sb.WriteString(fmt.Sprintf(" \033[2m// %s\033[0m", node.Function.Doc))
+	}
 }
\ No newline at end of file
@@ -0,0 +1,172 @@
+package output
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/tree"
+)
+
+// FlamegraphFormatter writes the caller paths of the trees as folded
+// stacks, the input of flamegraph.pl and speedscope, or renders them
+// directly as an SVG flame graph. Each path from a traced function to the
+// end of a branch is a stack, the traced function at its base, and counts
+// the call sites of its last caller, so the widest frames are where the
+// usage concentrates.
+type FlamegraphFormatter struct {
+	outputFile string
+	opts       Options
+	asSVG      bool
+}
+
+// NewFlamegraphFormatter returns a formatter writing folded stacks, or an
+// SVG flame graph when asSVG is set, to outputFile.
+func NewFlamegraphFormatter(outputFile string, opts Options, asSVG bool) *FlamegraphFormatter {
+	return &FlamegraphFormatter{outputFile: outputFile, opts: opts, asSVG: asSVG}
+}
+
+// foldedStack is a caller path, base frame first, and its weight.
+type foldedStack struct {
+	frames []string
+	count  int
+}
+
+func (ff *FlamegraphFormatter) Format(callTree *tree.CallTree) error {
+	return ff.FormatMulti([]*tree.CallTree{callTree})
+}
+
+// FormatMulti writes the stacks of every call tree to one file.
+func (ff *FlamegraphFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	var stacks []foldedStack
+	for _, callTree := range callTrees {
+		if callTree.Root != nil {
+			stacks = foldStacks(stacks, callTree.Root, nil)
+		}
+	}
+
+	var data string
+	if ff.asSVG {
+		data = renderFlamegraph(stacks)
+	} else {
+		var sb strings.Builder
+		for _, stack := range stacks {
+			fmt.Fprintf(&sb, "%s %d\n", strings.Join(stack.frames, ";"), stack.count)
+		}
+		data = sb.String()
+	}
+	return os.WriteFile(ff.outputFile, []byte(data), 0644)
+}
+
+// foldStacks appends the stacks of the paths from node to the end of its
+// branches, below the frames of path.
+func foldStacks(stacks []foldedStack, node *tree.CallNode, path []string) []foldedStack {
+	// Semicolons separate frames and the last space the count
+	frame := strings.NewReplacer(";", ":", " ", "").Replace(qualifiedName(node.Function))
+	path = append(path[:len(path):len(path)], frame)
+	if len(node.Children) == 0 {
+		count := len(node.CallSites)
+		if count == 0 {
+			count = 1
+		}
+		return append(stacks, foldedStack{frames: path, count: count})
+	}
+	for _, child := range node.Children {
+		stacks = foldStacks(stacks, child, path)
+	}
+	return stacks
+}
+
+// flameFrame is a frame of the flame graph, the stacks sharing a prefix.
+type flameFrame struct {
+	name     string
+	count    int
+	children []*flameFrame
+}
+
+const (
+	flameWidth       = 1200
+	flameFrameHeight = 16
+	flameMargin      = 10
+	flameTitleHeight = 24
+)
+
+// renderFlamegraph draws stacks as an SVG flame graph in the manner of
+// flamegraph.pl: base frames at the bottom, each frame as wide as the
+// counts of the stacks going through it, and the full name and count in a
+// tooltip.
+func renderFlamegraph(stacks []foldedStack) string {
+	root := &flameFrame{name: "all"}
+	depth := 0
+	for _, stack := range stacks {
+		root.count += stack.count
+		frame := root
+		for _, name := range stack.frames {
+			var next *flameFrame
+			for _, child := range frame.children {
+				if child.name == name {
+					next = child
+					break
+				}
+			}
+			if next == nil {
+				next = &flameFrame{name: name}
+				frame.children = append(frame.children, next)
+			}
+			next.count += stack.count
+			frame = next
+		}
+		if len(stack.frames) > depth {
+			depth = len(stack.frames)
+		}
+	}
+
+	height := flameTitleHeight + (depth+1)*flameFrameHeight + 2*flameMargin
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<?xml version="1.0" standalone="no"?>`+"\n")
+	fmt.Fprintf(&sb, `<svg version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`+"\n", flameWidth, height, flameWidth, height)
+	fmt.Fprintf(&sb, `<style>text { font-family: Verdana, sans-serif; font-size: 12px; fill: #000; } rect:hover { stroke: #000; stroke-width: 0.5; }</style>`+"\n")
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="#f8f8f8"/>`+"\n")
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="middle" style="font-size: 16px">Callers</text>`+"\n", flameWidth/2, flameTitleHeight-6)
+	if root.count > 0 {
+		scale := float64(flameWidth-2*flameMargin) / float64(root.count)
+		drawFlameFrame(&sb, root, float64(flameMargin), height-flameMargin-flameFrameHeight, scale)
+	}
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+// drawFlameFrame draws frame at x on row y, then its children above it.
+func drawFlameFrame(sb *strings.Builder, frame *flameFrame, x float64, y int, scale float64) {
+	width := float64(frame.count) * scale
+	sites := "call sites"
+	if frame.count == 1 {
+		sites = "call site"
+	}
+	fmt.Fprintf(sb, `<g><title>%s (%d %s)</title>`, xmlEscape(frame.name), frame.count, sites)
+	fmt.Fprintf(sb, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2" ry="2"/>`, x, y, width, flameFrameHeight-1, flameColor(frame.name))
+	// Names are cut to what fits, about 7px per character
+	if chars := int(width-6) / 7; chars >= 3 {
+		label := frame.name
+		if len(label) > chars {
+			label = label[:chars-2] + ".."
+		}
+		fmt.Fprintf(sb, `<text x="%.1f" y="%d">%s</text>`, x+3, y+flameFrameHeight-4, xmlEscape(label))
+	}
+	sb.WriteString("</g>\n")
+
+	for _, child := range frame.children {
+		drawFlameFrame(sb, child, x, y-flameFrameHeight, scale)
+		x += float64(child.count) * scale
+	}
+}
+
+// flameColor picks a warm color from name, so that a function keeps its
+// color everywhere in the graph.
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, (v>>8)%230, (v>>16)%55)
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"html/template"
 	"os"
+	"strings"
 
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/owners"
 	"github.com/gogotrace/gogotrace/tree"
+	"github.com/gogotrace/gogotrace/version"
 )
 
 const htmlTemplate = `<!DOCTYPE html>
@@ -75,10 +79,97 @@ const htmlTemplate = `<!DOCTYPE html>
         .file {
             color: #008000;
         }
+        .editor-link {
+            text-decoration: none;
+        }
+        .editor-link:hover .file {
+            text-decoration: underline;
+        }
         .usages {
             color: #ff6b6b;
             font-weight: bold;
         }
+        .chain-arrow {
+            color: #999;
+            margin: 0 4px;
+        }
+        .omitted {
+            color: #999;
+            font-style: italic;
+        }
+        .owner {
+            color: #2e7d32;
+            font-size: 0.85em;
+            margin-left: 5px;
+        }
+        .owner-table {
+            border-collapse: collapse;
+            margin: 5px 0;
+        }
+        .owner-table th, .owner-table td {
+            text-align: left;
+            padding: 2px 12px 2px 0;
+        }
+        .owner-table .owner {
+            margin-left: 0;
+        }
+        .tag {
+            color: #8e24aa;
+            font-size: 0.85em;
+            margin-left: 5px;
+        }
+        .blame {
+            color: #888;
+            font-size: 0.8em;
+            margin-left: 8px;
+        }
+        .params {
+            color: #8e24aa;
+        }
+        .dispatch {
+            color: #8e24aa;
+            font-size: 0.85em;
+            margin-left: 8px;
+        }
+        .constraint {
+            color: #00838f;
+            font-size: 0.85em;
+            margin-left: 8px;
+        }
+        .entry-point {
+            color: #2e7d32;
+            font-weight: bold;
+            font-size: 0.85em;
+            margin-left: 8px;
+        }
+        .dependency {
+            color: #757575;
+            font-style: italic;
+        }
+        .cross-module {
+            color: #ef6c00;
+            font-weight: bold;
+            font-size: 0.85em;
+            margin-left: 8px;
+        }
+        .doc {
+            color: #666;
+            font-size: 0.85em;
+            margin-left: 8px;
+        }
+        .args {
+            color: #795548;
+            font-size: 0.85em;
+            margin-left: 8px;
+        }
+        .annotation {
+            color: #c62828;
+            font-size: 0.85em;
+            margin-left: 8px;
+        }
+        .generated-indicator {
+            background-color: #d0d7de;
+        }
         .test-indicator {
             background-color: #ffd93d;
             padding: 2px 6px;
@@ -104,6 +195,10 @@ const htmlTemplate = `<!DOCTYPE html>
         .node-wrapper.test-hidden {
             display: none;
         }
+        .root-node {
+            border-bottom: 1px solid #ddd;
+            margin-top: 15px;
+        }
         .footer {
             margin-top: 40px;
             padding-top: 20px;
@@ -118,13 +213,21 @@ const htmlTemplate = `<!DOCTYPE html>
         .footer a:hover {
             text-decoration: underline;
         }
+        .build-info {
+            margin-top: 8px;
+            font-size: 0.8em;
+        }
     </style>
 </head>
 <body>
     <h1>GoGoTrace - Reverse Call Graph</h1>
     <div class="info">
-        <strong>Target Function:</strong> <span class="function-name">{{.TargetSignature}}</span><br>
+        {{if .Targets}}<strong>Target Functions:</strong>
+        <ul>{{range .Targets}}<li class="function-name">{{.}}</li>{{end}}</ul>
+        {{else}}<strong>Target Function:</strong> <span class="function-name">{{.TargetSignature}}</span><br>{{end}}
         <strong>Total Callers:</strong> {{.TotalCallers}}
+        {{if .CallersByOwner}}<br><strong>Callers by Owner:</strong>
+        <table class="owner-table"><tr><th>Owner</th><th>Callers</th><th>Direct</th></tr>{{range .CallersByOwner}}<tr><td><span class="owner">{{.Owner}}</span></td><td>{{.Callers}}</td><td>{{.Direct}}</td></tr>{{end}}</table>{{end}}
     </div>
     <div class="controls">
         <button onclick="expandAll()">Expand All</button>
@@ -136,6 +239,7 @@ const htmlTemplate = `<!DOCTYPE html>
     </div>
     <div class="footer">
         <a href="https://github.com/kevin-valerio/gogotrace" target="_blank">GoGoTrace on GitHub - Kevin VALERIO</a>
+        <div class="build-info">Generated by {{.Generator}}</div>
     </div>
     <script>
         function toggleNode(element) {
@@ -190,16 +294,20 @@ const htmlTemplate = `<!DOCTYPE html>
 
 type HTMLFormatter struct {
 	outputFile string
+	opts       Options
 }
 
 type HTMLData struct {
 	TargetSignature string
+	Targets         []string
 	TotalCallers    int
 	TreeHTML        template.HTML
+	Generator       string
+	CallersByOwner  []tree.OwnerCount
 }
 
-func NewHTMLFormatter(outputFile string) *HTMLFormatter {
-	return &HTMLFormatter{outputFile: outputFile}
+func NewHTMLFormatter(outputFile string, opts Options) *HTMLFormatter {
+	return &HTMLFormatter{outputFile: outputFile, opts: opts}
 }
 
 func (hf *HTMLFormatter) Format(callTree *tree.CallTree) error {
@@ -207,14 +315,48 @@ func (hf *HTMLFormatter) Format(callTree *tree.CallTree) error {
 		return nil
 	}
 
-	treeHTML := hf.buildTreeHTML(callTree.Root.Children, callTree)
+	treeHTML := hf.buildTreeHTML(callTree.Root.Children, callTree) + hf.buildOmittedHTML(callTree.Root)
 
 	data := HTMLData{
 		TargetSignature: callTree.Root.Function.Signature,
 		TotalCallers:    hf.countTotalCallers(callTree.Root),
 		TreeHTML:        template.HTML(treeHTML),
+		Generator:       version.Get().String(),
+		CallersByOwner:  callTree.CallersByOwner(owners.Unowned),
+	}
+
+	return hf.write(data)
+}
+
+// FormatMulti writes a combined page with one expandable section per
+// traced function.
+func (hf *HTMLFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	data := HTMLData{
+		Generator: version.Get().String(),
 	}
 
+	treeHTML := ""
+	for _, callTree := range callTrees {
+		if callTree.Root == nil {
+			continue
+		}
+		data.Targets = append(data.Targets, callTree.Root.Function.Signature)
+		data.TotalCallers += hf.countTotalCallers(callTree.Root)
+
+		treeHTML += `<div class="node-wrapper">`
+		treeHTML += fmt.Sprintf(`<div class="node expandable expanded root-node" onclick="toggleNode(this)"><span class="function-name">%s</span></div>`,
+			template.HTMLEscapeString(callTree.Root.Function.Signature))
+		treeHTML += `<div class="children show">`
+		treeHTML += hf.buildTreeHTML(callTree.Root.Children, callTree)
+		treeHTML += hf.buildOmittedHTML(callTree.Root)
+		treeHTML += `</div></div>`
+	}
+	data.TreeHTML = template.HTML(treeHTML)
+
+	return hf.write(data)
+}
+
+func (hf *HTMLFormatter) write(data HTMLData) error {
 	tmpl, err := template.New("callgraph").Parse(htmlTemplate)
 	if err != nil {
 		return err
@@ -242,7 +384,12 @@ func (hf *HTMLFormatter) buildTreeHTML(nodes []*tree.CallNode, ct *tree.CallTree
 }
 
 func (hf *HTMLFormatter) buildNodeHTML(node *tree.CallNode, ct *tree.CallTree) string {
-	hasChildren := len(node.Children) > 0
+	chain := []*tree.CallNode{node}
+	if hf.opts.CollapseChains {
+		chain = collapseChain(node)
+		node = chain[len(chain)-1]
+	}
+	hasChildren := len(node.Children) > 0 || node.Omitted > 0
 
 	html := `<div class="node-wrapper">`
 
@@ -253,31 +400,164 @@ func (hf *HTMLFormatter) buildNodeHTML(node *tree.CallNode, ct *tree.CallTree) s
 
 	html += fmt.Sprintf(`<div class="%s" onclick="toggleNode(this)">`, nodeClass)
 
-	if node.Function.Receiver != "" {
-		html += fmt.Sprintf(`<span class="receiver">%s.</span>`, node.Function.Receiver)
+	// Folded chains read outermost caller first: A → B → C.
+	for i := len(chain) - 1; i >= 0; i-- {
+		html += hf.buildNodeLabelHTML(chain[i])
+		if i > 0 {
+			html += ` <span class="chain-arrow">→</span> `
+		}
+	}
+
+	html += `</div>`
+
+	if hasChildren {
+		html += `<div class="children">`
+		html += hf.buildTreeHTML(node.Children, ct)
+		html += hf.buildOmittedHTML(node)
+		html += `</div>`
+	}
+
+	html += `</div>`
+
+	return html
+}
+
+func (hf *HTMLFormatter) buildOmittedHTML(node *tree.CallNode) string {
+	marker := omittedMarker(node)
+	if marker == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<div class="node omitted">%s</div>`, template.HTMLEscapeString(marker))
+}
+
+func (hf *HTMLFormatter) buildNodeLabelHTML(node *tree.CallNode) string {
+	html := ""
+	if summary := node.DependencySummary(); summary != "" {
+		html += fmt.Sprintf(`<span class="function-name dependency">%s</span>`, template.HTMLEscapeString(summary))
+	} else {
+		if node.Function.Receiver != "" {
+			html += fmt.Sprintf(`<span class="receiver">(%s).</span>`, node.Function.Receiver)
+		}
+		html += fmt.Sprintf(`<span class="function-name">%s</span>`, node.Function.Name)
+	}
+	if hf.opts.ShowParams && node.Function.Parameters != "" {
+		html += fmt.Sprintf(`<span class="params">%s</span>`, template.HTMLEscapeString(node.Function.Parameters))
+		if node.Function.Results != "" {
+			html += fmt.Sprintf(` <span class="params">%s</span>`, template.HTMLEscapeString(node.Function.Results))
+		}
 	}
-	html += fmt.Sprintf(`<span class="function-name">%s</span>`, node.Function.Name)
 
 	if node.Usages > 1 {
 		html += fmt.Sprintf(` <span class="usages">(%d usages)</span>`, node.Usages)
 	}
 
-	html += fmt.Sprintf(` in <span class="package">%s</span>/<span class="file">%s</span>`,
+	if node.Alternates > 0 {
+		html += fmt.Sprintf(` <span class="usages">(+%d other paths)</span>`, node.Alternates)
+	}
+
+	location := fmt.Sprintf(`<span class="package">%s</span>/<span class="file">%s</span>`,
 		node.Function.Package, node.Function.File)
+	if hf.opts.AbsPaths {
+		location = fmt.Sprintf(`<span class="file">%s</span>`, template.HTMLEscapeString(hf.opts.location(node.Function)))
+	}
+	if uri := hf.opts.editorURI(node.Function); uri != "" {
+		location = fmt.Sprintf(`<a class="editor-link" href="%s">%s</a>`, template.HTMLEscapeString(uri), location)
+	}
+	html += " in " + location
+
+	for _, owner := range node.Owners {
+		html += fmt.Sprintf(`<span class="owner">%s</span>`, template.HTMLEscapeString(owner))
+	}
+	for _, tag := range node.Tags {
+		html += fmt.Sprintf(`<span class="tag">#%s</span>`, template.HTMLEscapeString(tag))
+	}
 
 	if node.Function.IsTest {
 		html += `<span class="test-indicator">TEST</span>`
 	}
 
-	html += `</div>`
+	if node.Function.IsGenerated {
+		html += `<span class="test-indicator generated-indicator">GENERATED</span>`
+	}
 
-	if hasChildren {
-		html += `<div class="children">`
-		html += hf.buildTreeHTML(node.Children, ct)
-		html += `</div>`
+	if node.Function.IsWrapper {
+		html += `<span class="dispatch">wrapper</span>`
 	}
 
-	html += `</div>`
+	if hf.opts.ShowComplexity {
+		html += fmt.Sprintf(`<span class="constraint">complexity %d</span>`, node.Function.Complexity)
+	}
+
+	if through := node.ThroughLabel(); through != "" {
+		html += fmt.Sprintf(`<span class="dispatch">%s</span>`, template.HTMLEscapeString(through))
+	}
+
+	if node.Dispatch != "" {
+		html += fmt.Sprintf(`<span class="dispatch">%s</span>`, node.Dispatch)
+	}
+
+	for _, via := range node.Via() {
+		if via == "" {
+			via = analyzer.CallSynthetic
+		} else {
+			via = "via " + via
+		}
+		html += fmt.Sprintf(`<span class="dispatch">%s</span>`, template.HTMLEscapeString(via))
+	}
+
+	if node.Function.Constraint != "" {
+		html += fmt.Sprintf(`<span class="constraint">%s</span>`, template.HTMLEscapeString(node.Function.Constraint))
+	}
+
+	if node.CrossModule() {
+		html += fmt.Sprintf(`<span class="cross-module">module %s</span>`, template.HTMLEscapeString(node.Function.Module))
+	}
+
+	for _, entry := range node.EntryPoints {
+		html += fmt.Sprintf(`<span class="entry-point">%s</span>`, template.HTMLEscapeString(entry.String()))
+	}
+
+	seenArgs := make(map[string]bool)
+	for _, cs := range node.CallSites {
+		if cs.Args == nil {
+			continue
+		}
+		// Arguments resolved through a name show their value, and the
+		// title tells constants from variables
+		args := make([]string, len(cs.Args))
+		described := make([]string, len(cs.Args))
+		for i, arg := range cs.Args {
+			args[i] = arg
+			if value := cs.Constants[i]; value != "" && value != arg {
+				args[i] = arg + " = " + value
+			}
+			described[i] = analyzer.DescribeArgument(arg, cs.Constants[i])
+		}
+		call := fmt.Sprintf("%s(%s)", cs.Callee.Name, strings.Join(args, ", "))
+		if !seenArgs[call] {
+			seenArgs[call] = true
+			title := cs.Callee.Name + " called without arguments"
+			if len(described) > 0 {
+				title = cs.Callee.Name + " called with " + strings.Join(described, ", ")
+			}
+			html += fmt.Sprintf(`<span class="args" title="%s">%s</span>`, template.HTMLEscapeString(title), template.HTMLEscapeString(call))
+		}
+	}
+
+	for _, note := range node.Annotations {
+		html += fmt.Sprintf(`<span class="annotation">[%s]</span>`, template.HTMLEscapeString(note))
+	}
+
+	if node.Blame != nil {
+		html += fmt.Sprintf(`<span class="blame" title="%s">%s, %s</span>`,
+			template.HTMLEscapeString(node.Blame.Commit),
+			template.HTMLEscapeString(node.Blame.Author),
+			node.Blame.Date.Format("2006-01-02"))
+	}
+
+	if node.Function.Doc != "" {
+		html += fmt.Sprintf(`<span class="doc">— %s</span>`, template.HTMLEscapeString(node.Function.Doc))
+	}
 
 	return html
 }
@@ -1,30 +1,109 @@
 package output
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"os"
 
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/blame"
+	"github.com/gogotrace/gogotrace/owners"
 	"github.com/gogotrace/gogotrace/tree"
+	"github.com/gogotrace/gogotrace/version"
 )
 
 type JSONNode struct {
-	Name      string      `json:"name"`
-	Receiver  string      `json:"receiver,omitempty"`
-	Package   string      `json:"package"`
-	File      string      `json:"file"`
-	Line      int         `json:"line"`
-	Signature string      `json:"signature"`
-	Usages    int         `json:"usages,omitempty"`
-	IsTest    bool        `json:"isTest,omitempty"`
-	Children  []*JSONNode `json:"children,omitempty"`
+	Generator      *version.Info     `json:"generator,omitempty"`
+	ID             string            `json:"id"`
+	Parent         string            `json:"parent,omitempty"`
+	Name           string            `json:"name"`
+	Receiver       string            `json:"receiver,omitempty"`
+	PointerRecv    bool              `json:"pointerReceiver,omitempty"`
+	Package        string            `json:"package"`
+	File           string            `json:"file"`
+	Path           string            `json:"path"`
+	Line           int               `json:"line"`
+	Column         int               `json:"column"`
+	EndLine        int               `json:"endLine"`
+	EndColumn      int               `json:"endColumn"`
+	Signature      string            `json:"signature"`
+	Doc            string            `json:"doc,omitempty"`
+	Usages         int               `json:"usages,omitempty"`
+	Alternates     int               `json:"alternatePaths,omitempty"`
+	Calls          []JSONCall        `json:"calls,omitempty"`
+	IsTest         bool              `json:"isTest,omitempty"`
+	TestKind       string            `json:"testKind,omitempty"`
+	IsGenerated    bool              `json:"isGenerated,omitempty"`
+	IsWrapper      bool              `json:"isWrapper,omitempty"`
+	Complexity     int               `json:"complexity,omitempty"`
+	Statements     int               `json:"statements,omitempty"`
+	Through        []string          `json:"through,omitempty"`
+	Dispatch       string            `json:"dispatch,omitempty"`
+	Constraint     string            `json:"constraint,omitempty"`
+	Module         string            `json:"module,omitempty"`
+	CrossModule    bool              `json:"crossModule,omitempty"`
+	Dependency     string            `json:"dependency,omitempty"`
+	Collapsed      int               `json:"collapsed,omitempty"`
+	EntryPoints    []string          `json:"entryPoints,omitempty"`
+	Children       []*JSONNode       `json:"children,omitempty"`
+	Omitted        int               `json:"omitted,omitempty"`
+	OmittedBy      string            `json:"omittedBy,omitempty"`
+	Owners         []string          `json:"owners,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+	Blame          *blame.Line       `json:"blame,omitempty"`
+	Annotations    []string          `json:"annotations,omitempty"`
+	CallersByOwner []tree.OwnerCount `json:"callersByOwner,omitempty"`
+}
+
+// JSONCall is one call from a node's function to its parent's, also the
+// <call> element of the XML output.
+type JSONCall struct {
+	Line       int      `json:"line" xml:"line,attr"`
+	Column     int      `json:"column" xml:"column,attr"`
+	Kind       string   `json:"kind" xml:"kind,attr"`
+	Via        string   `json:"via,omitempty" xml:"via,attr,omitempty"`
+	Confidence float64  `json:"confidence,omitempty" xml:"confidence,attr,omitempty"`
+	Args       []string `json:"args,omitempty" xml:"arg,omitempty"`
+	// Constants holds the value of each of Args known statically, "" for
+	// the others, and is left out when none is
+	Constants []string `json:"constants,omitempty" xml:"constant,omitempty"`
+}
+
+// JSONGraph is the document written with the edges JSON format: every
+// function of the trees once in Nodes, and every call from a caller to the
+// function it calls once in Edges, for graph tools that cannot read nested
+// trees.
+type JSONGraph struct {
+	Generator *version.Info `json:"generator,omitempty"`
+	Roots     []string      `json:"roots"` // IDs of the traced functions
+	Nodes     []*JSONNode   `json:"nodes"`
+	Edges     []JSONEdge    `json:"edges"`
+}
+
+// JSONEdge is a call from the Source function to the Target function.
+type JSONEdge struct {
+	Source  string     `json:"source"`
+	Target  string     `json:"target"`
+	Calls   []JSONCall `json:"calls,omitempty"`
+	Through []string   `json:"through,omitempty"`
 }
 
 type JSONFormatter struct {
 	outputFile string
+	opts       Options
+}
+
+func NewJSONFormatter(outputFile string, opts Options) *JSONFormatter {
+	return &JSONFormatter{outputFile: outputFile, opts: opts}
 }
 
-func NewJSONFormatter(outputFile string) *JSONFormatter {
-	return &JSONFormatter{outputFile: outputFile}
+// JSONReport is the document written for a multi-target run: one tree per
+// traced function.
+type JSONReport struct {
+	Generator *version.Info `json:"generator,omitempty"`
+	Roots     []*JSONNode   `json:"roots"`
 }
 
 func (jf *JSONFormatter) Format(callTree *tree.CallTree) error {
@@ -32,48 +111,203 @@ func (jf *JSONFormatter) Format(callTree *tree.CallTree) error {
 		return nil
 	}
 	
+	generator := version.Get()
+	root := jf.buildRootNode(callTree)
+	if jf.opts.JSONFormat == "edges" {
+		graph := &JSONGraph{Generator: &generator, Roots: []string{}, Nodes: []*JSONNode{}, Edges: []JSONEdge{}}
+		graph.add(root)
+		return jf.write(graph)
+	}
+	root.Generator = &generator
+	
+	return jf.write(root)
+}
+
+// FormatMulti writes a combined report with one root per call tree.
+func (jf *JSONFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	generator := version.Get()
+	if jf.opts.JSONFormat == "edges" {
+		graph := &JSONGraph{Generator: &generator, Roots: []string{}, Nodes: []*JSONNode{}, Edges: []JSONEdge{}}
+		for _, callTree := range callTrees {
+			if callTree.Root != nil {
+				graph.add(jf.buildRootNode(callTree))
+			}
+		}
+		return jf.write(graph)
+	}
+	report := &JSONReport{
+		Generator: &generator,
+		Roots:     []*JSONNode{},
+	}
+	for _, callTree := range callTrees {
+		if callTree.Root != nil {
+			report.Roots = append(report.Roots, jf.buildRootNode(callTree))
+		}
+	}
+	
+	return jf.write(report)
+}
+
+func (jf *JSONFormatter) buildRootNode(callTree *tree.CallTree) *JSONNode {
 	root := &JSONNode{
-		Name:      callTree.Root.Function.Name,
-		Receiver:  callTree.Root.Function.Receiver,
-		Package:   callTree.Root.Function.Package,
-		File:      callTree.Root.Function.File,
-		Line:      callTree.Root.Function.Line,
-		Signature: callTree.Root.Function.Signature,
-		IsTest:    callTree.Root.Function.IsTest,
+		ID:          nodeID(callTree.Root.Function),
+		Name:        callTree.Root.Function.Name,
+		Receiver:    callTree.Root.Function.Receiver,
+		PointerRecv: callTree.Root.Function.PointerRecv,
+		Package:     callTree.Root.Function.Package,
+		File:        callTree.Root.Function.File,
+		Path:        jf.opts.path(callTree.Root.Function),
+		Line:        callTree.Root.Function.Line,
+		Column:      callTree.Root.Function.Column,
+		EndLine:     callTree.Root.Function.EndLine,
+		EndColumn:   callTree.Root.Function.EndColumn,
+		Signature:   callTree.Root.Function.Signature,
+		Doc:         callTree.Root.Function.Doc,
+		IsTest:      callTree.Root.Function.IsTest,
+		Constraint:  callTree.Root.Function.Constraint,
+		Module:      callTree.Root.Function.Module,
+		Dependency:  callTree.Root.Function.Dependency,
+		Omitted:     callTree.Root.Omitted,
+		OmittedBy:   callTree.Root.OmittedBy,
+		Owners:      callTree.Root.Owners,
+		Tags:        callTree.Root.Tags,
+		EntryPoints: entryPointLabels(callTree.Root),
+
+		CallersByOwner: callTree.CallersByOwner(owners.Unowned),
 	}
 	
 	for _, child := range callTree.Root.Children {
-		jsonChild := jf.buildJSONNode(child)
+		jsonChild := jf.buildJSONNode(child, root.ID)
 		root.Children = append(root.Children, jsonChild)
 	}
 	
-	file, err := os.Create(jf.outputFile)
+	return root
+}
+
+// add flattens the tree under root into the graph. A function appearing
+// several times in the trees keeps the attributes of its first node, and a
+// call repeated under several paths is a single edge.
+func (g *JSONGraph) add(root *JSONNode) {
+	nodes := make(map[string]bool)
+	for _, node := range g.Nodes {
+		nodes[node.ID] = true
+	}
+	edges := make(map[string]bool)
+	for _, edge := range g.Edges {
+		edges[edge.Source+">"+edge.Target] = true
+	}
+	
+	g.Roots = append(g.Roots, root.ID)
+	var walk func(node *JSONNode)
+	walk = func(node *JSONNode) {
+		if node.Parent != "" && !edges[node.ID+">"+node.Parent] {
+			edges[node.ID+">"+node.Parent] = true
+			g.Edges = append(g.Edges, JSONEdge{Source: node.ID, Target: node.Parent, Calls: node.Calls, Through: node.Through})
+		}
+		if !nodes[node.ID] {
+			nodes[node.ID] = true
+			flat := *node
+			flat.Parent, flat.Children, flat.Calls, flat.Through, flat.Usages = "", nil, nil, nil, 0
+			g.Nodes = append(g.Nodes, &flat)
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+}
+
+// write encodes v, checks it against Schema so that consumers generating
+// types from the schema can rely on it, and writes it to the output file.
+func (jf *JSONFormatter) write(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if err := Validate(data); err != nil {
+		return fmt.Errorf("output does not match the JSON schema: %w", err)
+	}
 	
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(root)
+	return os.WriteFile(jf.outputFile, append(data, '\n'), 0644)
 }
 
-func (jf *JSONFormatter) buildJSONNode(node *tree.CallNode) *JSONNode {
+func (jf *JSONFormatter) buildJSONNode(node *tree.CallNode, parentID string) *JSONNode {
 	jsonNode := &JSONNode{
-		Name:      node.Function.Name,
-		Receiver:  node.Function.Receiver,
-		Package:   node.Function.Package,
-		File:      node.Function.File,
-		Line:      node.Function.Line,
-		Signature: node.Function.Signature,
-		Usages:    node.Usages,
-		IsTest:    node.Function.IsTest,
+		ID:          nodeID(node.Function),
+		Parent:      parentID,
+		Name:        node.Function.Name,
+		Receiver:    node.Function.Receiver,
+		PointerRecv: node.Function.PointerRecv,
+		Package:     node.Function.Package,
+		File:        node.Function.File,
+		Path:        jf.opts.path(node.Function),
+		Line:        node.Function.Line,
+		Column:      node.Function.Column,
+		EndLine:     node.Function.EndLine,
+		EndColumn:   node.Function.EndColumn,
+		Signature:   node.Function.Signature,
+		Doc:         node.Function.Doc,
+		Usages:      node.Usages,
+		Alternates:  node.Alternates,
+		IsTest:      node.Function.IsTest,
+		TestKind:    node.Function.TestKind,
+		IsGenerated: node.Function.IsGenerated,
+		IsWrapper:   node.Function.IsWrapper,
+		Complexity:  node.Function.Complexity,
+		Statements:  node.Function.Statements,
+		Dispatch:    node.Dispatch,
+		Constraint:  node.Function.Constraint,
+		Module:      node.Function.Module,
+		CrossModule: node.CrossModule(),
+		Dependency:  node.Function.Dependency,
+		Collapsed:   len(node.Collapsed),
+		Omitted:     node.Omitted,
+		OmittedBy:   node.OmittedBy,
+		Owners:      node.Owners,
+		Tags:        node.Tags,
+		Blame:       node.Blame,
+		Annotations: node.Annotations,
+		EntryPoints: entryPointLabels(node),
+	}
+	
+	for _, fn := range node.Through {
+		jsonNode.Through = append(jsonNode.Through, nodeID(fn))
+	}
+	
+	for _, cs := range node.CallSites {
+		call := JSONCall{Line: cs.Line, Column: cs.Column, Kind: cs.Kind, Via: cs.Via, Confidence: cs.Confidence, Args: cs.Args}
+		for _, value := range cs.Constants {
+			if value != "" {
+				call.Constants = cs.Constants
+				break
+			}
+		}
+		jsonNode.Calls = append(jsonNode.Calls, call)
 	}
 	
 	for _, child := range node.Children {
-		jsonChild := jf.buildJSONNode(child)
+		jsonChild := jf.buildJSONNode(child, jsonNode.ID)
 		jsonNode.Children = append(jsonNode.Children, jsonChild)
 	}
 	
 	return jsonNode
+}
+
+// entryPointLabels describes the handler registrations of node, as in
+// "HTTP GET /users (registered in routes.go:42)".
+func entryPointLabels(node *tree.CallNode) []string {
+	var labels []string
+	for _, entry := range node.EntryPoints {
+		labels = append(labels, entry.String())
+	}
+	return labels
+}
+
+// nodeID identifies fn across runs and reports: a hash of its package,
+// receiver, name, file and line. A function reached through several paths
+// has the same ID at every place it appears.
+func nodeID(fn *analyzer.Function) string {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%d", fn.Package, fn.Receiver, fn.Name, fn.FullPath, fn.Line)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
 }
\ No newline at end of file
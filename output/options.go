@@ -0,0 +1,113 @@
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/tree"
+)
+
+// Options controls how the formatters render a call tree.
+type Options struct {
+	ShowParams     bool   // append parameter lists to function names
+	CollapseChains bool   // fold runs of single-caller nodes into one line
+	Dir            string // absolute analyzed directory
+	AbsPaths       bool   // show absolute paths with line and column
+	Editor         string // one of Editors to link locations to, "" for none
+	Hyperlinks     bool   // link console locations to their files without Editor
+	ColorBy        string // one of ColorModes to color console names by, "" for none
+	Guides         bool   // color console tree guides by depth
+	ShowDocs       bool   // append doc comment synopses to console lines
+	ShowComplexity bool   // show the cyclomatic complexity of each function (console and HTML)
+	JSONFormat     string // one of JSONFormats, "" for tree
+}
+
+// JSONFormats lists the accepted values of Options.JSONFormat.
+var JSONFormats = []string{"tree", "edges"}
+
+// Editors lists the accepted values of Options.Editor.
+var Editors = []string{"vscode", "goland", "vim"}
+
+// ColorModes lists the accepted values of Options.ColorBy.
+var ColorModes = []string{"depth", "package"}
+
+// path returns the file fn is declared in, relative to the analyzed
+// directory or, with AbsPaths, absolute.
+func (o Options) path(fn *analyzer.Function) string {
+	if !o.AbsPaths {
+		return fn.FullPath
+	}
+	return filepath.Join(o.Dir, fn.FullPath)
+}
+
+// location returns the file name shown next to fn. With AbsPaths it is
+// the absolute path with line and column, which terminals and editors can
+// open directly.
+func (o Options) location(fn *analyzer.Function) string {
+	if !o.AbsPaths {
+		return fn.File
+	}
+	return fmt.Sprintf("%s:%d:%d", o.path(fn), fn.Line, fn.Column)
+}
+
+// editorURI returns a URI opening fn's declaration in Editor, or "" when no
+// editor is set.
+func (o Options) editorURI(fn *analyzer.Function) string {
+	file := filepath.ToSlash(filepath.Join(o.Dir, fn.FullPath))
+	switch o.Editor {
+	case "vscode":
+		return fmt.Sprintf("vscode://file%s:%d:%d", slashPrefixed(file), fn.Line, fn.Column)
+	case "goland":
+		return fmt.Sprintf("goland://open?file=%s&line=%d", url.QueryEscape(file), fn.Line)
+	case "vim":
+		return fmt.Sprintf("mvim://open?url=%s&line=%d", url.QueryEscape("file://"+slashPrefixed(file)), fn.Line)
+	}
+	return ""
+}
+
+// consoleURI returns the target of the terminal hyperlink on fn's location:
+// the editor URI, a file URI with Hyperlinks, or "" for no link.
+func (o Options) consoleURI(fn *analyzer.Function) string {
+	if uri := o.editorURI(fn); uri != "" || !o.Hyperlinks {
+		return uri
+	}
+	u := url.URL{Scheme: "file", Path: slashPrefixed(filepath.ToSlash(filepath.Join(o.Dir, fn.FullPath)))}
+	return u.String()
+}
+
+// slashPrefixed makes Windows paths such as C:/src look like the /C:/src
+// that URIs expect.
+func slashPrefixed(path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+	return "/" + path
+}
+
+// collapseChain returns node followed by its callers for as long as each
+// has exactly one caller and none were omitted. The last element is the
+// node whose children are rendered below the folded line.
+func collapseChain(node *tree.CallNode) []*tree.CallNode {
+	chain := []*tree.CallNode{node}
+	for len(node.Children) == 1 && node.Omitted == 0 {
+		node = node.Children[0]
+		chain = append(chain, node)
+	}
+	return chain
+}
+
+// omittedMarker describes the callers of node that a depth or node budget
+// left out, or returns "" when none were.
+func omittedMarker(node *tree.CallNode) string {
+	if node.Omitted == 0 {
+		return ""
+	}
+	noun := "callers"
+	if node.Omitted == 1 {
+		noun = "caller"
+	}
+	return fmt.Sprintf("… %d more %s omitted (use -%s)", node.Omitted, noun, node.OmittedBy)
+}
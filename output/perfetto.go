@@ -0,0 +1,103 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/gogotrace/gogotrace/tree"
+)
+
+// PerfettoFormatter writes the call trees in the Chrome trace event format,
+// which ui.perfetto.dev and chrome://tracing open, so that huge trees can be
+// explored in their flame view. Time is synthetic: the traced function is
+// a slice spanning the whole track, and each caller a slice nested in the
+// function it calls, as wide as the paths going through it.
+type PerfettoFormatter struct {
+	outputFile string
+	opts       Options
+}
+
+func NewPerfettoFormatter(outputFile string, opts Options) *PerfettoFormatter {
+	return &PerfettoFormatter{outputFile: outputFile, opts: opts}
+}
+
+// traceEvent is an event of the trace event format: a complete slice
+// ("X") or thread metadata ("M").
+type traceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat,omitempty"`
+	Phase string                 `json:"ph"`
+	TS    int                    `json:"ts"`
+	Dur   int                    `json:"dur,omitempty"`
+	PID   int                    `json:"pid"`
+	TID   int                    `json:"tid"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+// traceUnit is the width in microseconds of a path through a tree.
+const traceUnit = 1000
+
+func (pf *PerfettoFormatter) Format(callTree *tree.CallTree) error {
+	return pf.FormatMulti([]*tree.CallTree{callTree})
+}
+
+// FormatMulti writes one track per call tree.
+func (pf *PerfettoFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	events := []traceEvent{{Name: "process_name", Phase: "M", PID: 1, Args: map[string]interface{}{"name": "gogotrace"}}}
+	for i, callTree := range callTrees {
+		if callTree.Root == nil {
+			continue
+		}
+		tid := i + 1
+		events = append(events, traceEvent{Name: "thread_name", Phase: "M", PID: 1, TID: tid, Args: map[string]interface{}{"name": callTree.Root.Function.Signature}})
+		events, _ = pf.appendSlices(events, callTree.Root, 0, tid)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pf.outputFile, data, 0644)
+}
+
+// appendSlices adds the slice of node starting at ts, then those of its
+// callers side by side within it, one unit wide per path from node to the
+// end of a branch, and returns the events and the width of node's slice.
+func (pf *PerfettoFormatter) appendSlices(events []traceEvent, node *tree.CallNode, ts, tid int) ([]traceEvent, int) {
+	name := node.Function.Name
+	if node.Function.Receiver != "" {
+		name = "(" + node.Function.Receiver + ")." + node.Function.Name
+	}
+	args := map[string]interface{}{
+		"package": node.Function.Package,
+		"file":    pf.opts.path(node.Function),
+		"line":    node.Function.Line,
+	}
+	if node.Usages > 0 {
+		args["usages"] = node.Usages
+	}
+	if node.Omitted > 0 {
+		args["omitted"] = node.Omitted
+	}
+	cat := "function"
+	if node.Function.IsTest {
+		cat = "test"
+	}
+	slice := len(events)
+	events = append(events, traceEvent{Name: name, Cat: cat, Phase: "X", TS: ts, PID: 1, TID: tid, Args: args})
+
+	width := 0
+	for _, child := range node.Children {
+		var w int
+		events, w = pf.appendSlices(events, child, ts+width, tid)
+		width += w
+	}
+	if width == 0 {
+		width = traceUnit
+	}
+	events[slice].Dur = width
+	return events, width
+}
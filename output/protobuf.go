@@ -0,0 +1,239 @@
+package output
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+
+	"github.com/gogotrace/gogotrace/tree"
+	"github.com/gogotrace/gogotrace/version"
+)
+
+// ProtobufFormatter writes the call graph as the Graph message of
+// graph.proto, in the binary wire format or in its JSON mapping, with the
+// content of the edges JSON format.
+type ProtobufFormatter struct {
+	outputFile string
+	opts       Options
+	asJSON     bool
+}
+
+// NewProtobufFormatter returns a formatter writing the binary wire format,
+// or the protobuf JSON mapping when asJSON is set, to outputFile.
+func NewProtobufFormatter(outputFile string, opts Options, asJSON bool) *ProtobufFormatter {
+	return &ProtobufFormatter{outputFile: outputFile, opts: opts, asJSON: asJSON}
+}
+
+// pbGraph and the types below mirror the messages of graph.proto; their
+// JSON names are those of the protobuf JSON mapping.
+type pbGraph struct {
+	Roots     []string      `json:"roots,omitempty"`
+	Functions []*pbFunction `json:"functions,omitempty"`
+	Edges     []*pbEdge     `json:"edges,omitempty"`
+	Generator string        `json:"generator,omitempty"`
+}
+
+type pbFunction struct {
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Receiver    string   `json:"receiver,omitempty"`
+	PointerRecv bool     `json:"pointerReceiver,omitempty"`
+	Package     string   `json:"package,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Line        int      `json:"line,omitempty"`
+	Column      int      `json:"column,omitempty"`
+	EndLine     int      `json:"endLine,omitempty"`
+	EndColumn   int      `json:"endColumn,omitempty"`
+	Signature   string   `json:"signature,omitempty"`
+	Doc         string   `json:"doc,omitempty"`
+	IsTest      bool     `json:"isTest,omitempty"`
+	TestKind    string   `json:"testKind,omitempty"`
+	IsGenerated bool     `json:"isGenerated,omitempty"`
+	Module      string   `json:"module,omitempty"`
+	Dependency  string   `json:"dependency,omitempty"`
+	EntryPoints []string `json:"entryPoints,omitempty"`
+}
+
+type pbCall struct {
+	Line       int     `json:"line,omitempty"`
+	Column     int     `json:"column,omitempty"`
+	Kind       string  `json:"kind,omitempty"`
+	Via        string  `json:"via,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+type pbEdge struct {
+	Source string    `json:"source,omitempty"`
+	Target string    `json:"target,omitempty"`
+	Calls  []*pbCall `json:"calls,omitempty"`
+}
+
+func (pf *ProtobufFormatter) Format(callTree *tree.CallTree) error {
+	return pf.FormatMulti([]*tree.CallTree{callTree})
+}
+
+// FormatMulti writes one graph holding every call tree.
+func (pf *ProtobufFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	jf := &JSONFormatter{opts: pf.opts}
+	flat := &JSONGraph{}
+	for _, callTree := range callTrees {
+		if callTree.Root != nil {
+			flat.add(jf.buildRootNode(callTree))
+		}
+	}
+
+	graph := &pbGraph{Roots: flat.Roots, Generator: version.Get().Version}
+	for _, node := range flat.Nodes {
+		graph.Functions = append(graph.Functions, &pbFunction{
+			ID:          node.ID,
+			Name:        node.Name,
+			Receiver:    node.Receiver,
+			PointerRecv: node.PointerRecv,
+			Package:     node.Package,
+			Path:        node.Path,
+			Line:        node.Line,
+			Column:      node.Column,
+			EndLine:     node.EndLine,
+			EndColumn:   node.EndColumn,
+			Signature:   node.Signature,
+			Doc:         node.Doc,
+			IsTest:      node.IsTest,
+			TestKind:    node.TestKind,
+			IsGenerated: node.IsGenerated,
+			Module:      node.Module,
+			Dependency:  node.Dependency,
+			EntryPoints: node.EntryPoints,
+		})
+	}
+	for _, edge := range flat.Edges {
+		e := &pbEdge{Source: edge.Source, Target: edge.Target}
+		for _, call := range edge.Calls {
+			e.Calls = append(e.Calls, &pbCall{Line: call.Line, Column: call.Column, Kind: call.Kind, Via: call.Via, Confidence: call.Confidence})
+		}
+		graph.Edges = append(graph.Edges, e)
+	}
+
+	var data []byte
+	if pf.asJSON {
+		var err error
+		if data, err = json.MarshalIndent(graph, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	} else {
+		data = graph.marshal()
+	}
+	return os.WriteFile(pf.outputFile, data, 0644)
+}
+
+func (g *pbGraph) marshal() []byte {
+	var b pbBuffer
+	for _, root := range g.Roots {
+		b.bytes(1, []byte(root))
+	}
+	for _, fn := range g.Functions {
+		b.bytes(2, fn.marshal())
+	}
+	for _, edge := range g.Edges {
+		b.bytes(3, edge.marshal())
+	}
+	b.string(4, g.Generator)
+	return b
+}
+
+func (fn *pbFunction) marshal() []byte {
+	var b pbBuffer
+	b.string(1, fn.ID)
+	b.string(2, fn.Name)
+	b.string(3, fn.Receiver)
+	b.bool(4, fn.PointerRecv)
+	b.string(5, fn.Package)
+	b.string(6, fn.Path)
+	b.int(7, fn.Line)
+	b.int(8, fn.Column)
+	b.int(9, fn.EndLine)
+	b.int(10, fn.EndColumn)
+	b.string(11, fn.Signature)
+	b.string(12, fn.Doc)
+	b.bool(13, fn.IsTest)
+	b.string(14, fn.TestKind)
+	b.bool(15, fn.IsGenerated)
+	b.string(16, fn.Module)
+	b.string(17, fn.Dependency)
+	for _, entry := range fn.EntryPoints {
+		b.bytes(18, []byte(entry))
+	}
+	return b
+}
+
+func (e *pbEdge) marshal() []byte {
+	var b pbBuffer
+	b.string(1, e.Source)
+	b.string(2, e.Target)
+	for _, call := range e.Calls {
+		b.bytes(3, call.marshal())
+	}
+	return b
+}
+
+func (c *pbCall) marshal() []byte {
+	var b pbBuffer
+	b.int(1, c.Line)
+	b.int(2, c.Column)
+	b.string(3, c.Kind)
+	b.string(4, c.Via)
+	b.double(5, c.Confidence)
+	return b
+}
+
+// pbBuffer appends fields in the protobuf wire format. Scalar fields with
+// their zero value are left out, as proto3 does.
+type pbBuffer []byte
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (b *pbBuffer) tag(field, wireType int) {
+	*b = binary.AppendUvarint(*b, uint64(field)<<3|uint64(wireType))
+}
+
+// bytes writes a length-delimited field: a string, a repeated string
+// element or an embedded message, even when empty.
+func (b *pbBuffer) bytes(field int, data []byte) {
+	b.tag(field, wireBytes)
+	*b = binary.AppendUvarint(*b, uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *pbBuffer) string(field int, s string) {
+	if s != "" {
+		b.bytes(field, []byte(s))
+	}
+}
+
+// int writes an int32 field; negative values take ten bytes, as with
+// the reference encoders.
+func (b *pbBuffer) int(field, v int) {
+	if v != 0 {
+		b.tag(field, wireVarint)
+		*b = binary.AppendUvarint(*b, uint64(int64(v)))
+	}
+}
+
+func (b *pbBuffer) bool(field int, v bool) {
+	if v {
+		b.tag(field, wireVarint)
+		*b = append(*b, 1)
+	}
+}
+
+func (b *pbBuffer) double(field int, v float64) {
+	if v != 0 {
+		b.tag(field, wireFixed64)
+		*b = binary.LittleEndian.AppendUint64(*b, math.Float64bits(v))
+	}
+}
@@ -0,0 +1,153 @@
+package output
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Schema is the JSON Schema of the documents the JSON formatter writes, in
+// its tree, multi-target and edges shapes.
+//
+//go:embed schema.json
+var Schema []byte
+
+// schemaRoot is Schema decoded, for validate.
+var schemaRoot = func() map[string]interface{} {
+	var root map[string]interface{}
+	if err := json.Unmarshal(Schema, &root); err != nil {
+		panic("output: invalid schema.json: " + err.Error())
+	}
+	return root
+}()
+
+// Validate checks a JSON document against Schema.
+func Validate(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return validateValue(schemaRoot, doc, "$")
+}
+
+// validateValue checks v against schema, supporting the keywords Schema
+// uses: $ref to its $defs, anyOf, type, enum, minimum, maximum, required,
+// properties, additionalProperties and items.
+func validateValue(schema map[string]interface{}, v interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, err := resolveRef(ref)
+		if err != nil {
+			return err
+		}
+		return validateValue(def, v, path)
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var errs []string
+		for _, s := range anyOf {
+			err := validateValue(s.(map[string]interface{}), v, path)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s matches no allowed shape: %s", path, strings.Join(errs, "; "))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		for _, e := range enum {
+			if e == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+	}
+	if typ, ok := schema["type"].(string); ok && !hasType(v, typ) {
+		return fmt.Errorf("%s: %v is not of type %s", path, v, typ)
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			return fmt.Errorf("%s: %v is below %v", path, v, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			return fmt.Errorf("%s: %v is above %v", path, v, max)
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					return fmt.Errorf("%s: missing %s", path, name)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: unexpected property %s", path, name)
+				}
+				continue
+			}
+			if err := validateValue(prop, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRef returns the definition a "#/$defs/name" reference names.
+func resolveRef(ref string) (map[string]interface{}, error) {
+	name := strings.TrimPrefix(ref, "#/$defs/")
+	defs, _ := schemaRoot["$defs"].(map[string]interface{})
+	def, ok := defs[name].(map[string]interface{})
+	if name == ref || !ok {
+		return nil, fmt.Errorf("schema: unknown reference %s", ref)
+	}
+	return def, nil
+}
+
+// hasType reports whether v, decoded by encoding/json, is of the JSON
+// Schema type typ.
+func hasType(v interface{}, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return v == nil
+	}
+	return false
+}
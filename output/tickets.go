@@ -0,0 +1,155 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/owners"
+	"github.com/gogotrace/gogotrace/tree"
+)
+
+// TicketFormatter writes one markdown file per owning team listing the
+// team's direct call sites of the traced functions, as a checklist ready
+// to paste into a migration ticket. It needs call trees annotated with
+// owners.
+type TicketFormatter struct {
+	dir  string
+	opts Options
+}
+
+func NewTicketFormatter(dir string, opts Options) *TicketFormatter {
+	return &TicketFormatter{dir: dir, opts: opts}
+}
+
+// ticketSite is a call of a traced function in a team's code.
+type ticketSite struct {
+	target *analyzer.Function
+	caller *analyzer.Function
+	call   *analyzer.CallSite
+}
+
+func (tf *TicketFormatter) Format(callTree *tree.CallTree) ([]string, error) {
+	return tf.FormatMulti([]*tree.CallTree{callTree})
+}
+
+// FormatMulti writes the tickets for every call tree, callers without an
+// owner going to the unowned file, and returns the paths written.
+func (tf *TicketFormatter) FormatMulti(callTrees []*tree.CallTree) ([]string, error) {
+	byOwner := make(map[string][]ticketSite)
+	for _, callTree := range callTrees {
+		if callTree.Root == nil {
+			continue
+		}
+		for _, child := range callTree.Root.Children {
+			teams := child.Owners
+			if len(teams) == 0 {
+				teams = []string{owners.Unowned}
+			}
+			for _, team := range teams {
+				for _, cs := range child.CallSites {
+					byOwner[team] = append(byOwner[team], ticketSite{target: callTree.Root.Function, caller: child.Function, call: cs})
+				}
+			}
+		}
+	}
+
+	if err := os.MkdirAll(tf.dir, 0755); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, team := range sortedOwners(byOwner) {
+		path := filepath.Join(tf.dir, ticketFileName(team))
+		if err := os.WriteFile(path, []byte(tf.ticket(team, byOwner[team])), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// ticket renders the markdown snippet of team.
+func (tf *TicketFormatter) ticket(team string, sites []ticketSite) string {
+	sort.SliceStable(sites, func(i, j int) bool {
+		a, b := sites[i], sites[j]
+		if a.target.Key() != b.target.Key() {
+			return a.target.Key() < b.target.Key()
+		}
+		if a.caller.FullPath != b.caller.FullPath {
+			return a.caller.FullPath < b.caller.FullPath
+		}
+		return analyzer.CallSiteLess(a.call, b.call)
+	})
+
+	var sb strings.Builder
+	var targets []string
+	seen := make(map[string]bool)
+	for _, site := range sites {
+		if name := "`" + qualifiedName(site.target) + "`"; !seen[name] {
+			seen[name] = true
+			targets = append(targets, name)
+		}
+	}
+	fmt.Fprintf(&sb, "## Migrate off %s\n\n", strings.Join(targets, ", "))
+	count := fmt.Sprintf("%d call sites", len(sites))
+	verb := "need"
+	if len(sites) == 1 {
+		count, verb = "1 call site", "needs"
+	}
+	if team == owners.Unowned {
+		fmt.Fprintf(&sb, "%s without an owner in CODEOWNERS %s to be migrated:\n\n", count, verb)
+	} else {
+		fmt.Fprintf(&sb, "%s owned by %s %s to be migrated:\n\n", count, team, verb)
+	}
+
+	var current *analyzer.Function
+	for _, site := range sites {
+		if len(targets) > 1 && site.target != current {
+			current = site.target
+			fmt.Fprintf(&sb, "\n### `%s` (%s:%d)\n\n", qualifiedName(current), tf.opts.path(current), current.Line)
+		}
+		fmt.Fprintf(&sb, "- [ ] `%s:%d:%d` in `%s`\n", tf.opts.path(site.caller), site.call.Line, site.call.Column, qualifiedName(site.caller))
+	}
+	return sb.String()
+}
+
+// qualifiedName returns fn's name with its package and receiver, as in
+// "internal/db.(*Store).Save".
+func qualifiedName(fn *analyzer.Function) string {
+	name := fn.Name
+	if fn.Receiver != "" {
+		name = fmt.Sprintf("(%s).%s", fn.Receiver, fn.Name)
+	}
+	if fn.Package == "" || fn.Package == "." {
+		return name
+	}
+	return fn.Package + "." + name
+}
+
+// ticketFileName turns an owner such as @org/team-a or dev@example.com
+// into a file name such as org-team-a.md.
+func ticketFileName(owner string) string {
+	if owner == owners.Unowned {
+		return "unowned.md"
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == '@':
+			return r
+		}
+		return '-'
+	}, strings.TrimPrefix(owner, "@"))
+	return name + ".md"
+}
+
+func sortedOwners(m map[string][]ticketSite) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,329 @@
+package output
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+
+	"github.com/gogotrace/gogotrace/tree"
+	"github.com/gogotrace/gogotrace/version"
+)
+
+const treemapTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>GoGoTrace - Treemap</title>
+    <style>
+        body {
+            font-family: 'Monaco', 'Menlo', 'Courier New', monospace;
+            margin: 20px;
+            background-color: #f5f5f5;
+        }
+        h1 {
+            color: #333;
+            border-bottom: 2px solid #007acc;
+            padding-bottom: 10px;
+        }
+        .info {
+            background-color: #e8f4fd;
+            padding: 10px;
+            border-radius: 5px;
+            margin-bottom: 20px;
+        }
+        .function-name {
+            font-weight: bold;
+            color: #007acc;
+        }
+        .treemap {
+            position: relative;
+            width: 100%;
+            aspect-ratio: {{.Width}} / {{.Height}};
+            background-color: white;
+            box-shadow: 0 2px 4px rgba(0,0,0,0.1);
+        }
+        .tile {
+            position: absolute;
+            box-sizing: border-box;
+            border: 1px solid white;
+            overflow: hidden;
+            padding: 4px;
+            font-size: 0.8em;
+            color: #222;
+        }
+        .tile:hover {
+            border-color: #333;
+        }
+        .tile .count {
+            display: block;
+            color: #444;
+        }
+        .legend {
+            margin-top: 10px;
+            color: #666;
+            font-size: 0.9em;
+        }
+        .footer {
+            margin-top: 40px;
+            padding-top: 20px;
+            border-top: 1px solid #ddd;
+            text-align: center;
+            color: #666;
+        }
+        .footer a {
+            color: #007acc;
+            text-decoration: none;
+        }
+        .footer a:hover {
+            text-decoration: underline;
+        }
+        .build-info {
+            margin-top: 8px;
+            font-size: 0.8em;
+        }
+    </style>
+</head>
+<body>
+    <h1>GoGoTrace - Who Depends on This</h1>
+    <div class="info">
+        <strong>{{if gt (len .Targets) 1}}Target Functions{{else}}Target Function{{end}}:</strong>
+        {{range $i, $t := .Targets}}{{if $i}}, {{end}}<span class="function-name">{{$t}}</span>{{end}}<br>
+        <strong>Call Sites:</strong> {{.CallSites}} in {{len .Tiles}} packages
+    </div>
+    <div class="treemap">
+        {{range .Tiles}}<div class="tile" style="left: {{.Left}}%; top: {{.Top}}%; width: {{.Width}}%; height: {{.Height}}%; background-color: {{.Color}};" title="{{.Package}}: {{.CallSites}} call sites ({{.Direct}} direct) from {{.Callers}} callers">{{.Package}}<span class="count">{{.CallSites}}</span></div>
+        {{end}}
+    </div>
+    <div class="legend">Each rectangle is a package, sized by its call sites into the target, directly or through other callers; the darker, the larger its share.</div>
+    <div class="footer">
+        <a href="https://github.com/kevin-valerio/gogotrace" target="_blank">GoGoTrace on GitHub - Kevin VALERIO</a>
+        <div class="build-info">Generated by {{.Generator}}</div>
+    </div>
+</body>
+</html>`
+
+// TreemapFormatter writes an HTML page with a treemap of the packages
+// calling the traced functions, each sized by its call sites, to show at a
+// glance which areas of the code depend on them.
+type TreemapFormatter struct {
+	outputFile string
+	opts       Options
+}
+
+func NewTreemapFormatter(outputFile string, opts Options) *TreemapFormatter {
+	return &TreemapFormatter{outputFile: outputFile, opts: opts}
+}
+
+// TreemapData is the data of the treemap template.
+type TreemapData struct {
+	Targets   []string
+	CallSites int
+	Tiles     []TreemapTile
+	Width     int
+	Height    int
+	Generator string
+}
+
+// TreemapTile is the rectangle of a package, positioned in percent of the
+// treemap.
+type TreemapTile struct {
+	Package   string
+	CallSites int
+	Direct    int
+	Callers   int
+	Left      string
+	Top       string
+	Width     string
+	Height    string
+	Color     template.CSS
+}
+
+// The treemap is laid out in a box of this size, then scaled to the page
+const (
+	treemapWidth  = 1200
+	treemapHeight = 700
+)
+
+func (tf *TreemapFormatter) Format(callTree *tree.CallTree) error {
+	return tf.FormatMulti([]*tree.CallTree{callTree})
+}
+
+// FormatMulti writes one treemap counting the call sites of every call
+// tree.
+func (tf *TreemapFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	jf := &JSONFormatter{opts: tf.opts}
+	graph := &JSONGraph{}
+	data := TreemapData{Width: treemapWidth, Height: treemapHeight, Generator: version.Get().String()}
+	for _, callTree := range callTrees {
+		if callTree.Root != nil {
+			graph.add(jf.buildRootNode(callTree))
+			data.Targets = append(data.Targets, callTree.Root.Function.Signature)
+		}
+	}
+	nodes := make(map[string]*JSONNode)
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = node
+	}
+	roots := make(map[string]bool)
+	for _, id := range graph.Roots {
+		roots[id] = true
+	}
+
+	type usage struct {
+		pkg     string
+		sites   int
+		direct  int
+		callers map[string]bool
+	}
+	byPackage := make(map[string]*usage)
+	var packages []*usage
+	for _, edge := range graph.Edges {
+		caller := nodes[edge.Source]
+		u := byPackage[caller.Package]
+		if u == nil {
+			u = &usage{pkg: caller.Package, callers: make(map[string]bool)}
+			byPackage[caller.Package] = u
+			packages = append(packages, u)
+		}
+		u.sites += len(edge.Calls)
+		if roots[edge.Target] {
+			u.direct += len(edge.Calls)
+		}
+		u.callers[caller.ID] = true
+		data.CallSites += len(edge.Calls)
+	}
+	// Largest first, as the squarified layout expects
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].sites != packages[j].sites {
+			return packages[i].sites > packages[j].sites
+		}
+		return packages[i].pkg < packages[j].pkg
+	})
+
+	sizes := make([]float64, len(packages))
+	for i, u := range packages {
+		sizes[i] = float64(u.sites)
+	}
+	percent := func(v, total float64) string {
+		return fmt.Sprintf("%.3f", 100*v/total)
+	}
+	for i, r := range squarify(sizes, treemapRect{w: treemapWidth, h: treemapHeight}) {
+		u := packages[i]
+		data.Tiles = append(data.Tiles, TreemapTile{
+			Package:   u.pkg,
+			CallSites: u.sites,
+			Direct:    u.direct,
+			Callers:   len(u.callers),
+			Left:      percent(r.x, treemapWidth),
+			Top:       percent(r.y, treemapHeight),
+			Width:     percent(r.w, treemapWidth),
+			Height:    percent(r.h, treemapHeight),
+			Color:     heatColor(float64(u.sites) / float64(packages[0].sites)),
+		})
+	}
+
+	tmpl, err := template.New("treemap").Parse(treemapTemplate)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(tf.outputFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return tmpl.Execute(file, data)
+}
+
+// heatColor returns a color from pale yellow to deep red as share goes
+// from 0 to 1.
+func heatColor(share float64) template.CSS {
+	return template.CSS(fmt.Sprintf("hsl(%.0f, 85%%, %.0f%%)", 50-50*share, 85-35*share))
+}
+
+type treemapRect struct {
+	x, y, w, h float64
+}
+
+// squarify lays sizes, sorted in decreasing order, out in bounds with the
+// squarified algorithm of Bruls, Huizing and van Wijk: rectangles are
+// added to the current row along the shorter side of the space left for
+// as long as it improves the worst aspect ratio of the row.
+func squarify(sizes []float64, bounds treemapRect) []treemapRect {
+	var total float64
+	for _, size := range sizes {
+		total += size
+	}
+	if total == 0 {
+		return make([]treemapRect, len(sizes))
+	}
+	// Scale the sizes to areas of bounds
+	areas := make([]float64, len(sizes))
+	for i, size := range sizes {
+		areas[i] = size * bounds.w * bounds.h / total
+	}
+
+	rects := make([]treemapRect, 0, len(sizes))
+	free := bounds
+	start := 0
+	for start < len(areas) {
+		side := free.w
+		if free.h < side {
+			side = free.h
+		}
+		end := start + 1
+		for end < len(areas) && worstRatio(areas[start:end+1], side) <= worstRatio(areas[start:end], side) {
+			end++
+		}
+
+		var sum float64
+		for _, area := range areas[start:end] {
+			sum += area
+		}
+		if free.w >= free.h {
+			// A column along the left edge
+			width := sum / free.h
+			y := free.y
+			for _, area := range areas[start:end] {
+				rects = append(rects, treemapRect{x: free.x, y: y, w: width, h: area / width})
+				y += area / width
+			}
+			free.x += width
+			free.w -= width
+		} else {
+			// A row along the top edge
+			height := sum / free.w
+			x := free.x
+			for _, area := range areas[start:end] {
+				rects = append(rects, treemapRect{x: x, y: free.y, w: area / height, h: height})
+				x += area / height
+			}
+			free.y += height
+			free.h -= height
+		}
+		start = end
+	}
+	return rects
+}
+
+// worstRatio returns the largest aspect ratio of the rectangles of a row
+// holding areas along a side of the given length.
+func worstRatio(areas []float64, side float64) float64 {
+	var sum float64
+	smallest, largest := areas[0], areas[0]
+	for _, area := range areas {
+		sum += area
+		if area < smallest {
+			smallest = area
+		}
+		if area > largest {
+			largest = area
+		}
+	}
+	sum2, side2 := sum*sum, side*side
+	if a, b := side2*largest/sum2, sum2/(side2*smallest); a > b {
+		return a
+	}
+	return sum2 / (side2 * smallest)
+}
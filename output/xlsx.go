@@ -0,0 +1,222 @@
+package output
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/owners"
+	"github.com/gogotrace/gogotrace/tree"
+)
+
+// XLSXFormatter writes a spreadsheet for reporting in office tools: an
+// Edges sheet with one row per call in the trees, and a Summary sheet with
+// the callers and call sites per package and, with CODEOWNERS, per owner.
+type XLSXFormatter struct {
+	outputFile string
+	opts       Options
+}
+
+func NewXLSXFormatter(outputFile string, opts Options) *XLSXFormatter {
+	return &XLSXFormatter{outputFile: outputFile, opts: opts}
+}
+
+// sheetRow is a row of cells, strings or ints.
+type sheetRow []interface{}
+
+func (xf *XLSXFormatter) Format(callTree *tree.CallTree) error {
+	return xf.FormatMulti([]*tree.CallTree{callTree})
+}
+
+// FormatMulti writes the calls of every call tree to one workbook.
+func (xf *XLSXFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	jf := &JSONFormatter{opts: xf.opts}
+	graph := &JSONGraph{}
+	for _, callTree := range callTrees {
+		if callTree.Root != nil {
+			graph.add(jf.buildRootNode(callTree))
+		}
+	}
+	nodes := make(map[string]*JSONNode)
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = node
+	}
+
+	edges := []sheetRow{{"Caller", "Caller package", "File", "Line", "Column", "Kind", "Callee", "Callee package", "Owners"}}
+	type tally struct {
+		callers map[string]bool
+		sites   int
+	}
+	byPackage := make(map[string]*tally)
+	byOwner := make(map[string]*tally)
+	count := func(m map[string]*tally, key, caller string, sites int) {
+		if m[key] == nil {
+			m[key] = &tally{callers: make(map[string]bool)}
+		}
+		m[key].callers[caller] = true
+		m[key].sites += sites
+	}
+	owned := false
+	for _, edge := range graph.Edges {
+		caller, callee := nodes[edge.Source], nodes[edge.Target]
+		for _, call := range edge.Calls {
+			edges = append(edges, sheetRow{displayName(caller), caller.Package, caller.Path, call.Line, call.Column, call.Kind, displayName(callee), callee.Package, strings.Join(caller.Owners, " ")})
+		}
+		count(byPackage, caller.Package, caller.ID, len(edge.Calls))
+		callerOwners := caller.Owners
+		if len(callerOwners) == 0 {
+			callerOwners = []string{owners.Unowned}
+		} else {
+			owned = true
+		}
+		for _, owner := range callerOwners {
+			count(byOwner, owner, caller.ID, len(edge.Calls))
+		}
+	}
+
+	tallyRows := func(title string, m map[string]*tally) []sheetRow {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		// Most callers first, as in the console summary
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := m[keys[i]], m[keys[j]]
+			if len(a.callers) != len(b.callers) {
+				return len(a.callers) > len(b.callers)
+			}
+			return keys[i] < keys[j]
+		})
+		rows := []sheetRow{{title, "Callers", "Call sites"}}
+		for _, key := range keys {
+			rows = append(rows, sheetRow{key, len(m[key].callers), m[key].sites})
+		}
+		return rows
+	}
+	summary := tallyRows("Package", byPackage)
+	if owned {
+		summary = append(summary, sheetRow{})
+		summary = append(summary, tallyRows("Owner", byOwner)...)
+	}
+
+	return writeXLSX(xf.outputFile, []string{"Edges", "Summary"}, [][]sheetRow{edges, summary})
+}
+
+// displayName returns the name of node's function as the console shows it.
+func displayName(node *JSONNode) string {
+	if node.Receiver != "" {
+		return fmt.Sprintf("(%s).%s", node.Receiver, node.Name)
+	}
+	return node.Name
+}
+
+// writeXLSX writes a minimal Office Open XML workbook, with one worksheet
+// per name. The first row of each sheet is a bold header. Strings are
+// stored inline, so no shared string table is needed.
+func writeXLSX(path string, names []string, sheets [][]sheetRow) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	zw := zip.NewWriter(file)
+
+	var overrides, sheetList, rels strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&sheetList, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(names)+1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			overrides.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheetList.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		{"xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font/><font><b/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border/></borders>` +
+			`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf/><xf fontId="1" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for i, rows := range sheets {
+		parts = append(parts, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), worksheetXML(rows)})
+	}
+
+	for _, part := range parts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + part.content)); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// worksheetXML renders rows as a worksheet, with the first row frozen.
+func worksheetXML(rows []sheetRow) string {
+	var sb strings.Builder
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sb.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	sb.WriteString(`<sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, r+1)
+		// Bold marks the header of each table, the first row or the one
+		// after an empty row
+		style := ""
+		if r == 0 || len(rows[r-1]) == 0 {
+			style = ` s="1"`
+		}
+		for c, cell := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			switch v := cell.(type) {
+			case int:
+				fmt.Fprintf(&sb, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+			case string:
+				if v == "" {
+					continue
+				}
+				fmt.Fprintf(&sb, `<c r="%s" t="inlineStr"%s><is><t>%s</t></is></c>`, ref, style, xmlEscape(v))
+			}
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
+
+// columnName returns the letters of the zero-based column i: A, B, ...,
+// Z, AA, AB, ...
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+var xmlReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func xmlEscape(s string) string {
+	return xmlReplacer.Replace(s)
+}
@@ -0,0 +1,188 @@
+package output
+
+import (
+	"encoding/xml"
+	"os"
+	"time"
+
+	"github.com/gogotrace/gogotrace/tree"
+	"github.com/gogotrace/gogotrace/version"
+)
+
+// XMLFormatter writes the call trees as XML for reporting systems that
+// only ingest XML. The document mirrors the JSON tree: a <gogotrace> root
+// holds one <function> per traced function, the scalar fields of a JSON
+// node are attributes of the same name, free text such as the signature is
+// in child elements, and a function's callers are its nested <function>
+// elements, inside <callers>.
+type XMLFormatter struct {
+	outputFile string
+	opts       Options
+}
+
+func NewXMLFormatter(outputFile string, opts Options) *XMLFormatter {
+	return &XMLFormatter{outputFile: outputFile, opts: opts}
+}
+
+// XMLReport is the <gogotrace> root element.
+type XMLReport struct {
+	XMLName   xml.Name       `xml:"gogotrace"`
+	Version   string         `xml:"version,attr"`
+	Commit    string         `xml:"commit,attr,omitempty"`
+	Date      string         `xml:"date,attr,omitempty"`
+	GoVersion string         `xml:"goVersion,attr"`
+	Functions []*XMLFunction `xml:"function"`
+}
+
+// XMLFunction is a <function> element, the counterpart of JSONNode.
+type XMLFunction struct {
+	ID          string      `xml:"id,attr"`
+	Parent      string      `xml:"parent,attr,omitempty"`
+	Name        string      `xml:"name,attr"`
+	Receiver    string      `xml:"receiver,attr,omitempty"`
+	PointerRecv bool        `xml:"pointerReceiver,attr,omitempty"`
+	Package     string      `xml:"package,attr"`
+	File        string      `xml:"file,attr"`
+	Path        string      `xml:"path,attr"`
+	Line        int         `xml:"line,attr"`
+	Column      int         `xml:"column,attr"`
+	EndLine     int         `xml:"endLine,attr"`
+	EndColumn   int         `xml:"endColumn,attr"`
+	Usages      int         `xml:"usages,attr,omitempty"`
+	Alternates  int         `xml:"alternatePaths,attr,omitempty"`
+	IsTest      bool        `xml:"isTest,attr,omitempty"`
+	TestKind    string      `xml:"testKind,attr,omitempty"`
+	IsGenerated bool        `xml:"isGenerated,attr,omitempty"`
+	IsWrapper   bool        `xml:"isWrapper,attr,omitempty"`
+	Complexity  int         `xml:"complexity,attr,omitempty"`
+	Statements  int         `xml:"statements,attr,omitempty"`
+	Dispatch    string      `xml:"dispatch,attr,omitempty"`
+	Constraint  string      `xml:"constraint,attr,omitempty"`
+	Module      string      `xml:"module,attr,omitempty"`
+	CrossModule bool        `xml:"crossModule,attr,omitempty"`
+	Dependency  string      `xml:"dependency,attr,omitempty"`
+	Collapsed   int         `xml:"collapsed,attr,omitempty"`
+	Omitted     int         `xml:"omitted,attr,omitempty"`
+	OmittedBy   string      `xml:"omittedBy,attr,omitempty"`
+	Signature   string      `xml:"signature"`
+	Doc         string      `xml:"doc,omitempty"`
+	Calls       []JSONCall  `xml:"call"`
+	Through     []string    `xml:"through,omitempty"`
+	EntryPoints []string    `xml:"entryPoint,omitempty"`
+	Owners      []string    `xml:"owner,omitempty"`
+	Tags        []string    `xml:"tag,omitempty"`
+	Blame       *XMLBlame   `xml:"blame,omitempty"`
+	Annotations []string    `xml:"annotation,omitempty"`
+	Callers     *XMLCallers `xml:"callers,omitempty"`
+	// CallersByOwner is set on the traced functions with CODEOWNERS
+	CallersByOwner []XMLOwnerCount `xml:"callersByOwner,omitempty"`
+}
+
+// XMLCallers is the <callers> element of a function that has callers.
+type XMLCallers struct {
+	Functions []*XMLFunction `xml:"function"`
+}
+
+// XMLOwnerCount is a <callersByOwner> element, the number of distinct
+// callers an owner has in the tree.
+type XMLOwnerCount struct {
+	Owner   string `xml:"owner,attr"`
+	Callers int    `xml:"callers,attr"`
+	Direct  int    `xml:"direct,attr,omitempty"`
+}
+
+// XMLBlame is the <blame> element of a caller annotated with git blame.
+type XMLBlame struct {
+	Commit string `xml:"commit,attr"`
+	Author string `xml:"author,attr"`
+	Email  string `xml:"email,attr,omitempty"`
+	Date   string `xml:"date,attr"`
+}
+
+func (xf *XMLFormatter) Format(callTree *tree.CallTree) error {
+	return xf.FormatMulti([]*tree.CallTree{callTree})
+}
+
+// FormatMulti writes one <function> element per call tree.
+func (xf *XMLFormatter) FormatMulti(callTrees []*tree.CallTree) error {
+	generator := version.Get()
+	report := &XMLReport{
+		Version:   generator.Version,
+		Commit:    generator.Commit,
+		Date:      generator.Date,
+		GoVersion: generator.GoVersion,
+	}
+	jf := &JSONFormatter{opts: xf.opts}
+	for _, callTree := range callTrees {
+		if callTree.Root != nil {
+			report.Functions = append(report.Functions, xmlFunction(jf.buildRootNode(callTree)))
+		}
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(xf.outputFile, append(data, '\n'), 0644)
+}
+
+// xmlFunction converts node and its callers.
+func xmlFunction(node *JSONNode) *XMLFunction {
+	fn := &XMLFunction{
+		ID:          node.ID,
+		Parent:      node.Parent,
+		Name:        node.Name,
+		Receiver:    node.Receiver,
+		PointerRecv: node.PointerRecv,
+		Package:     node.Package,
+		File:        node.File,
+		Path:        node.Path,
+		Line:        node.Line,
+		Column:      node.Column,
+		EndLine:     node.EndLine,
+		EndColumn:   node.EndColumn,
+		Usages:      node.Usages,
+		Alternates:  node.Alternates,
+		IsTest:      node.IsTest,
+		TestKind:    node.TestKind,
+		IsGenerated: node.IsGenerated,
+		IsWrapper:   node.IsWrapper,
+		Complexity:  node.Complexity,
+		Statements:  node.Statements,
+		Dispatch:    node.Dispatch,
+		Constraint:  node.Constraint,
+		Module:      node.Module,
+		CrossModule: node.CrossModule,
+		Dependency:  node.Dependency,
+		Collapsed:   node.Collapsed,
+		Omitted:     node.Omitted,
+		OmittedBy:   node.OmittedBy,
+		Signature:   node.Signature,
+		Doc:         node.Doc,
+		Calls:       node.Calls,
+		Through:     node.Through,
+		EntryPoints: node.EntryPoints,
+		Owners:      node.Owners,
+		Tags:        node.Tags,
+		Annotations: node.Annotations,
+	}
+	if node.Blame != nil {
+		fn.Blame = &XMLBlame{
+			Commit: node.Blame.Commit,
+			Author: node.Blame.Author,
+			Email:  node.Blame.Email,
+			Date:   node.Blame.Date.Format(time.RFC3339),
+		}
+	}
+	for _, count := range node.CallersByOwner {
+		fn.CallersByOwner = append(fn.CallersByOwner, XMLOwnerCount{Owner: count.Owner, Callers: count.Callers, Direct: count.Direct})
+	}
+	if len(node.Children) > 0 {
+		fn.Callers = &XMLCallers{}
+		for _, child := range node.Children {
+			fn.Callers.Functions = append(fn.Callers.Functions, xmlFunction(child))
+		}
+	}
+	return fn
+}
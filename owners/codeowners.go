@@ -0,0 +1,188 @@
+// Package owners maps source files to their owners as declared in a
+// CODEOWNERS file, or by directory in a mapping file.
+package owners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Unowned is the owner reported for files no rule matches.
+const Unowned = "(unowned)"
+
+// locations lists where CODEOWNERS is looked up, relative to a repository
+// root, in the order GitHub uses.
+var locations = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+}
+
+// Rule is one pattern line of a CODEOWNERS file.
+type Rule struct {
+	Pattern string
+	Owners  []string
+	re      *regexp.Regexp
+}
+
+// CODEOWNERS holds the parsed rules of a CODEOWNERS file.
+type CODEOWNERS struct {
+	Root  string // directory the patterns are relative to
+	Rules []Rule
+}
+
+// Locate searches dir and its parents for a CODEOWNERS file and returns its
+// path.
+func Locate(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, loc := range locations {
+			path := filepath.Join(dir, loc)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no CODEOWNERS file found")
+		}
+		dir = parent
+	}
+}
+
+// Load parses the CODEOWNERS file at path. Patterns are taken relative to
+// the repository root, which is the file's directory or, for files kept in
+// .github or docs, its parent.
+func Load(path string) (*CODEOWNERS, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	co, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	co.Root = filepath.Dir(path)
+	if base := filepath.Base(co.Root); base == ".github" || base == "docs" {
+		co.Root = filepath.Dir(co.Root)
+	}
+	return co, nil
+}
+
+// Parse reads CODEOWNERS rules from r.
+func Parse(r io.Reader) (*CODEOWNERS, error) {
+	co := &CODEOWNERS{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		// Section headers of GitLab-style files carry no pattern
+		if line == "" || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		re, err := compilePattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		co.Rules = append(co.Rules, Rule{
+			Pattern: fields[0],
+			Owners:  fields[1:],
+			re:      re,
+		})
+	}
+	return co, scanner.Err()
+}
+
+// Owners returns the owners of path, which is relative to Root and uses
+// forward slashes. As on GitHub, the last matching rule wins; a rule with
+// no owners leaves the file unowned.
+func (co *CODEOWNERS) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "./")
+	for i := len(co.Rules) - 1; i >= 0; i-- {
+		if co.Rules[i].re.MatchString(path) {
+			return co.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// OwnersOf returns the owners of the file at path, which may be absolute or
+// relative to the working directory.
+func (co *CODEOWNERS) OwnersOf(path string) []string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(co.Root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return co.Owners(filepath.ToSlash(rel))
+}
+
+// compilePattern turns a gitignore-style CODEOWNERS pattern into a regular
+// expression over slash-separated paths relative to the root.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	// A slash anywhere but the end anchors the pattern to the root
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case p[i] == '*':
+			sb.WriteString("[^/]*")
+		case p[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	// A pattern naming a directory owns everything below it, except that
+	// "dir/*" stops at the direct children as it does on GitHub
+	switch {
+	case dirOnly:
+		sb.WriteString("/.*$")
+	case strings.HasSuffix(p, "/*"):
+		sb.WriteString("$")
+	default:
+		sb.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,46 @@
+package owners
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `# Default owners
+*                @org/everyone
+
+*.js             @org/frontend
+/build/          @org/infra
+docs/*           docs@example.com
+apps/            @org/apps
+/internal/**/db  @org/data
+/vendor/
+`
+
+func TestOwners(t *testing.T) {
+	co, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.go", "@org/everyone"},
+		{"web/app.js", "@org/frontend"},
+		{"build/ci/run.go", "@org/infra"},
+		{"tools/build/run.go", "@org/everyone"},
+		{"docs/intro.md", "docs@example.com"},
+		{"docs/api/intro.md", "@org/everyone"},
+		{"services/apps/api.go", "@org/apps"},
+		{"internal/db/conn.go", "@org/data"},
+		{"internal/store/db/conn.go", "@org/data"},
+		{"vendor/lib/lib.go", ""},
+	}
+	for _, tt := range tests {
+		got := strings.Join(co.Owners(tt.path), " ")
+		if got != tt.want {
+			t.Errorf("Owners(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
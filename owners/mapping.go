@@ -0,0 +1,115 @@
+package owners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Source assigns owners to source files, from a CODEOWNERS file or a
+// Mapping.
+type Source interface {
+	// OwnersOf returns the owners of the file at path, which may be
+	// absolute or relative to the working directory.
+	OwnersOf(path string) []string
+}
+
+// Mapping assigns owners to files by directory convention, for
+// repositories without a CODEOWNERS file: each line of a mapping file is a
+// path prefix followed by its owners, as in
+//
+//	internal/payments  @org/payments
+//	internal/          @org/platform
+//	.                  @org/everyone
+//
+// and the longest prefix matching a file wins. Prefixes are made of whole
+// path elements, so internal/pay does not match internal/payments, and
+// "." matches every file.
+type Mapping struct {
+	Root     string // directory the prefixes are relative to
+	Prefixes []Rule // Pattern is the prefix, longest first
+}
+
+// LoadMapping parses the mapping file at path. Prefixes are taken relative
+// to the file's directory.
+func LoadMapping(path string) (*Mapping, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m, err := ParseMapping(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	m.Root = filepath.Dir(path)
+	return m, nil
+}
+
+// ParseMapping reads path prefix rules from r. Blank lines and text after
+// a # are ignored.
+func ParseMapping(r io.Reader) (*Mapping, error) {
+	m := &Mapping{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 1 {
+			return nil, fmt.Errorf("line %d: no owner for %s", lineNum, fields[0])
+		}
+		prefix := strings.Trim(filepath.ToSlash(filepath.Clean(fields[0])), "/")
+		m.Prefixes = append(m.Prefixes, Rule{Pattern: prefix, Owners: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Longest first, keeping the file order among equal prefixes so that
+	// the first of duplicates wins
+	sort.SliceStable(m.Prefixes, func(i, j int) bool {
+		return len(m.Prefixes[i].Pattern) > len(m.Prefixes[j].Pattern)
+	})
+	return m, nil
+}
+
+// Owners returns the owners of path, which is relative to Root and uses
+// forward slashes.
+func (m *Mapping) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "./")
+	for _, rule := range m.Prefixes {
+		if rule.Pattern == "." || path == rule.Pattern || strings.HasPrefix(path, rule.Pattern+"/") {
+			return rule.Owners
+		}
+	}
+	return nil
+}
+
+// OwnersOf returns the owners of the file at path, which may be absolute or
+// relative to the working directory.
+func (m *Mapping) OwnersOf(path string) []string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(m.Root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return m.Owners(filepath.ToSlash(rel))
+}
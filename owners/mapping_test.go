@@ -0,0 +1,42 @@
+package owners
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleMapping = `# Teams by directory
+.                    @org/everyone
+internal/            @org/platform
+internal/payments    @org/payments @org/billing
+./cmd/tool/          @org/cli  # trailing comment
+`
+
+func TestMappingOwners(t *testing.T) {
+	m, err := ParseMapping(strings.NewReader(sampleMapping))
+	if err != nil {
+		t.Fatalf("ParseMapping failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.go", "@org/everyone"},
+		{"internal/store/db.go", "@org/platform"},
+		{"internal/payments/charge.go", "@org/payments @org/billing"},
+		{"internal/payments2/charge.go", "@org/platform"},
+		{"cmd/tool/main.go", "@org/cli"},
+		{"cmd/other/main.go", "@org/everyone"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(m.Owners(tt.path), " ")
+		if got != tt.want {
+			t.Errorf("Owners(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := ParseMapping(strings.NewReader("internal/\n")); err == nil {
+		t.Error("ParseMapping accepted a prefix without owner")
+	}
+}
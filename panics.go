@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/graph"
+)
+
+func init() {
+	subcommands["panics"] = subcommand{
+		summary: "Audit which exported entry points can reach a panic",
+		run:     runPanics,
+	}
+}
+
+func runPanics(args []string) int {
+	fs := newFlagSet("panics")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	signature := fs.String("func", "", "Function whose panics to trace (default: list every function calling panic)")
+	baseline := addBaselineFlags(fs, "panics")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace panics [-func \"<signature>\"] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	if *signature == "" {
+		var panicking, contained []*analyzer.Function
+		suppressed := 0
+		for _, fn := range a.GetFunctions() {
+			if (*noTests && fn.IsTest) || !a.Panics(fn) {
+				continue
+			}
+			if a.Suppressed(fn, analyzer.RulePanics) {
+				suppressed++
+				continue
+			}
+			if a.Recovers(fn) {
+				contained = append(contained, fn)
+			} else {
+				panicking = append(panicking, fn)
+			}
+		}
+		printFunctionSet("Functions calling panic without recover", panicking)
+		printFunctionSet("Functions calling panic and recovering", contained)
+		printSuppressed(suppressed)
+		return baseline.check(functionFindings(panicking))
+	}
+
+	fn, err := a.FindFunction(*signature)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !a.Panics(fn) {
+		fmt.Printf("Note: %s does not call panic directly\n", fn.Signature)
+	}
+	if a.Recovers(fn) {
+		fmt.Printf("%s recovers its own panics; nothing propagates\n", fn.Signature)
+		return 0
+	}
+
+	exposed, guards, suppressed, next := panicExposure(a, fn, *noTests)
+	fmt.Printf("\nExported entry points that can panic through %s (%d):\n", fn.Name, len(exposed))
+	for _, entry := range exposed {
+		fmt.Printf("  %s in %s:%d\n", entry.Signature, entry.FullPath, entry.Line)
+		fmt.Printf("      %s\n", formatPath(graph.PathTo(entry, next)))
+	}
+	printFunctionSet("Callers that recover and stop the panic", guards)
+	printSuppressed(suppressed)
+
+	// The same entry point is a separate finding for each traced function
+	var findings []string
+	for _, entry := range exposed {
+		findings = append(findings, stableID(entry)+" → "+stableID(fn))
+	}
+	return baseline.check(findings)
+}
+
+// panicExposure walks up from fn to the exported functions a panic in it
+// can escape from, sorted, and the callers that recover it. A caller
+// deferring recover contains the panic: it is reported but the walk does
+// not continue past it. next leads each caller reached toward fn.
+func panicExposure(a *analyzer.Analyzer, fn *analyzer.Function, noTests bool) (exposed, guards []*analyzer.Function, suppressed int, next map[string]*analyzer.Function) {
+	reached, next := graph.ReverseReach(a, fn, noTests, a.Recovers)
+	for _, caller := range reached {
+		switch {
+		case a.Recovers(caller):
+			guards = append(guards, caller)
+		case caller.IsExported() && a.Suppressed(caller, analyzer.RulePanics):
+			suppressed++
+		case caller.IsExported():
+			exposed = append(exposed, caller)
+		}
+	}
+	sortFunctions(exposed)
+	return exposed, guards, suppressed, next
+}
+
+// printSuppressed notes the findings of an audit left out by
+// //gogotrace:ignore comments.
+func printSuppressed(n int) {
+	if n > 0 {
+		fmt.Printf("\n%d suppressed by //gogotrace:ignore\n", n)
+	}
+}
+
+// formatPath renders a call path as "A → B → C".
+func formatPath(path []*analyzer.Function) string {
+	names := make([]string, len(path))
+	for i, fn := range path {
+		names[i] = fn.Name
+		if fn.Receiver != "" {
+			names[i] = fn.Receiver + "." + fn.Name
+		}
+	}
+	return strings.Join(names, " → ")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/graph"
+)
+
+func TestPanicExposure(t *testing.T) {
+	src := `package lib
+
+func explode() { panic("boom") }
+
+func helper() { explode() }
+
+// API lets the panic through.
+func API() { helper() }
+
+// Safe stops it, so Outer is not exposed.
+func Safe() {
+	defer func() { recover() }()
+	helper()
+}
+
+func Outer() { Safe() }
+
+type Svc struct{}
+
+func (s *Svc) Run() { API() }
+
+type svc struct{}
+
+// Method is exported but not part of the API.
+func (svc) Method() { explode() }
+
+//gogotrace:ignore panics
+func Ignored() { explode() }
+`
+	a := analyzer.NewAnalyzer(analyzer.WithFS(fstest.MapFS{"lib/lib.go": {Data: []byte(src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	fn, err := a.FindFunction("func explode()")
+	if err != nil {
+		t.Fatalf("FindFunction: %v", err)
+	}
+
+	exposed, guards, suppressed, next := panicExposure(a, fn, false)
+	var paths []string
+	for _, entry := range exposed {
+		paths = append(paths, formatPath(graph.PathTo(entry, next)))
+	}
+	if want := "API → helper → explode|*Svc.Run → API → helper → explode"; strings.Join(paths, "|") != want {
+		t.Errorf("exposed through %s, want %s", strings.Join(paths, "|"), want)
+	}
+	if len(guards) != 1 || guards[0].Name != "Safe" {
+		t.Errorf("guards = %v, want Safe", guards)
+	}
+	if suppressed != 1 {
+		t.Errorf("%d suppressed, want 1 (Ignored)", suppressed)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/graph"
+)
+
+func init() {
+	subcommands["pkggraph"] = subcommand{
+		summary: "Export package-to-package dependencies derived from calls as DOT or Mermaid",
+		run:     runPkgGraph,
+	}
+}
+
+func runPkgGraph(args []string) int {
+	fs := newFlagSet("pkggraph")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	format := fs.String("format", "dot", "Output format: dot or mermaid")
+	out := fs.String("o", "", "Write the graph to file instead of standard output")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace pkggraph [-format dot|mermaid] [-o file] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "dot" && *format != "mermaid" {
+		fmt.Fprintf(os.Stderr, "Invalid -format value %q (want dot or mermaid)\n", *format)
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	edges := graph.PackageEdges(a, *noTests)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+		slog.Info("writing output", "format", *format, "file", *out)
+	}
+
+	var err error
+	if *format == "mermaid" {
+		err = graph.WriteMermaid(w, edges)
+	} else {
+		err = graph.WriteDOT(w, edges)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing package graph: %v\n", err)
+		return 1
+	}
+
+	// Cycles go to stderr so the graph on stdout stays valid
+	for _, cycle := range graph.PackageCycles(edges) {
+		fmt.Fprintf(os.Stderr, "Package cycle: %s\n", strings.Join(cycle, ", "))
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// loadResolver opens a Go plugin, built with -buildmode=plugin against the
+// same gogotrace version, and returns the resolver it exports as
+//
+//	var Resolver analyzer.CallResolver = ...
+func loadResolver(path string) (analyzer.CallResolver, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup("Resolver")
+	if err != nil {
+		return nil, err
+	}
+	switch r := sym.(type) {
+	case *analyzer.CallResolver:
+		if *r != nil {
+			return *r, nil
+		}
+		return nil, fmt.Errorf("%s: Resolver is nil", path)
+	case analyzer.CallResolver:
+		return r, nil
+	}
+	return nil, fmt.Errorf("%s: Resolver is a %T, want an analyzer.CallResolver", path, sym)
+}
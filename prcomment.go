@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// prCommentLimit caps each list of the pull request comment, which keeps
+// it readable and well below the size limit of a bot comment.
+const prCommentLimit = 25
+
+// impactComment renders the changes between the snapshots as a compact
+// markdown summary for a pull request comment. Without targets, the new and
+// removed callers are the call edges added and removed, and the affected
+// entry points those that can reach a function whose calls changed. With
+// targets, the callers are the transitive callers of the targets gained or
+// lost, and the affected entry points those among them.
+func impactComment(base, head string, before, after *graphSnapshot, targets []string) (string, error) {
+	var added, removed, affected []string
+	var subject string
+	if len(targets) == 0 {
+		addedEdges := edgeDifference(after.edges, before.edges)
+		removedEdges := edgeDifference(before.edges, after.edges)
+		added = codeList(addedEdges)
+		removed = codeList(removedEdges)
+
+		// Entry points reaching the caller of a changed edge, in the
+		// revision where the edge exists
+		entries := make(map[string]string)
+		for _, side := range []struct {
+			snapshot *graphSnapshot
+			edges    []string
+		}{{after, addedEdges}, {before, removedEdges}} {
+			var changed []string
+			for _, edge := range side.edges {
+				changed = append(changed, strings.SplitN(edge, " → ", 2)[0])
+			}
+			for id := range side.snapshot.upstream(changed) {
+				if kind, ok := side.snapshot.entries[id]; ok {
+					entries[id] = kind
+				}
+			}
+		}
+		affected = entryList(entries, nil)
+	} else {
+		var ids, names []string
+		for _, target := range targets {
+			fn, err := after.analyzer.FindFunction(target)
+			if err != nil {
+				// The target may be gone in the head revision
+				if fn, err = before.analyzer.FindFunction(target); err != nil {
+					return "", err
+				}
+			}
+			ids = append(ids, stableID(fn))
+			names = append(names, "`"+stableID(fn)+"`")
+		}
+		subject = " on " + strings.Join(names, ", ")
+
+		reachedBefore, reachedAfter := before.upstream(ids), after.upstream(ids)
+		for _, id := range ids {
+			delete(reachedBefore, id)
+			delete(reachedAfter, id)
+		}
+		status := make(map[string]string)
+		entries := make(map[string]string)
+		for id := range reachedAfter {
+			if !reachedBefore[id] {
+				added = append(added, "`"+id+"`")
+				if kind, ok := after.entries[id]; ok {
+					entries[id], status[id] = kind, "now reaches"
+				}
+			}
+		}
+		for id := range reachedBefore {
+			if !reachedAfter[id] {
+				removed = append(removed, "`"+id+"`")
+				if kind, ok := before.entries[id]; ok {
+					entries[id], status[id] = kind, "no longer reaches"
+				}
+			}
+		}
+		sort.Strings(added)
+		sort.Strings(removed)
+		affected = entryList(entries, status)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "### Call graph impact%s\n\n", subject)
+	fmt.Fprintf(&sb, "Comparing `%s` to `%s`: **%d** new %s, **%d** removed %s, **%d** affected %s.\n",
+		base, head,
+		len(added), plural(len(added), "caller", "callers"),
+		len(removed), plural(len(removed), "caller", "callers"),
+		len(affected), plural(len(affected), "entry point", "entry points"))
+	writeCommentSection(&sb, "New callers", added)
+	writeCommentSection(&sb, "Removed callers", removed)
+	writeCommentSection(&sb, "Affected entry points", affected)
+	return sb.String(), nil
+}
+
+// upstream returns the functions from which any of ids can be reached,
+// ids included.
+func (s *graphSnapshot) upstream(ids []string) map[string]bool {
+	reached := make(map[string]bool)
+	queue := append([]string(nil), ids...)
+	for _, id := range ids {
+		reached[id] = true
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for caller := range s.callers[id] {
+			if !reached[caller] {
+				reached[caller] = true
+				queue = append(queue, caller)
+			}
+		}
+	}
+	return reached
+}
+
+// codeList formats edges or identities as inline code, keeping the arrow
+// of edges outside of it.
+func codeList(items []string) []string {
+	list := make([]string, len(items))
+	for i, item := range items {
+		list[i] = "`" + strings.ReplaceAll(item, " → ", "` → `") + "`"
+	}
+	return list
+}
+
+// entryList formats entry points with what makes them one and, when
+// given, how their reach changed.
+func entryList(entries map[string]string, status map[string]string) []string {
+	var list []string
+	for id, kind := range entries {
+		var notes []string
+		if kind != "exported" && kind != "main" {
+			notes = append(notes, kind)
+		}
+		if status[id] != "" {
+			notes = append(notes, status[id])
+		}
+		item := "`" + id + "`"
+		if len(notes) > 0 {
+			item += " — " + strings.Join(notes, ", ")
+		}
+		list = append(list, item)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// writeCommentSection writes a list under a heading, cut to
+// prCommentLimit items.
+func writeCommentSection(sb *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "\n#### %s\n\n", title)
+	for i, item := range items {
+		if i == prCommentLimit {
+			fmt.Fprintf(sb, "- … and %d more\n", len(items)-prCommentLimit)
+			break
+		}
+		fmt.Fprintf(sb, "- %s\n", item)
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// testSnapshot analyzes src as the only file of a package main.
+func testSnapshot(t *testing.T, src string) *graphSnapshot {
+	t.Helper()
+	a := analyzer.NewAnalyzer(analyzer.WithFS(fstest.MapFS{"main.go": {Data: []byte("package main\n\n" + src)}}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	return newGraphSnapshot(a, false)
+}
+
+func TestImpactComment(t *testing.T) {
+	// A calls C instead of B in the head revision
+	before := testSnapshot(t, "func main() { A() }\n\nfunc A() { B() }\n\nfunc B() {}\n\nfunc C() {}\n")
+	after := testSnapshot(t, "func main() { A() }\n\nfunc A() { C() }\n\nfunc B() {}\n\nfunc C() {}\n")
+
+	tests := []struct {
+		name    string
+		targets []string
+		want    string
+	}{
+		{"edges", nil, "### Call graph impact\n\n" +
+			"Comparing `v1` to `v2`: **1** new caller, **1** removed caller, **2** affected entry points.\n\n" +
+			"#### New callers\n\n- `A` → `C`\n\n" +
+			"#### Removed callers\n\n- `A` → `B`\n\n" +
+			"#### Affected entry points\n\n- `A`\n- `main`\n"},
+		{"gained", []string{"func C()"}, "### Call graph impact on `C`\n\n" +
+			"Comparing `v1` to `v2`: **2** new callers, **0** removed callers, **2** affected entry points.\n\n" +
+			"#### New callers\n\n- `A`\n- `main`\n\n" +
+			"#### Affected entry points\n\n- `A` — now reaches\n- `main` — now reaches\n"},
+		{"lost", []string{"func B()"}, "### Call graph impact on `B`\n\n" +
+			"Comparing `v1` to `v2`: **0** new callers, **2** removed callers, **2** affected entry points.\n\n" +
+			"#### Removed callers\n\n- `A`\n- `main`\n\n" +
+			"#### Affected entry points\n\n- `A` — no longer reaches\n- `main` — no longer reaches\n"},
+		{"unchanged", []string{"func A()"}, "### Call graph impact on `A`\n\n" +
+			"Comparing `v1` to `v2`: **0** new callers, **0** removed callers, **0** affected entry points.\n"},
+	}
+	for _, tt := range tests {
+		got, err := impactComment("v1", "v2", before, after, tt.targets)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: comment\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := impactComment("v1", "v2", before, after, []string{"func Missing()"}); err == nil {
+		t.Errorf("a target in neither revision gave no error")
+	}
+}
+
+func TestCommentSectionLimit(t *testing.T) {
+	items := func(n int) []string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = fmt.Sprintf("`F%d`", i)
+		}
+		return list
+	}
+	tests := []struct {
+		items     int
+		lines     int
+		truncated string
+	}{
+		{0, 0, ""},
+		{prCommentLimit, prCommentLimit, ""},
+		{prCommentLimit + 1, prCommentLimit + 1, "- … and 1 more\n"},
+		{prCommentLimit + 10, prCommentLimit + 1, "- … and 10 more\n"},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		writeCommentSection(&sb, "Callers", items(tt.items))
+		got := sb.String()
+		if n := strings.Count(got, "\n- "); n != tt.lines {
+			t.Errorf("%d items: %d list lines, want %d", tt.items, n, tt.lines)
+		}
+		if tt.truncated != "" && !strings.HasSuffix(got, tt.truncated) {
+			t.Errorf("%d items: section ends %q, want %q", tt.items, got[strings.LastIndex(got, "\n- "):], tt.truncated)
+		}
+		if tt.truncated == "" && strings.Contains(got, "more") {
+			t.Errorf("%d items: section cut although within the limit", tt.items)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package protocol defines the JSON-RPC 2.0 messages exchanged between
+// `gogotrace rpc` and editor extensions such as a VS Code "Show Reverse Call
+// Graph" panel.
+//
+// Messages are framed as in the Language Server Protocol: a Content-Length
+// header, a blank line, then the JSON body. The server answers three
+// methods:
+//
+//	gogotrace/resolveSymbol  Position          -> *Symbol (null when none)
+//	gogotrace/incomingCalls  SymbolParams      -> []IncomingCall
+//	gogotrace/pathToMain     SymbolParams      -> PathToMainResult
+//
+// Lines and columns are 1-based, columns count bytes.
+package protocol
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// Method names.
+const (
+	MethodResolveSymbol = "gogotrace/resolveSymbol"
+	MethodIncomingCalls = "gogotrace/incomingCalls"
+	MethodPathToMain    = "gogotrace/pathToMain"
+)
+
+// JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeUnknownSymbol reports a symbol ID that is not in the graph,
+	// e.g. after the file changed.
+	CodeUnknownSymbol = -32001
+)
+
+// Version is the JSON-RPC version carried by every message.
+const Version = "2.0"
+
+// Request is a call from the client. Notifications have no ID and get no
+// response.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response answers a Request with either Result or Error.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Position is a cursor location. File is absolute or relative to the
+// analyzed directory.
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Symbol is a function or method of the call graph.
+type Symbol struct {
+	ID        string `json:"id"` // opaque, valid for the lifetime of the server
+	Name      string `json:"name"`
+	Receiver  string `json:"receiver,omitempty"`
+	Package   string `json:"package"`
+	Signature string `json:"signature"`
+	File      string `json:"file"` // absolute path
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	IsTest    bool   `json:"isTest,omitempty"`
+}
+
+// SymbolParams names a symbol returned by an earlier request.
+type SymbolParams struct {
+	ID string `json:"id"`
+}
+
+// CallSite is the position of one call, in the caller's file.
+type CallSite struct {
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Kind   string `json:"kind"` // direct, method, go, defer, callback, interface or heuristic
+}
+
+// IncomingCall is a caller of the requested symbol with its calls to it.
+type IncomingCall struct {
+	From  Symbol     `json:"from"`
+	Calls []CallSite `json:"calls"`
+}
+
+// PathToMainResult is a shortest call path from a main function down to
+// the requested symbol, main first. Path is empty when no main reaches it.
+type PathToMainResult struct {
+	Path []Symbol `json:"path"`
+}
+
+// ReadMessage reads the body of the next framed message from r.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// WriteMessage writes v as a framed JSON message to w.
+func WriteMessage(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,37 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMessageFraming(t *testing.T) {
+	var buf bytes.Buffer
+	first := Request{JSONRPC: Version, ID: json.RawMessage("1"), Method: MethodResolveSymbol}
+	second := Request{JSONRPC: Version, Method: "exit"}
+	for _, req := range []Request{first, second} {
+		if err := WriteMessage(&buf, req); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+
+	r := bufio.NewReader(&buf)
+	for _, want := range []Request{first, second} {
+		body, err := ReadMessage(r)
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		var got Request
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("Unmarshal %s: %v", body, err)
+		}
+		if got.Method != want.Method || string(got.ID) != string(want.ID) {
+			t.Errorf("read %+v, want %+v", got, want)
+		}
+	}
+	if _, err := ReadMessage(r); err == nil {
+		t.Error("ReadMessage after the last message succeeded, want EOF")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gogotrace/gogotrace/query"
+)
+
+func init() {
+	subcommands["query"] = subcommand{
+		summary: "Evaluate a set expression over the call graph, e.g. 'callers(Save) - tests()'",
+		run:     runQuery,
+	}
+}
+
+func runQuery(args []string) int {
+	fs := newFlagSet("query")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace query [options] '<expression>'")
+		fmt.Fprintln(fs.Output(), "Operators: | (union), & (intersection), - (difference), parentheses")
+		fmt.Fprintln(fs.Output(), "Functions: all() name(\"re\") callers(S) callees(S) upstream(S) downstream(S)")
+		fmt.Fprintln(fs.Output(), "           package(\"path\") file(\"glob\") receiver(\"T\") tests() exported() generated()")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	// Reject syntax errors before spending time on the analysis
+	expr, err := query.Parse(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid query: %v\n", err)
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+	result, err := query.Eval(a, expr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	printFunctionSet(fs.Arg(0), result.Functions())
+	return 0
+}
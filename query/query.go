@@ -0,0 +1,520 @@
+// Package query evaluates set expressions over a loaded call graph, e.g.
+//
+//	callers(Save) & package("internal/db") - tests()
+//
+// Every expression denotes a set of functions. Sets are combined left to
+// right with | (union), & (intersection) and - (difference); parentheses
+// group. A bare name such as Save or Server.Start, or a quoted one, is the
+// set of functions with that name. The functions are:
+//
+//	all()              every function
+//	name("re")         functions whose name or Receiver.Name matches re
+//	callers(S)         direct callers of S
+//	callees(S)         functions S calls directly
+//	upstream(S)        functions reaching S through any number of calls
+//	downstream(S)      functions reachable from S
+//	package("path")    functions of a package, as a directory or import path
+//	file("glob")       functions declared in files matching glob
+//	receiver("T")      methods of T or *T
+//	tests()            tests, benchmarks, fuzz targets, examples and helpers
+//	exported()         exported functions and methods
+//	generated()        functions from generated files
+package query
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/graph"
+)
+
+// Set is a set of functions keyed by Function.Key.
+type Set map[string]*analyzer.Function
+
+// Functions returns the members of s in no particular order.
+func (s Set) Functions() []*analyzer.Function {
+	fns := make([]*analyzer.Function, 0, len(s))
+	for _, fn := range s {
+		fns = append(fns, fn)
+	}
+	return fns
+}
+
+// Expr is a parsed query.
+type Expr interface {
+	eval(e *evaluator) (Set, error)
+}
+
+// Parse parses a query expression.
+func Parse(src string) (Expr, error) {
+	p := &parser{lex: lexer{src: src}}
+	p.next()
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return expr, nil
+}
+
+// Eval evaluates expr over the call graph of a.
+func Eval(a *analyzer.Analyzer, expr Expr) (Set, error) {
+	return expr.eval(&evaluator{a: a})
+}
+
+// Run parses and evaluates src.
+func Run(a *analyzer.Analyzer, src string) (Set, error) {
+	expr, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return Eval(a, expr)
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokOp // one of | & - ( ) ,
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c == '*' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	switch c := l.src[l.pos]; {
+	case strings.IndexByte("|&-(),", c) >= 0:
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}, nil
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("column %d: unterminated string", start+1)
+		}
+		l.pos++
+		text, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return token{}, fmt.Errorf("column %d: invalid string: %v", start+1, err)
+		}
+		return token{kind: tokString, text: text, pos: start}, nil
+	case isIdentByte(c):
+		for l.pos < len(l.src) && isIdentByte(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	default:
+		return token{}, fmt.Errorf("column %d: unexpected character %q", start+1, c)
+	}
+}
+
+// Parser
+
+type parser struct {
+	lex lexer
+	tok token
+	err error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("column %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.err == nil && p.tok.kind == tokOp && p.tok.text == op
+}
+
+// parseExpr parses terms joined by set operators, left to right.
+func (p *parser) parseExpr() (Expr, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") || p.isOp("&") || p.isOp("-") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, p.err
+}
+
+func (p *parser) parseTerm() (Expr, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	switch {
+	case p.isOp("("):
+		p.next()
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.errorf("expected ) but found %s", p.tok)
+		}
+		p.next()
+		return expr, nil
+	case p.tok.kind == tokString:
+		name := p.tok.text
+		p.next()
+		return &nameExpr{name: name}, p.err
+	case p.tok.kind == tokIdent:
+		ident := p.tok
+		p.next()
+		if !p.isOp("(") {
+			return &nameExpr{name: ident.text}, p.err
+		}
+		p.next()
+		return p.parseCall(ident)
+	}
+	return nil, p.errorf("expected a name, a function or ( but found %s", p.tok)
+}
+
+// parseCall parses the arguments of a function call up to the closing
+// parenthesis, which has not been consumed yet.
+func (p *parser) parseCall(ident token) (Expr, error) {
+	fn, ok := functions[ident.text]
+	if !ok {
+		return nil, fmt.Errorf("column %d: unknown function %s", ident.pos+1, ident.text)
+	}
+
+	call := &callExpr{name: ident.text, fn: fn}
+	switch fn.arg {
+	case argString:
+		if p.tok.kind != tokString {
+			return nil, p.errorf("%s takes a quoted string, found %s", ident.text, p.tok)
+		}
+		call.str = p.tok.text
+		p.next()
+	case argSet:
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.set = arg
+	}
+	if !p.isOp(")") {
+		return nil, p.errorf("expected ) after the argument of %s but found %s", ident.text, p.tok)
+	}
+	p.next()
+
+	if fn.compile != nil {
+		if err := fn.compile(call); err != nil {
+			return nil, fmt.Errorf("column %d: %s: %v", ident.pos+1, ident.text, err)
+		}
+	}
+	return call, p.err
+}
+
+// Expressions
+
+type binaryExpr struct {
+	op          string
+	left, right Expr
+}
+
+func (b *binaryExpr) eval(e *evaluator) (Set, error) {
+	left, err := b.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	right, err := b.right.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(Set)
+	switch b.op {
+	case "|":
+		for k, fn := range left {
+			result[k] = fn
+		}
+		for k, fn := range right {
+			result[k] = fn
+		}
+	case "&":
+		for k, fn := range left {
+			if _, ok := right[k]; ok {
+				result[k] = fn
+			}
+		}
+	case "-":
+		for k, fn := range left {
+			if _, ok := right[k]; !ok {
+				result[k] = fn
+			}
+		}
+	}
+	return result, nil
+}
+
+// nameExpr is a bare or quoted name: the functions called exactly that.
+type nameExpr struct {
+	name string
+}
+
+func (n *nameExpr) eval(e *evaluator) (Set, error) {
+	return e.filter(func(fn *analyzer.Function) bool {
+		for _, name := range names(fn) {
+			if name == n.name {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+type argKind int
+
+const (
+	argNone argKind = iota
+	argString
+	argSet
+)
+
+type callExpr struct {
+	name string
+	fn   function
+	str  string         // argument of argString functions
+	re   *regexp.Regexp // compiled from str by name()
+	set  Expr           // argument of argSet functions
+}
+
+func (c *callExpr) eval(e *evaluator) (Set, error) {
+	var arg Set
+	if c.set != nil {
+		var err error
+		if arg, err = c.set.eval(e); err != nil {
+			return nil, err
+		}
+	}
+	return c.fn.eval(e, c, arg), nil
+}
+
+// function describes one of the query functions.
+type function struct {
+	arg     argKind
+	compile func(c *callExpr) error // validates the argument at parse time
+	eval    func(e *evaluator, c *callExpr, arg Set) Set
+}
+
+var functions map[string]function
+
+func init() {
+	attribute := func(match func(fn *analyzer.Function, arg string) bool) function {
+		return function{
+			arg: argString,
+			compile: func(c *callExpr) error {
+				if c.str == "" {
+					return fmt.Errorf("empty argument")
+				}
+				return nil
+			},
+			eval: func(e *evaluator, c *callExpr, _ Set) Set {
+				return e.filter(func(fn *analyzer.Function) bool { return match(fn, c.str) })
+			},
+		}
+	}
+	flag := func(match func(fn *analyzer.Function) bool) function {
+		return function{arg: argNone, eval: func(e *evaluator, _ *callExpr, _ Set) Set {
+			return e.filter(match)
+		}}
+	}
+
+	functions = map[string]function{
+		"all": flag(func(*analyzer.Function) bool { return true }),
+		"name": {
+			arg: argString,
+			compile: func(c *callExpr) error {
+				var err error
+				c.re, err = regexp.Compile("^(?:" + c.str + ")$")
+				return err
+			},
+			eval: func(e *evaluator, c *callExpr, _ Set) Set {
+				return e.filter(func(fn *analyzer.Function) bool {
+					for _, name := range names(fn) {
+						if c.re.MatchString(name) {
+							return true
+						}
+					}
+					return false
+				})
+			},
+		},
+		"callers": {arg: argSet, eval: func(e *evaluator, _ *callExpr, arg Set) Set {
+			return e.step(arg, e.callers)
+		}},
+		"callees": {arg: argSet, eval: func(e *evaluator, _ *callExpr, arg Set) Set {
+			return e.step(arg, e.callees)
+		}},
+		"upstream": {arg: argSet, eval: func(e *evaluator, _ *callExpr, arg Set) Set {
+			return e.closure(arg, e.callers)
+		}},
+		"downstream": {arg: argSet, eval: func(e *evaluator, _ *callExpr, arg Set) Set {
+			return e.closure(arg, e.callees)
+		}},
+		"package": attribute(func(fn *analyzer.Function, pkg string) bool {
+			pkg = strings.TrimSuffix(pkg, "/")
+			return fn.Package == pkg || fn.Package != "." && strings.HasSuffix(pkg, "/"+fn.Package)
+		}),
+		"file": attribute(func(fn *analyzer.Function, glob string) bool {
+			for _, p := range []string{fn.FullPath, fn.File} {
+				if ok, _ := path.Match(glob, p); ok {
+					return true
+				}
+			}
+			return false
+		}),
+		"receiver": attribute(func(fn *analyzer.Function, typ string) bool {
+			return fn.Receiver != "" && strings.TrimPrefix(fn.Receiver, "*") == strings.TrimPrefix(typ, "*")
+		}),
+		"tests":     flag(func(fn *analyzer.Function) bool { return fn.IsTest }),
+		"exported":  flag(func(fn *analyzer.Function) bool { return fn.IsExported() }),
+		"generated": flag(func(fn *analyzer.Function) bool { return fn.IsGenerated }),
+	}
+}
+
+// names returns the names a query can refer to fn by: Name, and for
+// methods Receiver.Name with and without the pointer.
+func names(fn *analyzer.Function) []string {
+	if fn.Receiver == "" {
+		return []string{fn.Name}
+	}
+	return []string{
+		fn.Name,
+		fn.Receiver + "." + fn.Name,
+		strings.TrimPrefix(fn.Receiver, "*") + "." + fn.Name,
+	}
+}
+
+// Evaluation
+
+type evaluator struct {
+	a           *analyzer.Analyzer
+	all         Set
+	calleeIndex map[string][]*analyzer.Function
+}
+
+func (e *evaluator) universe() Set {
+	if e.all == nil {
+		e.all = make(Set)
+		for _, fn := range e.a.GetFunctions() {
+			e.all[fn.Key()] = fn
+		}
+	}
+	return e.all
+}
+
+func (e *evaluator) filter(match func(fn *analyzer.Function) bool) Set {
+	result := make(Set)
+	for key, fn := range e.universe() {
+		if match(fn) {
+			result[key] = fn
+		}
+	}
+	return result
+}
+
+func (e *evaluator) callers(fn *analyzer.Function) []*analyzer.Function {
+	var fns []*analyzer.Function
+	for _, cs := range e.a.GetCallersOf(fn) {
+		fns = append(fns, cs.Caller)
+	}
+	return fns
+}
+
+func (e *evaluator) callees(fn *analyzer.Function) []*analyzer.Function {
+	if e.calleeIndex == nil {
+		e.calleeIndex = graph.Callees(e.a, false)
+	}
+	return e.calleeIndex[fn.Key()]
+}
+
+// step returns the neighbours of every member of s.
+func (e *evaluator) step(s Set, neighbours func(*analyzer.Function) []*analyzer.Function) Set {
+	result := make(Set)
+	for _, fn := range s {
+		for _, n := range neighbours(fn) {
+			result[n.Key()] = n
+		}
+	}
+	return result
+}
+
+// closure returns every function reached from s by following neighbours
+// one or more times.
+func (e *evaluator) closure(s Set, neighbours func(*analyzer.Function) []*analyzer.Function) Set {
+	result := make(Set)
+	frontier := s
+	for len(frontier) > 0 {
+		next := make(Set)
+		for key, fn := range e.step(frontier, neighbours) {
+			if _, seen := result[key]; !seen {
+				result[key] = fn
+				next[key] = fn
+			}
+		}
+		frontier = next
+	}
+	return result
+}
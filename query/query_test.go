@@ -0,0 +1,86 @@
+package query
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+const src = `package main
+
+type Store struct{}
+
+func (s *Store) Save() {}
+
+func handle(s *Store) { s.Save() }
+
+func Serve() { handle(nil) }
+
+func main() { Serve() }
+`
+
+const testSrc = `package main
+
+import "testing"
+
+func TestHandle(t *testing.T) { handle(nil) }
+`
+
+func TestRun(t *testing.T) {
+	a := analyzer.NewAnalyzer(analyzer.WithFS(fstest.MapFS{
+		"db/main.go":      {Data: []byte(src)},
+		"db/main_test.go": {Data: []byte(testSrc)},
+	}))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`Store.Save`, "Save"},
+		{`callers(Save)`, "handle"},
+		{`upstream(Save)`, "Serve TestHandle handle main"},
+		{`upstream(Save) - tests()`, "Serve handle main"},
+		{`upstream(Save) - tests() | TestHandle`, "Serve TestHandle handle main"},
+		{`upstream(Save) - (tests() | main)`, "Serve handle"},
+		{`upstream(Save) & exported()`, "Serve TestHandle"},
+		{`downstream(Serve) & receiver("Store")`, "Save"},
+		{`name("S.*") & package("example.com/db")`, "Save Serve"},
+		{`file("*_test.go")`, "TestHandle"},
+	}
+	for _, tt := range tests {
+		result, err := Run(a, tt.query)
+		if err != nil {
+			t.Errorf("Run(%q): %v", tt.query, err)
+			continue
+		}
+		var names []string
+		for _, fn := range result {
+			names = append(names, fn.Name)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("Run(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		`callers(Save`,
+		`unknown(Save)`,
+		`package(Save)`,
+		`name("[")`,
+		`Save &`,
+		`Save # x`,
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", query)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/owners"
+)
+
+func init() {
+	subcommands["rename-preview"] = subcommand{
+		summary: "List the call sites to edit when renaming a function, by owner and package",
+		run:     runRenamePreview,
+	}
+}
+
+// renameSite is a place naming the renamed function.
+type renameSite struct {
+	file         string // relative to the analyzed directory
+	line, column int
+	caller       *analyzer.Function
+	note         string // why the site needs a closer look, if it does
+}
+
+func runRenamePreview(args []string) int {
+	fs := newFlagSet("rename-preview")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	signature := fs.String("func", "", "Function or method to rename")
+	to := fs.String("to", "", "New name")
+	codeOwners := fs.String("codeowners", "auto", "CODEOWNERS file grouping the call sites by owner (auto to search the analyzed directory and its parents, none to skip)")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace rename-preview -func <signature> -to <name> [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *signature == "" || *to == "" {
+		fs.Usage()
+		return 2
+	}
+	if !token.IsIdentifier(*to) {
+		fmt.Fprintf(os.Stderr, "Error: %q is not a valid Go identifier\n", *to)
+		return 2
+	}
+
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path: %v\n", err)
+		return 1
+	}
+	var ownership *owners.CODEOWNERS
+	if path := *codeOwners; path != "none" {
+		if path == "auto" {
+			path, err = owners.Locate(absDir)
+		}
+		if err == nil {
+			ownership, err = owners.Load(path)
+		}
+		if err != nil && *codeOwners != "auto" {
+			fmt.Fprintf(os.Stderr, "Error reading CODEOWNERS: %v\n", err)
+			return 1
+		}
+	}
+
+	a, ok := loadAnalyzer(absDir)
+	if !ok {
+		return 1
+	}
+	fn, err := a.FindFunction(*signature)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	collisions := renameCollisions(a, fn, *to)
+	for _, other := range collisions {
+		fmt.Fprintf(os.Stderr, "Error: %s already declares %s in %s:%d\n", fn.Package, formatPath([]*analyzer.Function{other}), other.FullPath, other.Line)
+	}
+
+	sites, indirect := renameSites(a, fn, *to, *noTests)
+	groups := make(map[string]map[string][]renameSite)
+	for _, site := range sites {
+		owner := "(unowned)"
+		if ownership != nil {
+			if list := ownership.OwnersOf(filepath.Join(absDir, site.file)); len(list) > 0 {
+				owner = strings.Join(list, " ")
+			}
+		}
+		if groups[owner] == nil {
+			groups[owner] = make(map[string][]renameSite)
+		}
+		pkg := site.caller.Package
+		groups[owner][pkg] = append(groups[owner][pkg], site)
+	}
+
+	packages := make(map[string]bool)
+	for _, site := range sites {
+		packages[site.caller.Package] = true
+	}
+	fmt.Printf("Renaming %s to %s: declaration in %s:%d, %d %s in %d %s\n",
+		formatPath([]*analyzer.Function{fn}), *to, fn.FullPath, fn.Line,
+		len(sites), plural(len(sites), "call site", "call sites"), len(packages), plural(len(packages), "package", "packages"))
+
+	for _, owner := range sortedKeys(groups) {
+		fmt.Printf("\n%s\n", owner)
+		for _, pkg := range sortedKeys(groups[owner]) {
+			fmt.Printf("  %s\n", pkg)
+			for _, site := range groups[owner][pkg] {
+				line := fmt.Sprintf("    %s:%d:%d  %s", site.file, site.line, site.column, formatPath([]*analyzer.Function{site.caller}))
+				if site.note != "" {
+					line += "  [" + site.note + "]"
+				}
+				fmt.Println(line)
+			}
+		}
+	}
+	if indirect > 0 {
+		fmt.Printf("\n%d calls found by resolvers, which do not name %s in the source, are not listed\n", indirect, fn.Name)
+	}
+	if len(collisions) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// renameCollisions returns the functions that renaming fn to would clash
+// with: the functions of its package named so or, for a method, the
+// methods of its type, whether their receiver is a pointer or not. With
+// -all-platforms, variants for other platforms do not clash.
+func renameCollisions(a *analyzer.Analyzer, fn *analyzer.Function, to string) []*analyzer.Function {
+	var collisions []*analyzer.Function
+	for _, other := range a.GetFunctions() {
+		if other == fn || other.Name != to || other.Package != fn.Package {
+			continue
+		}
+		if strings.TrimPrefix(other.Receiver, "*") != strings.TrimPrefix(fn.Receiver, "*") {
+			continue
+		}
+		if other.Constraint != "" && fn.Constraint != "" && other.Constraint != fn.Constraint {
+			continue
+		}
+		collisions = append(collisions, other)
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		if collisions[i].FullPath != collisions[j].FullPath {
+			return collisions[i].FullPath < collisions[j].FullPath
+		}
+		return collisions[i].Line < collisions[j].Line
+	})
+	return collisions
+}
+
+// renameSites returns the call sites naming fn, in source order, one per
+// position, and the number of calls that do not name it in the source.
+func renameSites(a *analyzer.Analyzer, fn *analyzer.Function, to string, noTests bool) ([]renameSite, int) {
+	var sites []renameSite
+	indirect := 0
+	seen := make(map[string]bool)
+	unexported := fn.IsExported() && !token.IsExported(to)
+	for _, cs := range a.GetCallersOf(fn) {
+		if noTests && cs.Caller.IsTest {
+			continue
+		}
+		switch cs.Kind {
+		case analyzer.CallSynthetic, analyzer.CallResolved:
+			indirect++
+			continue
+		}
+		key := fmt.Sprintf("%s:%d:%d", cs.Caller.FullPath, cs.Line, cs.Column)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		site := renameSite{file: cs.Caller.FullPath, line: cs.Line, column: cs.Column, caller: cs.Caller}
+		switch {
+		case cs.Kind == analyzer.CallInterface:
+			site.note = "called through an interface: rename the interface method too"
+		case cs.Kind == analyzer.CallExpectation:
+			site.note = "mock expectation: regenerate the mock"
+		case cs.Kind == analyzer.CallHeuristic:
+			site.note = "callee guessed: check the receiver"
+		case unexported && cs.Caller.Package != fn.Package:
+			site.note = to + " is unexported in " + fn.Package
+		}
+		sites = append(sites, site)
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].file != sites[j].file {
+			return sites[i].file < sites[j].file
+		}
+		if sites[i].line != sites[j].line {
+			return sites[i].line < sites[j].line
+		}
+		return sites[i].column < sites[j].column
+	})
+	return sites, indirect
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
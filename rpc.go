@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/graph"
+	"github.com/gogotrace/gogotrace/protocol"
+)
+
+func init() {
+	subcommands["rpc"] = subcommand{
+		summary: "Serve editor requests as JSON-RPC over standard input and output",
+		run:     runRPC,
+	}
+}
+
+// rpcServer answers protocol requests against one analysis.
+type rpcServer struct {
+	a       *analyzer.Analyzer
+	dir     string // absolute analyzed directory
+	noTests bool
+	byKey   map[string]*analyzer.Function
+}
+
+func runRPC(args []string) int {
+	fs := newFlagSet("rpc")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace rpc [options]")
+		fmt.Fprintln(fs.Output(), "Speaks JSON-RPC 2.0 with Content-Length framing on stdin and stdout; see package protocol.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving directory path: %v\n", err)
+		return 1
+	}
+
+	a, ok := loadAnalyzer(absDir)
+	if !ok {
+		return 1
+	}
+
+	s := &rpcServer{a: a, dir: absDir, noTests: *noTests, byKey: make(map[string]*analyzer.Function)}
+	for _, fn := range a.GetFunctions() {
+		s.byKey[fn.Key()] = fn
+	}
+	if err := s.serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serve handles requests until r is closed.
+func (s *rpcServer) serve(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	for {
+		body, err := protocol.ReadMessage(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req protocol.Request
+		resp := protocol.Response{JSONRPC: protocol.Version, ID: json.RawMessage("null")}
+		if err := json.Unmarshal(body, &req); err != nil {
+			resp.Error = &protocol.Error{Code: protocol.CodeParseError, Message: err.Error()}
+		} else {
+			if req.ID == nil {
+				continue // notifications need no answer
+			}
+			resp.ID = req.ID
+			resp.Result, resp.Error = s.handle(req)
+		}
+		if err := protocol.WriteMessage(w, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *rpcServer) handle(req protocol.Request) (interface{}, *protocol.Error) {
+	switch req.Method {
+	case protocol.MethodResolveSymbol:
+		var pos protocol.Position
+		if err := json.Unmarshal(req.Params, &pos); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.resolveSymbol(pos), nil
+	case protocol.MethodIncomingCalls, protocol.MethodPathToMain:
+		var params protocol.SymbolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		fn, ok := s.byKey[params.ID]
+		if !ok {
+			return nil, &protocol.Error{Code: protocol.CodeUnknownSymbol, Message: "unknown symbol " + params.ID}
+		}
+		if req.Method == protocol.MethodIncomingCalls {
+			return s.incomingCalls(fn), nil
+		}
+		return s.pathToMain(fn), nil
+	}
+	return nil, &protocol.Error{Code: protocol.CodeMethodNotFound, Message: "unknown method " + req.Method}
+}
+
+func invalidParams(err error) *protocol.Error {
+	return &protocol.Error{Code: protocol.CodeInvalidParams, Message: err.Error()}
+}
+
+// resolveSymbol returns the innermost function whose declaration spans
+// pos, or nil.
+func (s *rpcServer) resolveSymbol(pos protocol.Position) *protocol.Symbol {
+	file := pos.File
+	if filepath.IsAbs(file) {
+		rel, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return nil
+		}
+		file = rel
+	}
+	file = filepath.Clean(file)
+
+	var best *analyzer.Function
+	for _, fn := range s.byKey {
+		if filepath.Clean(fn.FullPath) != file || !spans(fn, pos.Line, pos.Column) {
+			continue
+		}
+		if best == nil || fn.Line > best.Line || fn.Line == best.Line && fn.Column > best.Column {
+			best = fn
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	sym := s.symbol(best)
+	return &sym
+}
+
+// spans reports whether line and column fall within fn's declaration.
+func spans(fn *analyzer.Function, line, column int) bool {
+	if line < fn.Line || line > fn.EndLine {
+		return false
+	}
+	if line == fn.Line && column < fn.Column {
+		return false
+	}
+	return line != fn.EndLine || column <= fn.EndColumn
+}
+
+func (s *rpcServer) incomingCalls(fn *analyzer.Function) []protocol.IncomingCall {
+	calls := []protocol.IncomingCall{}
+	index := make(map[string]int)
+	for _, cs := range s.a.GetCallersOf(fn) {
+		if s.noTests && cs.Caller.IsTest {
+			continue
+		}
+		key := cs.Caller.Key()
+		i, ok := index[key]
+		if !ok {
+			i = len(calls)
+			index[key] = i
+			calls = append(calls, protocol.IncomingCall{From: s.symbol(cs.Caller)})
+		}
+		calls[i].Calls = append(calls[i].Calls, protocol.CallSite{Line: cs.Line, Column: cs.Column, Kind: cs.Kind})
+	}
+	return calls
+}
+
+// pathToMain returns a shortest path from a main function to fn, over
+// all the mains reaching it. Ties go to the first main by key.
+func (s *rpcServer) pathToMain(fn *analyzer.Function) protocol.PathToMainResult {
+	result := protocol.PathToMainResult{Path: []protocol.Symbol{}}
+	if isMain(fn) {
+		result.Path = append(result.Path, s.symbol(fn))
+		return result
+	}
+
+	// The walk is breadth first, so each path it records is a shortest one
+	reached, next := graph.ReverseReach(s.a, fn, s.noTests, isMain)
+	var mains []string
+	for key, caller := range reached {
+		if isMain(caller) {
+			mains = append(mains, key)
+		}
+	}
+	sort.Strings(mains)
+	var shortest []*analyzer.Function
+	for _, key := range mains {
+		if path := graph.PathTo(reached[key], next); shortest == nil || len(path) < len(shortest) {
+			shortest = path
+		}
+	}
+	for _, step := range shortest {
+		result.Path = append(result.Path, s.symbol(step))
+	}
+	return result
+}
+
+func isMain(fn *analyzer.Function) bool {
+	return fn.Name == "main" && fn.Receiver == ""
+}
+
+func (s *rpcServer) symbol(fn *analyzer.Function) protocol.Symbol {
+	return protocol.Symbol{
+		ID:        fn.Key(),
+		Name:      fn.Name,
+		Receiver:  fn.Receiver,
+		Package:   fn.Package,
+		Signature: fn.Signature,
+		File:      filepath.Join(s.dir, fn.FullPath),
+		Line:      fn.Line,
+		Column:    fn.Column,
+		EndLine:   fn.EndLine,
+		EndColumn: fn.EndColumn,
+		IsTest:    fn.IsTest,
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/protocol"
+)
+
+func TestServe(t *testing.T) {
+	// Two mains reach lib.Target, the one sorting first through two more
+	// functions
+	fsys := fstest.MapFS{
+		"go.mod":          {Data: []byte("module example.com/app\n")},
+		"lib/lib.go":      {Data: []byte("package lib\n\nfunc Target() {}\n")},
+		"cmd/a/main.go":   {Data: []byte("package main\n\nimport \"example.com/app/lib\"\n\nfunc main() { outer() }\n\nfunc outer() { inner() }\n\nfunc inner() { lib.Target() }\n")},
+		"cmd/b/main.go":   {Data: []byte("package main\n\nimport \"example.com/app/lib\"\n\nfunc main() {\n\tlib.Target()\n\tlib.Target()\n}\n")},
+		"cmd/b/b_test.go": {Data: []byte("package main\n\nimport (\n\t\"testing\"\n\n\t\"example.com/app/lib\"\n)\n\nfunc TestTarget(t *testing.T) { lib.Target() }\n")},
+	}
+	a := analyzer.NewAnalyzer(analyzer.WithFS(fsys))
+	if err := a.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	s := &rpcServer{a: a, dir: "/repo", noTests: true, byKey: make(map[string]*analyzer.Function)}
+	for _, fn := range a.GetFunctions() {
+		s.byKey[fn.Key()] = fn
+	}
+
+	// The symbol ID is opaque: resolve it first, as an editor would
+	var target *protocol.Symbol
+	call(t, s, protocol.MethodResolveSymbol, protocol.Position{File: "/repo/lib/lib.go", Line: 3, Column: 8}, &target)
+	if target == nil || target.Name != "Target" || target.File != "/repo/lib/lib.go" {
+		t.Fatalf("resolveSymbol = %+v, want Target", target)
+	}
+	var none *protocol.Symbol
+	call(t, s, protocol.MethodResolveSymbol, protocol.Position{File: "lib/lib.go", Line: 1, Column: 1}, &none)
+	if none != nil {
+		t.Errorf("resolveSymbol outside any function = %+v, want null", none)
+	}
+
+	var incoming []protocol.IncomingCall
+	call(t, s, protocol.MethodIncomingCalls, protocol.SymbolParams{ID: target.ID}, &incoming)
+	calls := make(map[string]int)
+	for _, in := range incoming {
+		calls[in.From.File+" "+in.From.Name] = len(in.Calls)
+	}
+	want := map[string]int{"/repo/cmd/a/main.go inner": 1, "/repo/cmd/b/main.go main": 2}
+	if len(calls) != len(want) {
+		t.Errorf("incomingCalls = %v, want %v", calls, want)
+	}
+	for caller, n := range want {
+		if calls[caller] != n {
+			t.Errorf("%s makes %d calls, want %d", caller, calls[caller], n)
+		}
+	}
+
+	var path protocol.PathToMainResult
+	call(t, s, protocol.MethodPathToMain, protocol.SymbolParams{ID: target.ID}, &path)
+	var steps []string
+	for _, sym := range path.Path {
+		steps = append(steps, sym.File+" "+sym.Name)
+	}
+	if len(steps) != 2 || steps[0] != "/repo/cmd/b/main.go main" || steps[1] != "/repo/lib/lib.go Target" {
+		t.Errorf("pathToMain = %v, want the shorter path from cmd/b", steps)
+	}
+
+	resp := exchange(t, s, protocol.Request{JSONRPC: protocol.Version, ID: json.RawMessage("1"), Method: protocol.MethodIncomingCalls, Params: json.RawMessage(`{"id":"gone"}`)})
+	if resp.Error == nil || resp.Error.Code != protocol.CodeUnknownSymbol {
+		t.Errorf("unknown symbol answered %+v, want code %d", resp.Error, protocol.CodeUnknownSymbol)
+	}
+}
+
+// call sends one request to s and decodes its result into result.
+func call(t *testing.T, s *rpcServer, method string, params, result interface{}) {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := exchange(t, s, protocol.Request{JSONRPC: protocol.Version, ID: json.RawMessage("1"), Method: method, Params: data})
+	if resp.Error != nil {
+		t.Fatalf("%s: %v", method, resp.Error)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		t.Fatalf("%s: decoding result: %v", method, err)
+	}
+}
+
+// exchange serves req, preceded by a notification that must go unanswered,
+// and returns the only response.
+func exchange(t *testing.T, s *rpcServer, req protocol.Request) rawResponse {
+	t.Helper()
+	var in, out bytes.Buffer
+	protocol.WriteMessage(&in, protocol.Request{JSONRPC: protocol.Version, Method: "initialized"})
+	protocol.WriteMessage(&in, req)
+	if err := s.serve(&in, &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	br := bufio.NewReader(&out)
+	body, err := protocol.ReadMessage(br)
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	var resp rawResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if string(resp.ID) != string(req.ID) {
+		t.Errorf("response ID %s, want %s", resp.ID, req.ID)
+	}
+	if _, err := protocol.ReadMessage(br); err == nil {
+		t.Errorf("got more than one response")
+	}
+	return resp
+}
+
+// rawResponse is a protocol.Response with its result left undecoded.
+type rawResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *protocol.Error `json:"error"`
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gogotrace/gogotrace/output"
+)
+
+func init() {
+	subcommands["schema"] = subcommand{
+		summary: "Print the JSON Schema of the -json output",
+		run:     runSchema,
+	}
+}
+
+func runSchema(args []string) int {
+	fs := newFlagSet("schema")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace schema")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+	os.Stdout.Write(output.Schema)
+	return 0
+}
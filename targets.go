@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// readSignatures reads one function signature per line from path, or from
+// standard input when path is "-". Blank lines and lines starting with #
+// are ignored.
+func readSignatures(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var signatures []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		signatures = append(signatures, line)
+	}
+	return signatures, scanner.Err()
+}
+
+// readEntryPatterns reads an -entry-patterns file.
+func readEntryPatterns(path string) ([]analyzer.EntryPattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	patterns, err := analyzer.ParseEntryPatterns(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return patterns, nil
+}
+
+// readEdges reads an -edges file.
+func readEdges(path string) ([]analyzer.Edge, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return analyzer.ParseEdges(f)
+}
+
+// readRoutes reads a -routes file.
+func readRoutes(path string) ([]analyzer.Route, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return analyzer.ParseRoutes(f)
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// hyperlinkModes lists the accepted values of -hyperlinks.
+var hyperlinkModes = []string{"auto", "always", "never"}
+
+// useHyperlinks resolves a -hyperlinks mode: auto links only when standard
+// output is a terminal known to understand OSC 8 escape sequences.
+func useHyperlinks(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return isTerminal(os.Stdout) && terminalSupportsHyperlinks()
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSupportsHyperlinks guesses from the environment whether the
+// terminal renders OSC 8 hyperlinks rather than printing them as garbage.
+func terminalSupportsHyperlinks() bool {
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper":
+		return true
+	}
+	if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("WT_SESSION") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+	if term := os.Getenv("TERM"); strings.Contains(term, "kitty") || strings.Contains(term, "wezterm") || strings.Contains(term, "alacritty") {
+		return true
+	}
+	// GNOME Terminal and other VTE terminals since 0.50
+	if vte, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+	return false
+}
+
+// page writes out to standard output, through $PAGER (less by default)
+// when standard output is a terminal too short to show it at once. It
+// falls back to writing directly if the pager cannot be started.
+func page(out []byte) error {
+	if !isTerminal(os.Stdout) || bytes.Count(out, []byte("\n")) < screenHeight() {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 || args[0] == "cat" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(out)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Like git, let less pass colors through unless told otherwise
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil // the user quit the pager early
+		}
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	return nil
+}
+
+// screenHeight returns the height of the terminal, from the terminal
+// itself or $LINES, or a conventional 24 rows.
+func screenHeight() int {
+	if rows := terminalHeight(os.Stdout); rows > 0 {
+		return rows
+	}
+	if rows, err := strconv.Atoi(os.Getenv("LINES")); err == nil && rows > 0 {
+		return rows
+	}
+	return 24
+}
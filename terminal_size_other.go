@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import "os"
+
+// terminalHeight returns 0 where the terminal size cannot be queried; the
+// LINES environment variable is used instead.
+func terminalHeight(f *os.File) int {
+	return 0
+}
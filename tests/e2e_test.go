@@ -1,25 +1,35 @@
 package tests
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/gogotrace/gogotrace/output"
 )
 
 // JSONOutput represents the JSON output structure
 type JSONOutput struct {
+	ID       string     `json:"id"`
 	Name     string     `json:"name"`
 	Package  string     `json:"package"`
 	File     string     `json:"file"`
 	Line     int        `json:"line"`
 	Children []JSONNode `json:"children"`
+	Omitted  int        `json:"omitted,omitempty"`
 }
 
 type JSONNode struct {
+	ID       string     `json:"id"`
+	Parent   string     `json:"parent"`
 	Name     string     `json:"name"`
 	Receiver string     `json:"receiver,omitempty"`
 	Package  string     `json:"package"`
@@ -121,6 +131,16 @@ func testJSONOutput(t *testing.T, gogoTracePath, fixtureDir string) {
 	foundCallers := make(map[string]bool)
 	collectJSONCallers(&jsonOutput, foundCallers)
 
+	// Every node names its parent by ID
+	if jsonOutput.ID == "" {
+		t.Error("JSON root has no id")
+	}
+	for _, child := range jsonOutput.Children {
+		if child.ID == "" || child.Parent != jsonOutput.ID {
+			t.Errorf("caller %s has id %q and parent %q, want parent %q", child.Name, child.ID, child.Parent, jsonOutput.ID)
+		}
+	}
+
 	// Verify expected callers
 	for caller := range expectedCallers {
 		if !foundCallers[caller] {
@@ -263,6 +283,7 @@ func extractCallersFromConsole(output string) []string {
 				// Handle receiver.method format
 				if funcPart != "" {
 					// Parse the function name - could be in various formats
+					// "(*Service).Execute" -> keep as-is
 					// "*Service.Execute" -> "(*Service).Execute"
 					// "func(...) in main.go" -> keep as-is
 					// "main" -> keep as-is
@@ -270,7 +291,7 @@ func extractCallersFromConsole(output string) []string {
 					funcName := funcPart
 					
 					// Check if it's a method with receiver
-					if strings.Contains(funcName, ".") && !strings.Contains(funcName, "func(") {
+					if strings.Contains(funcName, ".") && !strings.Contains(funcName, "func(") && !strings.HasPrefix(funcName, "(") {
 						// Split by dot to get receiver and method
 						parts := strings.SplitN(funcName, ".", 2)
 						if len(parts) == 2 {
@@ -413,4 +434,694 @@ func TestSpecificFunctions(t *testing.T) {
 			t.Logf("Function %s has %d callers", tc.targetFunc, len(foundCallers))
 		})
 	}
-}
\ No newline at end of file
+}
+func TestMinUsages(t *testing.T) {
+	// Build gogotrace first
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	jsonFile := filepath.Join(os.TempDir(), "test_min_usages.json")
+	defer os.Remove(jsonFile)
+
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-min-usages", "2", "-json", jsonFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, output)
+	}
+
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("Failed to read JSON output: %v", err)
+	}
+
+	var jsonOutput JSONOutput
+	if err := json.Unmarshal(data, &jsonOutput); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	// UtilityFunction is the only caller with more than one call site
+	if len(jsonOutput.Children) != 1 || jsonOutput.Children[0].Name != "UtilityFunction" {
+		t.Errorf("Expected only UtilityFunction to remain, got %+v", jsonOutput.Children)
+	}
+}
+
+func TestClosureCallers(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	jsonFile := filepath.Join(t.TempDir(), "closures.json")
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-json", jsonFile)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, output)
+	}
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("Failed to read JSON output: %v", err)
+	}
+	var jsonOutput JSONOutput
+	if err := json.Unmarshal(data, &jsonOutput); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	// The calls in the closures of init and GetProcessor are credited to
+	// the closures, which those functions call in turn
+	enclosing := make(map[string]bool)
+	for _, child := range jsonOutput.Children {
+		if child.Name == "init" || child.Name == "GetProcessor" {
+			t.Errorf("%s is a direct caller, want it above its closure", child.Name)
+		}
+		if strings.HasPrefix(child.Name, "func(") {
+			for _, caller := range child.Children {
+				enclosing[caller.Name] = true
+			}
+		}
+	}
+	if !enclosing["init"] || !enclosing["GetProcessor"] {
+		t.Errorf("closures are called by %v, want init and GetProcessor", enclosing)
+	}
+}
+
+func TestPackageWithoutExternalCallers(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	// Nothing outside the service package calls into it
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-package", "./service", "-log-level", "warn")
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("-package ./service failed: %v\nStderr: %s", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "0 of 3 functions of ./service have external callers") {
+		t.Errorf("unexpected summary: %s", stdout.String())
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("unexpected errors: %s", stderr.String())
+	}
+}
+
+func TestRenamePreview(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	tests := []struct {
+		signature, to string
+		exitCode      int
+		stdout        string
+		stderr        string
+	}{
+		{"helperFunction", "assist", 0, "1 call site in 1 package", ""},
+		{"UtilityFunction", "AnotherHelper", 1, "", "already declares AnotherHelper in utils.go:14"},
+		{"func (s *Service) Execute()", "internalProcess", 1, "", "already declares *Service.internalProcess in main.go:47"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(gogoTracePath, "rename-preview", "-dir", fixtureDir, "-func", tt.signature, "-to", tt.to, "-log-level", "warn")
+		var stdout, stderr strings.Builder
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		exitCode := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatalf("Failed to run rename-preview: %v", err)
+		}
+		if exitCode != tt.exitCode {
+			t.Errorf("%s to %s: exit code %d, want %d\nStderr: %s", tt.signature, tt.to, exitCode, tt.exitCode, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tt.stdout) {
+			t.Errorf("%s to %s: output lacks %q:\n%s", tt.signature, tt.to, tt.stdout, stdout.String())
+		}
+		if tt.stderr == "" && stderr.Len() > 0 || !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%s to %s: stderr %q, want %q", tt.signature, tt.to, stderr.String(), tt.stderr)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := copyFixture(t, filepath.Join("fixtures", "testproject"))
+
+	// A dead entry point kept on purpose, suppressed for this audit only,
+	// next to one suppressed for another audit
+	legacy := `package main
+
+// LegacyEntry is looked up by name by old plugins.
+//
+//gogotrace:ignore unreachable loaded by name
+func LegacyEntry() { legacyHelper() }
+
+func legacyHelper() {}
+
+func stillDead() {} //gogotrace:ignore panics
+`
+	if err := os.WriteFile(filepath.Join(fixtureDir, "legacy.go"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(gogoTracePath, "unreachable", "-dir", fixtureDir, "-log-level", "warn")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("unreachable failed: %v\nOutput: %s", err, output)
+	}
+
+	// Functions listed under each section, by location
+	sections := make(map[string][]string)
+	section := ""
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "Unreachable, no callers"):
+			section = "orphans"
+		case strings.HasPrefix(line, "Unreachable, called only from unreachable code"):
+			section = "islands"
+		case strings.HasPrefix(line, "  ") && section != "":
+			fields := strings.Fields(line)
+			sections[section] = append(sections[section], fields[len(fields)-1])
+		default:
+			section = ""
+		}
+	}
+	want := map[string]string{
+		"orphans": "complex.go:10 complex.go:16 complex.go:36 complex.go:41 complex.go:48 legacy.go:10 service/handler.go:8 service/handler.go:20 utils.go:14",
+		"islands": "complex.go:21 complex.go:25 complex.go:42 complex.go:55 legacy.go:8 service/handler.go:15 utils.go:4",
+	}
+	for name, locations := range want {
+		if got := strings.Join(sections[name], " "); got != locations {
+			t.Errorf("%s are at %s, want %s", name, got, locations)
+		}
+	}
+	if strings.Contains(string(output), "LegacyEntry") {
+		t.Errorf("the suppressed LegacyEntry is listed:\n%s", output)
+	}
+	if !strings.Contains(string(output), "16 prune candidates") || !strings.Contains(string(output), "1 suppressed by //gogotrace:ignore") {
+		t.Errorf("unexpected counts:\n%s", output)
+	}
+}
+
+// copyFixture copies the Go files of a fixture to a temporary directory
+// that a test can add files to.
+func copyFixture(t *testing.T, fixtureDir string) string {
+	t.Helper()
+	dir := t.TempDir()
+	err := filepath.WalkDir(fixtureDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".go" {
+			return err
+		}
+		rel, err := filepath.Rel(fixtureDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(rel)), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), data, 0644)
+	})
+	if err != nil {
+		t.Fatalf("Failed to copy fixture: %v", err)
+	}
+	return dir
+}
+
+func TestMaxNodes(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	jsonFile := filepath.Join(os.TempDir(), "test_max_nodes.json")
+	defer os.Remove(jsonFile)
+
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-max-nodes", "3", "-json", jsonFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, output)
+	}
+
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("Failed to read JSON output: %v", err)
+	}
+
+	var jsonOutput JSONOutput
+	if err := json.Unmarshal(data, &jsonOutput); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	// The budget is spent on the shallowest callers, the rest are counted
+	if len(jsonOutput.Children) != 3 {
+		t.Errorf("Expected 3 direct callers, got %d", len(jsonOutput.Children))
+	}
+	if jsonOutput.Omitted == 0 {
+		t.Error("Expected the root to report omitted callers")
+	}
+}
+
+func TestDeterministicJSON(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	// Workers discover functions and calls in a different order on every
+	// run; the report must not depend on it
+	var first []byte
+	for run := 0; run < 5; run++ {
+		jsonFile := filepath.Join(os.TempDir(), fmt.Sprintf("test_deterministic_%d.json", run))
+		defer os.Remove(jsonFile)
+
+		cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-audit-errors", "-json", jsonFile)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, output)
+		}
+
+		data, err := os.ReadFile(jsonFile)
+		if err != nil {
+			t.Fatalf("Failed to read JSON output: %v", err)
+		}
+		if run == 0 {
+			first = data
+		} else if !bytes.Equal(data, first) {
+			t.Fatalf("Run %d produced different JSON than run 0", run)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	schema, err := exec.Command(gogoTracePath, "schema").Output()
+	if err != nil {
+		t.Fatalf("Failed to print the schema: %v", err)
+	}
+	if !bytes.Equal(schema, output.Schema) {
+		t.Error("gogotrace schema does not print output/schema.json")
+	}
+
+	// The formatter validates what it writes; check the files all the same
+	for _, format := range []string{"tree", "edges"} {
+		jsonFile := filepath.Join(os.TempDir(), "test_schema_"+format+".json")
+		defer os.Remove(jsonFile)
+
+		cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-audit-errors", "-locks", "-json-format", format, "-json", jsonFile)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, out)
+		}
+
+		data, err := os.ReadFile(jsonFile)
+		if err != nil {
+			t.Fatalf("Failed to read JSON output: %v", err)
+		}
+		if err := output.Validate(data); err != nil {
+			t.Errorf("%s output does not match the schema: %v", format, err)
+		}
+	}
+
+	if err := output.Validate([]byte(`{"id": "x", "name": "f"}`)); err == nil {
+		t.Error("Expected a node without its position to be rejected")
+	}
+}
+
+func TestProtobufOutput(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	pbFile := filepath.Join(os.TempDir(), "test_graph.binpb")
+	pbJSONFile := filepath.Join(os.TempDir(), "test_graph.json")
+	defer os.Remove(pbFile)
+	defer os.Remove(pbJSONFile)
+
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-pb", pbFile, "-pbjson", pbJSONFile)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, out)
+	}
+
+	data, err := os.ReadFile(pbJSONFile)
+	if err != nil {
+		t.Fatalf("Failed to read protobuf JSON output: %v", err)
+	}
+	var graph struct {
+		Roots     []string `json:"roots"`
+		Functions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"functions"`
+		Edges []struct {
+			Source string `json:"source"`
+			Target string `json:"target"`
+		} `json:"edges"`
+	}
+	if err := json.Unmarshal(data, &graph); err != nil {
+		t.Fatalf("Failed to parse protobuf JSON output: %v", err)
+	}
+	if len(graph.Roots) != 1 || len(graph.Functions) == 0 || len(graph.Edges) == 0 {
+		t.Fatalf("Expected one root with functions and edges, got %+v", graph)
+	}
+	if graph.Functions[0].ID != graph.Roots[0] || graph.Functions[0].Name != "TargetFunction" {
+		t.Errorf("Expected TargetFunction first, got %+v", graph.Functions[0])
+	}
+
+	// The binary graph starts with field 1, roots, holding the root's ID
+	binary, err := os.ReadFile(pbFile)
+	if err != nil {
+		t.Fatalf("Failed to read protobuf output: %v", err)
+	}
+	want := append([]byte{0x0a, byte(len(graph.Roots[0]))}, graph.Roots[0]...)
+	if !bytes.HasPrefix(binary, want) {
+		t.Errorf("Binary output starts with %q, want %q", binary[:min(len(binary), len(want))], want)
+	}
+}
+
+func TestXMLOutput(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	xmlFile := filepath.Join(os.TempDir(), "test_output.xml")
+	jsonFile := filepath.Join(os.TempDir(), "test_output_xml.json")
+	defer os.Remove(xmlFile)
+	defer os.Remove(jsonFile)
+
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-xml", xmlFile, "-json", jsonFile)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, out)
+	}
+
+	data, err := os.ReadFile(xmlFile)
+	if err != nil {
+		t.Fatalf("Failed to read XML output: %v", err)
+	}
+	var report output.XMLReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse XML output: %v", err)
+	}
+	if len(report.Functions) != 1 || report.Functions[0].Name != "TargetFunction" {
+		t.Fatalf("Expected a single TargetFunction element, got %d", len(report.Functions))
+	}
+
+	// The XML tree mirrors the JSON one
+	data, err = os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("Failed to read JSON output: %v", err)
+	}
+	var jsonOutput JSONOutput
+	if err := json.Unmarshal(data, &jsonOutput); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	var xmlIDs, jsonIDs []string
+	var walkXML func(fn *output.XMLFunction)
+	walkXML = func(fn *output.XMLFunction) {
+		xmlIDs = append(xmlIDs, fn.ID)
+		if fn.Callers != nil {
+			for _, caller := range fn.Callers.Functions {
+				walkXML(caller)
+			}
+		}
+	}
+	walkXML(report.Functions[0])
+	var walkJSON func(node *JSONNode)
+	walkJSON = func(node *JSONNode) {
+		jsonIDs = append(jsonIDs, node.ID)
+		for i := range node.Children {
+			walkJSON(&node.Children[i])
+		}
+	}
+	jsonIDs = append(jsonIDs, jsonOutput.ID)
+	for i := range jsonOutput.Children {
+		walkJSON(&jsonOutput.Children[i])
+	}
+	if strings.Join(xmlIDs, " ") != strings.Join(jsonIDs, " ") {
+		t.Errorf("XML functions %v differ from JSON nodes %v", xmlIDs, jsonIDs)
+	}
+}
+
+func TestXLSXOutput(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+
+	xlsxFile := filepath.Join(os.TempDir(), "test_report.xlsx")
+	defer os.Remove(xlsxFile)
+
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-xlsx", xlsxFile)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, out)
+	}
+
+	zr, err := zip.OpenReader(xlsxFile)
+	if err != nil {
+		t.Fatalf("Failed to open XLSX output: %v", err)
+	}
+	defer zr.Close()
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		var buf bytes.Buffer
+		buf.ReadFrom(rc)
+		rc.Close()
+		parts[f.Name] = buf.String()
+
+		// Every part must be well-formed XML
+		decoder := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if err != io.EOF {
+					t.Errorf("%s is not well-formed: %v", f.Name, err)
+				}
+				break
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Missing part %s", name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Edges"`) || !strings.Contains(parts["xl/workbook.xml"], `name="Summary"`) {
+		t.Errorf("Expected Edges and Summary sheets, got %s", parts["xl/workbook.xml"])
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], "<t>TargetFunction</t>") {
+		t.Error("Expected the edges sheet to list calls to TargetFunction")
+	}
+}
+
+func TestPerfettoOutput(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+	
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+	
+	traceFile := filepath.Join(os.TempDir(), "test_trace.json")
+	defer os.Remove(traceFile)
+	
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-perfetto", traceFile)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, out)
+	}
+	
+	data, err := os.ReadFile(traceFile)
+	if err != nil {
+		t.Fatalf("Failed to read trace: %v", err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name  string `json:"name"`
+			Phase string `json:"ph"`
+			TS    int    `json:"ts"`
+			Dur   int    `json:"dur"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatalf("Failed to parse trace: %v", err)
+	}
+	
+	var slices int
+	rootEnd := -1
+	for _, event := range trace.TraceEvents {
+		if event.Phase != "X" {
+			continue
+		}
+		slices++
+		if event.Name == "TargetFunction" {
+			rootEnd = event.TS + event.Dur
+			continue
+		}
+		// Every caller is drawn within the traced function's slice
+		if rootEnd < 0 || event.TS+event.Dur > rootEnd {
+			t.Errorf("Slice %s [%d, %d] is outside TargetFunction", event.Name, event.TS, event.TS+event.Dur)
+		}
+	}
+	if slices < 2 {
+		t.Errorf("Expected TargetFunction and its callers as slices, got %d", slices)
+	}
+}
+
+func TestFlamegraphOutput(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+	
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+	
+	foldedFile := filepath.Join(os.TempDir(), "test_callers.folded")
+	svgFile := filepath.Join(os.TempDir(), "test_callers.svg")
+	defer os.Remove(foldedFile)
+	defer os.Remove(svgFile)
+	
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-folded", foldedFile, "-flamegraph", svgFile)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, out)
+	}
+	
+	folded, err := os.ReadFile(foldedFile)
+	if err != nil {
+		t.Fatalf("Failed to read folded stacks: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(folded)), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("Expected a stack per caller path, got:\n%s", folded)
+	}
+	for _, line := range lines {
+		space := strings.LastIndex(line, " ")
+		if space < 0 || !strings.HasPrefix(line, "TargetFunction;") {
+			t.Errorf("Expected a stack based on TargetFunction followed by a count, got %q", line)
+			continue
+		}
+		var count int
+		if _, err := fmt.Sscanf(line[space+1:], "%d", &count); err != nil || count < 1 {
+			t.Errorf("Expected a positive count in %q", line)
+		}
+	}
+	
+	svg, err := os.ReadFile(svgFile)
+	if err != nil {
+		t.Fatalf("Failed to read flame graph: %v", err)
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(svg))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err != io.EOF {
+				t.Errorf("Flame graph is not well-formed: %v", err)
+			}
+			break
+		}
+	}
+	if !strings.Contains(string(svg), "<title>TargetFunction (") {
+		t.Error("Expected a TargetFunction frame in the flame graph")
+	}
+}
+
+func TestTreemapOutput(t *testing.T) {
+	buildCmd := exec.Command("go", "build", "-o", "gogotrace", ".")
+	buildCmd.Dir = ".."
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build gogotrace: %v", err)
+	}
+	
+	gogoTracePath := filepath.Join("..", "gogotrace")
+	fixtureDir := filepath.Join("fixtures", "testproject")
+	
+	treemapFile := filepath.Join(os.TempDir(), "test_treemap.html")
+	defer os.Remove(treemapFile)
+	
+	cmd := exec.Command(gogoTracePath, "-dir", fixtureDir, "-func", "TargetFunction", "-treemap", treemapFile)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to analyze TargetFunction: %v\nOutput: %s", err, out)
+	}
+	
+	content, err := os.ReadFile(treemapFile)
+	if err != nil {
+		t.Fatalf("Failed to read treemap: %v", err)
+	}
+	html := string(content)
+	if !strings.Contains(html, `class="tile"`) {
+		t.Error("Expected a tile per calling package")
+	}
+	// The fixture's callers are all in its root package, which fills the map
+	if !strings.Contains(html, "left: 0.000%; top: 0.000%; width: 100.000%; height: 100.000%") {
+		t.Error("Expected the only package to fill the treemap")
+	}
+}
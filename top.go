@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// printTop writes, for -top, the n transitive callers of each signature
+// that are the most central in the whole call graph: those the most code
+// depends on, whose call sites are the first to migrate. Direct callers
+// are marked as such.
+func printTop(w io.Writer, a *analyzer.Analyzer, signatures []string, noTests bool, n int, dir string) error {
+	centrality := a.Centrality()
+	for i, sig := range signatures {
+		fn, err := a.FindFunction(sig)
+		if err != nil {
+			return err
+		}
+
+		direct := make(map[string]int)
+		for _, cs := range a.GetCallersOf(fn) {
+			direct[cs.Caller.Key()]++
+		}
+		callers := make([]*analyzer.Function, 0)
+		for _, caller := range a.TransitiveCallers(fn, noTests) {
+			callers = append(callers, caller)
+		}
+		sort.Slice(callers, func(i, j int) bool {
+			ri, rj := centrality[callers[i].Key()], centrality[callers[j].Key()]
+			if ri != rj {
+				return ri > rj
+			}
+			return callers[i].Key() < callers[j].Key()
+		})
+		if len(callers) > n {
+			callers = callers[:n]
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Most central callers of %s:\n", fn.Signature)
+		if len(callers) == 0 {
+			fmt.Fprintln(w, "  (no callers)")
+		}
+		for rank, caller := range callers {
+			path := caller.FullPath
+			if rel, err := filepath.Rel(dir, path); err == nil {
+				path = rel
+			}
+			name := caller.Name
+			if caller.Receiver != "" {
+				name = fmt.Sprintf("(%s).%s", caller.Receiver, caller.Name)
+			}
+			if caller.Package != "" && caller.Package != "." {
+				name = caller.Package + "." + name
+			}
+			note := ""
+			if sites := direct[caller.Key()]; sites == 1 {
+				note = "  direct, 1 call site"
+			} else if sites > 1 {
+				note = fmt.Sprintf("  direct, %d call sites", sites)
+			}
+			fmt.Fprintf(w, "%4d. %.6f  %s  %s:%d%s\n", rank+1, centrality[caller.Key()], name, path, caller.Line, note)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package tree
+
+import (
+	"fmt"
+	
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// AuditContext annotates the call edges of the tree that break context
+// propagation: a caller taking a context.Context that calls a function
+// without one, and functions in the middle of a chain that pass the callee
+// a context made by context.Background or context.TODO. It returns the
+// number of findings.
+func (ct *CallTree) AuditContext() int {
+	if ct.Root == nil {
+		return 0
+	}
+	
+	findings := 0
+	ct.walk(ct.Root, func(node *CallNode) {
+		if node == ct.Root {
+			return
+		}
+		// Methods under a BuildType root do not call it
+		callee := node.parent.Function
+		if len(node.CallSites) > 0 && node.Function.TakesContext() && !callee.TakesContext() {
+			node.Annotations = append(node.Annotations, "drops context calling "+ct.GetDisplayName(callee))
+			findings++
+		}
+		// The outermost caller may legitimately create the root context
+		if len(node.Children) == 0 && !node.Function.TakesContext() {
+			return
+		}
+		for _, cs := range node.CallSites {
+			if cs.FreshContext > 0 {
+				node.Annotations = append(node.Annotations, fmt.Sprintf("passes context.Background/TODO from line %d mid-chain", cs.FreshContext))
+				findings++
+				break
+			}
+		}
+	})
+	return findings
+}
+
+// AnnotateLocks annotates the callers that make their call while holding a
+// mutex, naming the lock. It returns the number of such callers.
+func (ct *CallTree) AnnotateLocks() int {
+	if ct.Root == nil {
+		return 0
+	}
+	
+	findings := 0
+	ct.walk(ct.Root, func(node *CallNode) {
+		seen := make(map[string]bool)
+		for _, cs := range node.CallSites {
+			if cs.HeldLock == "" || seen[cs.HeldLock] {
+				continue
+			}
+			seen[cs.HeldLock] = true
+			node.Annotations = append(node.Annotations, "calls while holding "+cs.HeldLock)
+		}
+		if len(seen) > 0 {
+			findings++
+		}
+	})
+	return findings
+}
+
+// AuditErrors annotates every call edge whose callee returns an error with
+// how the caller handles it: propagates, wraps, logs or swallows. It
+// returns the number of callers that swallow the error.
+func (ct *CallTree) AuditErrors() int {
+	if ct.Root == nil {
+		return 0
+	}
+	
+	swallowed := 0
+	ct.walk(ct.Root, func(node *CallNode) {
+		if node == ct.Root || !node.parent.Function.ReturnsError() {
+			return
+		}
+		seen := make(map[string]bool)
+		for _, cs := range node.CallSites {
+			kind := ct.Analyzer.ErrorHandling(cs)
+			if kind == analyzer.ErrorHandlingNone || seen[kind] {
+				continue
+			}
+			seen[kind] = true
+			node.Annotations = append(node.Annotations, "error: "+kind)
+		}
+		if seen[analyzer.ErrorSwallows] {
+			swallowed++
+		}
+	})
+	return swallowed
+}
+
+// CaptureArguments records in each call site of the tree the argument
+// expressions it passes, for auditing how the traced function is called.
+func (ct *CallTree) CaptureArguments() {
+	if ct.Root == nil {
+		return
+	}
+	
+	ct.walk(ct.Root, func(node *CallNode) {
+		for _, cs := range node.CallSites {
+			ct.Analyzer.Arguments(cs)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func TestAuditContext(t *testing.T) {
+	src := `package app
+
+import "context"
+
+func noCtx() {}
+
+func needsCtx(ctx context.Context) {}
+
+func WithCtx(ctx context.Context) {
+	noCtx()
+	needsCtx(context.Background())
+}
+`
+	an := analyzer.NewAnalyzer(analyzer.WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := an.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	// Each edge gets only the finding about its own call
+	tests := []struct {
+		signature string
+		want      []string
+	}{
+		{"func noCtx()", []string{"drops context calling noCtx"}},
+		{"func needsCtx(ctx context.Context)", []string{"passes context.Background/TODO from line 11 mid-chain"}},
+	}
+	for _, tt := range tests {
+		ct := NewCallTree(an, false)
+		if err := ct.Build(tt.signature); err != nil {
+			t.Fatalf("Build(%q): %v", tt.signature, err)
+		}
+		if n := ct.AuditContext(); n != len(tt.want) {
+			t.Errorf("%s: %d findings, want %d", tt.signature, n, len(tt.want))
+		}
+		if len(ct.Root.Children) != 1 {
+			t.Fatalf("%s: %d callers, want 1", tt.signature, len(ct.Root.Children))
+		}
+		if got := ct.Root.Children[0].Annotations; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: WithCtx annotated %q, want %q", tt.signature, got, tt.want)
+		}
+	}
+}
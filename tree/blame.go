@@ -0,0 +1,33 @@
+package tree
+
+import (
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/blame"
+)
+
+// AnnotateBlame sets Blame on every caller node to the most recent change
+// among its call sites, as reported by lookup. Nodes whose lookups fail are
+// left without blame; the first error is returned after the whole tree has
+// been visited.
+func (ct *CallTree) AnnotateBlame(lookup func(cs *analyzer.CallSite) (*blame.Line, error)) error {
+	if ct.Root == nil {
+		return nil
+	}
+	
+	var firstErr error
+	ct.walk(ct.Root, func(node *CallNode) {
+		for _, cs := range node.CallSites {
+			line, err := lookup(cs)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if node.Blame == nil || line.Date.After(node.Blame.Date) {
+				node.Blame = line
+			}
+		}
+	})
+	return firstErr
+}
@@ -7,130 +7,420 @@ import (
 	"strings"
 
 	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/blame"
 )
 
 type CallNode struct {
-	Function  *analyzer.Function
-	Children  []*CallNode
-	Usages    int
-	Depth     int
-	Visited   bool
+	Function    *analyzer.Function
+	Children    []*CallNode
+	Usages      int
+	Depth       int
+	Visited     bool
+	Omitted     int    // callers left out because of a budget
+	OmittedBy   string // "max-nodes" or "max-depth" when Omitted > 0
+	Owners      []string
+	Tags        []string              // //gogotrace:tag tags of Function and of the functions reaching it
+	CallSites   []*analyzer.CallSite  // calls from Function to its parent node
+	Blame       *blame.Line           // most recent change among CallSites
+	Annotations []string              // audit findings about those calls
+	Dispatch    string                // DispatchDynamic or DispatchImpl below an interface method
+	Collapsed   []*analyzer.Function  // third-party functions summarized by this node, see CollapseDeps
+	EntryPoints []analyzer.EntryPoint // registrations of Function as an HTTP or gRPC handler
+	Through     []*analyzer.Function  // wrappers seen through to reach the parent, see SeeThroughWrappers
+	Alternates  int                   // other paths to the root through Function, see UniqueCallers
+	parent      *CallNode
+	// dependencyCallers are the first-party callers reaching the
+	// functions in Collapsed
+	dependencyCallers map[*analyzer.Function][]*analyzer.CallSite
 }
 
 type CallTree struct {
-	Root       *CallNode
-	Analyzer   *analyzer.Analyzer
-	NoTests    bool
-	visitedMap map[string]bool
+	Root      *CallNode
+	Analyzer  *analyzer.Analyzer
+	NoTests   bool
+	MinUsages int    // drop callers with fewer call sites than this
+	SortBy    string // one of SortOrders, package order when empty
+	MaxDepth  int    // deepest caller level expanded
+	MaxNodes  int    // total caller nodes emitted, unlimited when 0
+	Focus     string // keep only branches passing through this package
+	Tag       string // keep only branches passing through a function tagged so
+	// ExportedOnly hides unexported functions, linking each exported
+	// function to the exported callers that reach it through them
+	ExportedOnly bool
+	// Filter, when set, drops the callers it returns false for
+	Filter func(fn *analyzer.Function) bool
+	// OnlyTests keeps only the branches that end in a test function
+	OnlyTests bool
+	// CollapseDeps summarizes the callers from each third-party package
+	// in one node, whose callers are the first-party code above them
+	CollapseDeps bool
+	// Mocks keeps the tests setting expectations on a generated mock's
+	// method among the callers of that method, so that a mocked
+	// interface method shows the tests exercising it
+	Mocks bool
+	// SeeThroughWrappers replaces the callers that only forward their
+	// parameters to the function they call by their own callers, so that
+	// the logical callers of a function are shown directly
+	SeeThroughWrappers bool
+	// UniqueCallers shows each caller once, at its shallowest occurrence,
+	// counting the other paths it reaches the root through in Alternates
+	UniqueCallers bool
+	// rootChildren, when set, replaces the callers of a synthetic root
+	rootChildren func() []*CallNode
+	nodeCount int
+	owned     bool
+	// suppressed are the call sites dropped by a //gogotrace:ignore comment
+	suppressed map[*analyzer.CallSite]bool
 }
 
+// DefaultMaxDepth is the caller depth explored unless MaxDepth is changed.
+const DefaultMaxDepth = 20
+
+// SortOrders lists the accepted values of CallTree.SortBy.
+var SortOrders = []string{"package", "alpha", "usages", "depth"}
+
 func NewCallTree(a *analyzer.Analyzer, noTests bool) *CallTree {
 	return &CallTree{
-		Analyzer:   a,
-		NoTests:    noTests,
-		visitedMap: make(map[string]bool),
+		Analyzer: a,
+		NoTests:  noTests,
+		MaxDepth: DefaultMaxDepth,
 	}
 }
 
 func (ct *CallTree) Build(targetSignature string) error {
-	callSites, err := ct.Analyzer.FindCallers(targetSignature, ct.NoTests)
+	targetFunc, err := ct.Analyzer.FindFunction(targetSignature)
 	if err != nil {
+		if iface, method, ok := ct.interfaceMethod(targetSignature); ok {
+			return ct.BuildInterfaceMethod(iface, method)
+		}
 		return err
 	}
 	
-	if len(callSites) == 0 {
-		return fmt.Errorf("no callers found for signature: %s", targetSignature)
+	return ct.BuildFunction(targetFunc)
+}
+
+// BuildFunction builds the tree of the callers of fn.
+func (ct *CallTree) BuildFunction(fn *analyzer.Function) error {
+	if len(ct.filterCallers(ct.Analyzer.GetCallersOf(fn))) == 0 {
+		return fmt.Errorf("no callers found for signature: %s", fn.Signature)
 	}
 	
-	targetFunc := callSites[0].Callee
 	ct.Root = &CallNode{
-		Function: targetFunc,
-		Depth:    0,
+		Function:    fn,
+		Depth:       0,
+		EntryPoints: ct.Analyzer.EntryPoints(fn),
 	}
 	
-	callerGroups := ct.groupCallSitesByCaller(callSites)
+	ct.expand()
 	
-	for caller, sites := range callerGroups {
-		childNode := &CallNode{
-			Function: caller,
-			Usages:   len(sites),
-			Depth:    1,
-		}
-		ct.Root.Children = append(ct.Root.Children, childNode)
-		ct.buildSubtree(childNode, 2)
+	if ct.Focus != "" && !ct.keepBranches(ct.Root, ct.inFocus) {
+		return fmt.Errorf("no call paths to %s pass through package %s", fn.Signature, ct.Focus)
+	}
+	if ct.Tag != "" && !ct.keepBranches(ct.Root, ct.hasTag) {
+		return fmt.Errorf("no call paths to %s pass through a function tagged %s", fn.Signature, ct.Tag)
+	}
+	if ct.OnlyTests && !ct.keepBranches(ct.Root, isTest) {
+		return fmt.Errorf("no test reaches %s", fn.Signature)
 	}
-	
-	ct.sortChildren(ct.Root)
 	
 	return nil
 }
 
-func (ct *CallTree) groupCallSitesByCaller(callSites []*analyzer.CallSite) map[*analyzer.Function][]*analyzer.CallSite {
-	groups := make(map[*analyzer.Function][]*analyzer.CallSite)
-	for _, cs := range callSites {
-		groups[cs.Caller] = append(groups[cs.Caller], cs)
+// expand grows the tree breadth-first from the root, so that when the
+// MaxNodes budget runs out the shallowest callers are the ones kept.
+func (ct *CallTree) expand() {
+	ct.nodeCount = 0
+	queue := []*CallNode{ct.Root}
+	shown := make(map[string]*CallNode)
+	
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		
+		children := ct.callerNodes(node)
+		if node.Depth >= ct.MaxDepth {
+			node.Omitted, node.OmittedBy = len(children), "max-depth"
+			continue
+		}
+		
+		for i, child := range children {
+			if first, ok := shown[child.Function.Key()]; ok && ct.UniqueCallers {
+				first.Alternates++
+				continue
+			}
+			if ct.MaxNodes > 0 && ct.nodeCount >= ct.MaxNodes {
+				node.Omitted, node.OmittedBy = len(children)-i, "max-nodes"
+				break
+			}
+			node.Children = append(node.Children, child)
+			ct.nodeCount++
+			shown[child.Function.Key()] = child
+			
+			// Recursion: show the caller but stop expanding the cycle
+			if !ct.onPath(node, child.Function) {
+				queue = append(queue, child)
+			}
+		}
 	}
-	return groups
+	
+	ct.sortTree(ct.Root)
 }
 
-func (ct *CallTree) buildSubtree(node *CallNode, depth int) {
-	if depth > 20 { // Increase depth limit for debugging
-		return
+// CrossModule reports whether node's function calls its parent's from
+// another module, as in a workspace of several modules.
+func (node *CallNode) CrossModule() bool {
+	if node.parent == nil || node.Function.Module == "" || node.parent.Function.Module == "" {
+		return false
+	}
+	return node.Function.Module != node.parent.Function.Module
+}
+
+// Via returns the distinct labels of the synthetic calls from node's
+// function to its parent's, as declared with Analyzer.AddEdges, the names
+// of the resolvers that found resolved ones and the container functions
+// of injected ones.
+func (node *CallNode) Via() []string {
+	var vias []string
+	seen := make(map[string]bool)
+	for _, cs := range node.CallSites {
+		if cs.Via != "" && !seen[cs.Via] {
+			seen[cs.Via] = true
+			vias = append(vias, cs.Via)
+		}
+	}
+	return vias
+}
+
+// callerNodes returns the prospective children of node, one per caller,
+// in display order.
+func (ct *CallTree) callerNodes(node *CallNode) []*CallNode {
+	if node == ct.Root && ct.rootChildren != nil {
+		return ct.rootChildren()
 	}
 	
-	key := ct.getFunctionKey(node.Function)
-	if ct.visitedMap[key] {
-		return
+	groups := node.dependencyCallers
+	if node.Collapsed == nil {
+		callSites := ct.filterCallers(ct.Analyzer.GetCallersOf(node.Function))
+		if node.Dispatch == DispatchImpl {
+			callSites = staticCalls(callSites)
+		}
+		groups = ct.groupCallSitesByCaller(callSites)
+	}
+	var through map[*analyzer.Function][]*analyzer.Function
+	if ct.SeeThroughWrappers {
+		groups, through = ct.wrapperCallers(groups)
+	}
+	if ct.ExportedOnly {
+		groups = ct.exportedCallers(groups)
 	}
-	ct.visitedMap[key] = true
-	defer func() { ct.visitedMap[key] = false }()
 	
-	callSites := ct.Analyzer.GetCallersOf(node.Function)
-	if ct.NoTests {
-		callSites = ct.filterTestCallers(callSites)
+	var children []*CallNode
+	for caller, sites := range groups {
+		if len(sites) < ct.MinUsages {
+			continue
+		}
+		children = append(children, &CallNode{
+			Function:    caller,
+			Usages:      len(sites),
+			Depth:       node.Depth + 1,
+			CallSites:   sites,
+			EntryPoints: ct.Analyzer.EntryPoints(caller),
+			Through:     through[caller],
+			parent:      node,
+		})
+	}
+	if ct.CollapseDeps {
+		children = ct.collapseDependencies(node, children)
 	}
 	
-	callerGroups := ct.groupCallSitesByCaller(callSites)
+	holder := &CallNode{Children: children}
+	ct.sortChildren(holder)
+	return holder.Children
+}
+
+// exportedCallers replaces every unexported caller in groups by the
+// nearest exported functions calling it, keeping the call sites of the
+// last hop.
+func (ct *CallTree) exportedCallers(groups map[*analyzer.Function][]*analyzer.CallSite) map[*analyzer.Function][]*analyzer.CallSite {
+	result := make(map[*analyzer.Function][]*analyzer.CallSite)
+	seen := make(map[string]bool)
+	var queue []*analyzer.Function
 	
-	for caller, sites := range callerGroups {
-		childNode := &CallNode{
-			Function: caller,
-			Usages:   len(sites),
-			Depth:    depth,
+	add := func(groups map[*analyzer.Function][]*analyzer.CallSite) {
+		for caller, sites := range groups {
+			if caller.IsExported() {
+				result[caller] = append(result[caller], sites...)
+			} else if !seen[caller.Key()] {
+				seen[caller.Key()] = true
+				queue = append(queue, caller)
+			}
 		}
-		node.Children = append(node.Children, childNode)
-		ct.buildSubtree(childNode, depth+1)
 	}
 	
+	add(groups)
+	for len(queue) > 0 {
+		fn := queue[0]
+		queue = queue[1:]
+		callSites := ct.filterCallers(ct.Analyzer.GetCallersOf(fn))
+		add(ct.groupCallSitesByCaller(callSites))
+	}
+	// Sites were gathered in map order
+	for _, sites := range result {
+		sort.SliceStable(sites, func(i, j int) bool {
+			return analyzer.CallSiteLess(sites[i], sites[j])
+		})
+	}
+	return result
+}
+
+// onPath reports whether fn is node or one of its ancestors.
+func (ct *CallTree) onPath(node *CallNode, fn *analyzer.Function) bool {
+	key := ct.getFunctionKey(fn)
+	for n := node; n != nil; n = n.parent {
+		if ct.getFunctionKey(n.Function) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// keepBranches trims node's subtree to the branches containing a function
+// that match accepts and reports whether anything under node does. Once a
+// branch reaches a match every caller above it is kept.
+func (ct *CallTree) keepBranches(node *CallNode, match func(*analyzer.Function) bool) bool {
+	if node != ct.Root && match(node.Function) {
+		return true
+	}
+	
+	kept := node.Children[:0]
+	for _, child := range node.Children {
+		if ct.keepBranches(child, match) {
+			kept = append(kept, child)
+		}
+	}
+	node.Children = kept
+	return len(kept) > 0
+}
+
+// isTest matches functions go test runs: tests, benchmarks, fuzz targets
+// and examples, but not helpers.
+func isTest(fn *analyzer.Function) bool {
+	return fn.IsTest && fn.TestKind != analyzer.TestKindHelper
+}
+
+// inFocus matches the Focus package.
+func (ct *CallTree) inFocus(fn *analyzer.Function) bool {
+	return fn.InPackage(ct.Focus)
+}
+
+// hasTag matches the functions tagged Tag by a //gogotrace:tag comment.
+func (ct *CallTree) hasTag(fn *analyzer.Function) bool {
+	for _, tag := range ct.Analyzer.Tags(fn) {
+		if tag == ct.Tag {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTree sorts every level once all subtrees are known, which the depth
+// order needs.
+func (ct *CallTree) sortTree(node *CallNode) {
+	for _, child := range node.Children {
+		ct.sortTree(child)
+	}
 	ct.sortChildren(node)
 }
 
-func (ct *CallTree) filterTestCallers(callSites []*analyzer.CallSite) []*analyzer.CallSite {
+func (ct *CallTree) groupCallSitesByCaller(callSites []*analyzer.CallSite) map[*analyzer.Function][]*analyzer.CallSite {
+	groups := make(map[*analyzer.Function][]*analyzer.CallSite)
+	for _, cs := range callSites {
+		groups[cs.Caller] = append(groups[cs.Caller], cs)
+	}
+	return groups
+}
+
+// filterCallers drops the call sites whose caller is excluded by NoTests or
+// Filter, and those suppressed by a //gogotrace:ignore comment on the call
+// or the caller.
+func (ct *CallTree) filterCallers(callSites []*analyzer.CallSite) []*analyzer.CallSite {
 	var filtered []*analyzer.CallSite
 	for _, cs := range callSites {
-		if !cs.Caller.IsTest {
-			filtered = append(filtered, cs)
+		if ct.NoTests && cs.Caller.IsTest {
+			continue
 		}
+		if cs.Kind == analyzer.CallExpectation && !ct.Mocks {
+			continue
+		}
+		if ct.Filter != nil && !ct.Filter(cs.Caller) {
+			continue
+		}
+		if ct.Analyzer.Suppressed(cs.Caller, analyzer.RuleTrace) || ct.Analyzer.SuppressedCall(cs, analyzer.RuleTrace) {
+			if ct.suppressed == nil {
+				ct.suppressed = make(map[*analyzer.CallSite]bool)
+			}
+			ct.suppressed[cs] = true
+			continue
+		}
+		filtered = append(filtered, cs)
 	}
 	return filtered
 }
 
 func (ct *CallTree) sortChildren(node *CallNode) {
-	sort.Slice(node.Children, func(i, j int) bool {
-		if node.Children[i].Function.Package != node.Children[j].Function.Package {
-			return node.Children[i].Function.Package < node.Children[j].Function.Package
+	sort.SliceStable(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		// Dynamic callers of an interface method come before implementations
+		if a.Dispatch != b.Dispatch {
+			return a.Dispatch < b.Dispatch
 		}
-		if node.Children[i].Function.File != node.Children[j].Function.File {
-			return node.Children[i].Function.File < node.Children[j].Function.File
+		switch ct.SortBy {
+		case "usages":
+			if a.Usages != b.Usages {
+				return a.Usages > b.Usages
+			}
+		case "depth":
+			if ha, hb := subtreeHeight(a), subtreeHeight(b); ha != hb {
+				return ha > hb
+			}
+		case "alpha":
+			if na, nb := ct.GetDisplayName(a.Function), ct.GetDisplayName(b.Function); na != nb {
+				return na < nb
+			}
 		}
-		if node.Children[i].Function.Name != node.Children[j].Function.Name {
-			return node.Children[i].Function.Name < node.Children[j].Function.Name
-		}
-		// Add line number for deterministic ordering of anonymous functions
-		return node.Children[i].Function.Line < node.Children[j].Function.Line
+		return packageOrderLess(a, b)
 	})
 }
 
+// packageOrderLess is the default ordering: package, file, name, then line.
+func packageOrderLess(a, b *CallNode) bool {
+	if a.Function.Package != b.Function.Package {
+		return a.Function.Package < b.Function.Package
+	}
+	if a.Function.File != b.Function.File {
+		return a.Function.File < b.Function.File
+	}
+	if a.Function.Name != b.Function.Name {
+		return a.Function.Name < b.Function.Name
+	}
+	// Add line number for deterministic ordering of anonymous functions
+	if a.Function.Line != b.Function.Line {
+		return a.Function.Line < b.Function.Line
+	}
+	return a.Function.Key() < b.Function.Key()
+}
+
+// subtreeHeight returns the number of caller levels below node.
+func subtreeHeight(node *CallNode) int {
+	height := 0
+	for _, child := range node.Children {
+		if h := subtreeHeight(child) + 1; h > height {
+			height = h
+		}
+	}
+	return height
+}
+
 func (ct *CallTree) getFunctionKey(fn *analyzer.Function) string {
 	if fn.Receiver != "" {
 		return fmt.Sprintf("%s.%s.%s", fn.Package, fn.Receiver, fn.Name)
@@ -142,7 +432,7 @@ func (ct *CallTree) FormatNode(node *CallNode) string {
 	var sb strings.Builder
 	
 	if node.Function.Receiver != "" {
-		sb.WriteString(fmt.Sprintf("%s.%s", node.Function.Receiver, node.Function.Name))
+		sb.WriteString(fmt.Sprintf("(%s).%s", node.Function.Receiver, node.Function.Name))
 	} else {
 		sb.WriteString(node.Function.Name)
 	}
@@ -156,9 +446,11 @@ func (ct *CallTree) FormatNode(node *CallNode) string {
 	return sb.String()
 }
 
+// GetDisplayName returns fn's name, qualified by its receiver as in
+// "(*Service).Run" or "(Service).Run".
 func (ct *CallTree) GetDisplayName(fn *analyzer.Function) string {
 	if fn.Receiver != "" {
-		return fmt.Sprintf("%s.%s", fn.Receiver, fn.Name)
+		return fmt.Sprintf("(%s).%s", fn.Receiver, fn.Name)
 	}
 	return fn.Name
 }
@@ -0,0 +1,52 @@
+package tree
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+func TestUniqueCallersClosures(t *testing.T) {
+	src := `package app
+
+func Target() {}
+
+func run(f func()) { f() }
+
+func a() {
+	run(func() { Target() })
+}
+
+func b() {
+	run(func() { Target() })
+}
+`
+	an := analyzer.NewAnalyzer(analyzer.WithFS(fstest.MapFS{"app.go": {Data: []byte(src)}}))
+	if err := an.LoadPackages("."); err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	ct := NewCallTree(an, false)
+	ct.UniqueCallers = true
+	if err := ct.Build("func Target()"); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	// The closures share a name but are distinct callers, each shown with
+	// its enclosing function
+	var got []string
+	for _, closure := range ct.Root.Children {
+		if closure.Alternates != 0 {
+			t.Errorf("closure at line %d has %d other paths, want 0", closure.Function.Line, closure.Alternates)
+		}
+		for _, caller := range closure.Children {
+			got = append(got, caller.Function.Name)
+		}
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "a,b" {
+		t.Errorf("closures are called by %s, want a,b", strings.Join(got, ","))
+	}
+}
@@ -0,0 +1,105 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// DependencySummary describes a node standing for the third-party callers
+// of its parent in one dependency package, as in "called via 12 functions
+// in github.com/gin-gonic/gin". It is "" for other nodes.
+func (node *CallNode) DependencySummary() string {
+	switch len(node.Collapsed) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("called via 1 function in %s", node.Function.Dependency)
+	}
+	return fmt.Sprintf("called via %d functions in %s", len(node.Collapsed), node.Function.Dependency)
+}
+
+// collapseDependencies replaces the children of node declared in
+// third-party packages by one node per dependency package. The callers of
+// such a node are the first-party functions reaching the package, through
+// any third-party code.
+func (ct *CallTree) collapseDependencies(node *CallNode, children []*CallNode) []*CallNode {
+	var kept []*CallNode
+	summaries := make(map[string]*CallNode)
+	for _, child := range children {
+		dep := child.Function.Dependency
+		if dep == "" {
+			kept = append(kept, child)
+			continue
+		}
+		
+		summary := summaries[dep]
+		if summary == nil {
+			summary = &CallNode{
+				Function: &analyzer.Function{
+					Name:       dep,
+					Signature:  dep,
+					Package:    dep,
+					File:       child.Function.File,
+					Line:       child.Function.Line,
+					Column:     child.Function.Column,
+					EndLine:    child.Function.Line,
+					EndColumn:  child.Function.Column,
+					Module:     child.Function.Module,
+					Dependency: dep,
+					FullPath:   child.Function.FullPath,
+				},
+				Depth:    child.Depth,
+				Dispatch: child.Dispatch,
+				parent:   node,
+			}
+			summaries[dep] = summary
+			kept = append(kept, summary)
+		}
+		summary.Collapsed = append(summary.Collapsed, child.Function)
+		summary.Usages += child.Usages
+		summary.CallSites = append(summary.CallSites, child.CallSites...)
+	}
+	
+	for _, summary := range summaries {
+		ct.climbDependency(summary)
+	}
+	return kept
+}
+
+// climbDependency follows the callers of the functions collapsed into
+// summary through third-party code, adding those of the same package to
+// Collapsed and recording the first-party callers found above them.
+func (ct *CallTree) climbDependency(summary *CallNode) {
+	summary.dependencyCallers = make(map[*analyzer.Function][]*analyzer.CallSite)
+	seen := make(map[string]bool)
+	queue := append([]*analyzer.Function(nil), summary.Collapsed...)
+	for _, fn := range queue {
+		seen[fn.Key()] = true
+	}
+	
+	for len(queue) > 0 {
+		fn := queue[0]
+		queue = queue[1:]
+		callSites := ct.filterCallers(ct.Analyzer.GetCallersOf(fn))
+		for caller, sites := range ct.groupCallSitesByCaller(callSites) {
+			switch {
+			case caller.Dependency == "":
+				summary.dependencyCallers[caller] = append(summary.dependencyCallers[caller], sites...)
+			case !seen[caller.Key()]:
+				seen[caller.Key()] = true
+				queue = append(queue, caller)
+				if caller.Dependency == summary.Function.Dependency {
+					summary.Collapsed = append(summary.Collapsed, caller)
+				}
+			}
+		}
+	}
+	// Sites were gathered in map order
+	for _, sites := range summary.dependencyCallers {
+		sort.SliceStable(sites, func(i, j int) bool {
+			return analyzer.CallSiteLess(sites[i], sites[j])
+		})
+	}
+}
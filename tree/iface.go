@@ -0,0 +1,163 @@
+package tree
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// Values of CallNode.Dispatch below an interface method root.
+const (
+	DispatchDynamic = "dynamic"        // calls the method through the interface
+	DispatchImpl    = "implementation" // a concrete method; its callers call it statically
+)
+
+// interfaceMethod recognizes a target naming an interface method, either
+// as "Processor.DoWork" or as "func (Processor) DoWork()".
+func (ct *CallTree) interfaceMethod(target string) (*analyzer.Interface, string, bool) {
+	target = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(target), "func"))
+	
+	var ifaceName, method string
+	if strings.HasPrefix(target, "(") {
+		end := strings.Index(target, ")")
+		if end < 0 {
+			return nil, "", false
+		}
+		fields := strings.Fields(target[1:end])
+		if len(fields) == 0 {
+			return nil, "", false
+		}
+		ifaceName = strings.TrimPrefix(fields[len(fields)-1], "*")
+		method = strings.TrimSpace(target[end+1:])
+	} else {
+		if paren := strings.Index(target, "("); paren >= 0 {
+			target = target[:paren]
+		}
+		dot := strings.LastIndex(target, ".")
+		if dot < 0 {
+			return nil, "", false
+		}
+		ifaceName, method = target[:dot], target[dot+1:]
+	}
+	if paren := strings.Index(method, "("); paren >= 0 {
+		method = method[:paren]
+	}
+	method = strings.TrimSpace(method)
+	
+	iface, err := ct.Analyzer.FindInterface(ifaceName)
+	if err != nil {
+		return nil, "", false
+	}
+	if _, ok := iface.Methods[method]; !ok {
+		return nil, "", false
+	}
+	return iface, method, true
+}
+
+// BuildInterfaceMethod builds one tree for a method of iface covering every
+// implementation: the root stands for the interface method, and its
+// children are the callers that call it through the interface (Dispatch
+// DispatchDynamic) and the implementing methods (DispatchImpl), each with
+// the callers that call that implementation directly.
+func (ct *CallTree) BuildInterfaceMethod(iface *analyzer.Interface, method string) error {
+	var impls []*analyzer.Function
+	for _, impl := range ct.Analyzer.Implementations(iface) {
+		for _, fn := range impl.Methods {
+			if fn.Name == method {
+				impls = append(impls, fn)
+			}
+		}
+	}
+	if len(impls) == 0 {
+		return fmt.Errorf("no implementations found for %s.%s", iface.Name, method)
+	}
+	
+	file := ""
+	if iface.FullPath != "" {
+		file = filepath.Base(iface.FullPath)
+	}
+	ct.Root = &CallNode{
+		Function: &analyzer.Function{
+			Name:      method,
+			Receiver:  iface.Name,
+			Signature: fmt.Sprintf("func (%s) %s%s", iface.Name, method, iface.Methods[method]),
+			Package:   iface.Package,
+			File:      file,
+			Line:      iface.Line,
+			EndLine:   iface.Line,
+			FullPath:  iface.FullPath,
+		},
+	}
+	ct.rootChildren = func() []*CallNode {
+		return ct.interfaceNodes(impls)
+	}
+	
+	ct.expand()
+	
+	name := iface.Name + "." + method
+	if ct.Focus != "" && !ct.keepBranches(ct.Root, ct.inFocus) {
+		return fmt.Errorf("no call paths to %s pass through package %s", name, ct.Focus)
+	}
+	if ct.Tag != "" && !ct.keepBranches(ct.Root, ct.hasTag) {
+		return fmt.Errorf("no call paths to %s pass through a function tagged %s", name, ct.Tag)
+	}
+	if ct.OnlyTests && !ct.keepBranches(ct.Root, isTest) {
+		return fmt.Errorf("no test reaches %s", name)
+	}
+	
+	return nil
+}
+
+// interfaceNodes returns the children of a BuildInterfaceMethod root: the
+// callers dispatching through the interface to any of impls, and impls.
+func (ct *CallTree) interfaceNodes(impls []*analyzer.Function) []*CallNode {
+	// A call through the interface reaches every implementation; keep
+	// one site per call
+	var dynamic []*analyzer.CallSite
+	seen := make(map[string]bool)
+	for _, impl := range impls {
+		for _, cs := range ct.filterCallers(ct.Analyzer.GetCallersOf(impl)) {
+			key := fmt.Sprintf("%s:%d:%d", cs.Caller.Key(), cs.Line, cs.Column)
+			if cs.Kind == analyzer.CallInterface && !seen[key] {
+				seen[key] = true
+				dynamic = append(dynamic, cs)
+			}
+		}
+	}
+	
+	var callers []*CallNode
+	for caller, sites := range ct.groupCallSitesByCaller(dynamic) {
+		if len(sites) < ct.MinUsages {
+			continue
+		}
+		callers = append(callers, &CallNode{
+			Function:  caller,
+			Usages:    len(sites),
+			Depth:     1,
+			CallSites: sites,
+			Dispatch:  DispatchDynamic,
+			parent:    ct.Root,
+		})
+	}
+	for _, node := range ct.methodNodes(impls) {
+		node.Dispatch = DispatchImpl
+		callers = append(callers, node)
+	}
+	
+	holder := &CallNode{Children: callers}
+	ct.sortChildren(holder)
+	return holder.Children
+}
+
+// staticCalls drops the calls made through an interface.
+func staticCalls(callSites []*analyzer.CallSite) []*analyzer.CallSite {
+	var static []*analyzer.CallSite
+	for _, cs := range callSites {
+		if cs.Kind != analyzer.CallInterface {
+			static = append(static, cs)
+		}
+	}
+	return static
+}
@@ -0,0 +1,99 @@
+package tree
+
+import (
+	"sort"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// OwnerCount is the number of distinct callers belonging to one owner.
+type OwnerCount struct {
+	Owner   string `json:"owner"`
+	Callers int    `json:"callers"`
+	Direct  int    `json:"direct,omitempty"` // callers of the traced function itself
+}
+
+// AnnotateOwners sets Owners on every node of the tree from lookup.
+func (ct *CallTree) AnnotateOwners(lookup func(fn *analyzer.Function) []string) {
+	if ct.Root == nil {
+		return
+	}
+	ct.owned = true
+	ct.walk(ct.Root, func(node *CallNode) {
+		node.Owners = lookup(node.Function)
+	})
+}
+
+// AnnotateTags sets Tags on every node of the tree from lookup.
+func (ct *CallTree) AnnotateTags(lookup func(fn *analyzer.Function) []string) {
+	if ct.Root == nil {
+		return
+	}
+	ct.walk(ct.Root, func(node *CallNode) {
+		node.Tags = lookup(node.Function)
+	})
+}
+
+// CallersByOwner counts the distinct callers in the tree per owner, most
+// callers first, with how many of them call the traced function directly.
+// Callers with several owners count for each of them and
+// callers without one are counted under unowned. It returns nil unless
+// AnnotateOwners was called.
+func (ct *CallTree) CallersByOwner(unowned string) []OwnerCount {
+	if !ct.owned {
+		return nil
+	}
+	
+	// A caller may be reached directly and through other callers
+	callers := make(map[string]*CallNode)
+	direct := make(map[string]bool)
+	ct.walk(ct.Root, func(node *CallNode) {
+		if node == ct.Root {
+			return
+		}
+		key := node.Function.Key()
+		callers[key] = node
+		if node.parent == ct.Root {
+			direct[key] = true
+		}
+	})
+	
+	counts := make(map[string]*OwnerCount)
+	for key, node := range callers {
+		owners := node.Owners
+		if len(owners) == 0 {
+			owners = []string{unowned}
+		}
+		for _, owner := range owners {
+			count := counts[owner]
+			if count == nil {
+				count = &OwnerCount{Owner: owner}
+				counts[owner] = count
+			}
+			count.Callers++
+			if direct[key] {
+				count.Direct++
+			}
+		}
+	}
+	
+	var summary []OwnerCount
+	for _, count := range counts {
+		summary = append(summary, *count)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Callers != summary[j].Callers {
+			return summary[i].Callers > summary[j].Callers
+		}
+		return summary[i].Owner < summary[j].Owner
+	})
+	return summary
+}
+
+// walk calls visit for node and every node below it.
+func (ct *CallTree) walk(node *CallNode, visit func(*CallNode)) {
+	visit(node)
+	for _, child := range node.Children {
+		ct.walk(child, visit)
+	}
+}
@@ -0,0 +1,47 @@
+package tree
+
+// Summary holds aggregate counts over a call tree.
+type Summary struct {
+	Callers     int // distinct caller functions
+	TestCallers int // distinct callers that are test functions
+	Packages    int // distinct packages among the callers
+	MaxDepth    int // deepest caller level reached
+	Nodes       int // caller nodes, a function reached twice counting twice
+	Omitted     int // callers left out by a depth or node budget
+	Suppressed  int // call sites left out by a //gogotrace:ignore comment
+}
+
+// Summary counts the callers in the tree below the root.
+func (ct *CallTree) Summary() Summary {
+	var s Summary
+	if ct.Root == nil {
+		return s
+	}
+	
+	callers := make(map[string]bool)
+	packages := make(map[string]bool)
+	ct.walk(ct.Root, func(node *CallNode) {
+		s.Omitted += node.Omitted
+		if node == ct.Root {
+			return
+		}
+		s.Nodes++
+		if node.Depth > s.MaxDepth {
+			s.MaxDepth = node.Depth
+		}
+		key := node.Function.Key()
+		if callers[key] {
+			return
+		}
+		callers[key] = true
+		packages[node.Function.Package] = true
+		if node.Function.IsTest {
+			s.TestCallers++
+		}
+	})
+	s.Callers = len(callers)
+	s.Suppressed = len(ct.suppressed)
+	s.Packages = len(packages)
+	
+	return s
+}
@@ -0,0 +1,71 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// BuildType builds one tree covering every method of the named type, as
+// found by Analyzer.FindMethods: the root stands for the type, its
+// children are the methods, with or without callers, and below them are
+// the callers of each method. The root takes the location of the first
+// method since type declarations are not recorded.
+func (ct *CallTree) BuildType(typeName string) error {
+	methods, err := ct.Analyzer.FindMethods(typeName)
+	if err != nil {
+		return err
+	}
+	
+	name := typeName[strings.LastIndex(typeName, ".")+1:]
+	first := methods[0]
+	ct.rootChildren = func() []*CallNode {
+		return ct.methodNodes(methods)
+	}
+	ct.Root = &CallNode{
+		Function: &analyzer.Function{
+			Name:        name,
+			Signature:   "type " + name,
+			Package:     first.Package,
+			File:        first.File,
+			Line:        first.Line,
+			Column:      first.Column,
+			EndLine:     first.Line,
+			EndColumn:   first.Column,
+			IsGenerated: first.IsGenerated,
+			Module:      first.Module,
+			FullPath:    first.FullPath,
+		},
+	}
+	
+	ct.expand()
+	
+	if ct.Focus != "" && !ct.keepBranches(ct.Root, ct.inFocus) {
+		return fmt.Errorf("no call paths to type %s pass through package %s", typeName, ct.Focus)
+	}
+	if ct.Tag != "" && !ct.keepBranches(ct.Root, ct.hasTag) {
+		return fmt.Errorf("no call paths to type %s pass through a function tagged %s", typeName, ct.Tag)
+	}
+	if ct.OnlyTests && !ct.keepBranches(ct.Root, isTest) {
+		return fmt.Errorf("no test reaches type %s", typeName)
+	}
+	
+	return nil
+}
+
+// methodNodes returns the children of a BuildType root, one per method.
+func (ct *CallTree) methodNodes(methods []*analyzer.Function) []*CallNode {
+	var children []*CallNode
+	for _, method := range methods {
+		children = append(children, &CallNode{
+			Function: method,
+			Depth:    1,
+			parent:   ct.Root,
+		})
+	}
+	
+	holder := &CallNode{Children: children}
+	ct.sortChildren(holder)
+	return holder.Children
+}
@@ -0,0 +1,70 @@
+package tree
+
+import (
+	"sort"
+	"strings"
+	
+	"github.com/gogotrace/gogotrace/analyzer"
+)
+
+// ThroughLabel describes the wrappers seen through between node and its
+// parent, as in "through (*Store).Get, get", or "".
+func (node *CallNode) ThroughLabel() string {
+	if len(node.Through) == 0 {
+		return ""
+	}
+	var names []string
+	for _, fn := range node.Through {
+		if fn.Receiver != "" {
+			names = append(names, "("+fn.Receiver+")."+fn.Name)
+		} else {
+			names = append(names, fn.Name)
+		}
+	}
+	return "through " + strings.Join(names, ", ")
+}
+
+// wrapperCallers replaces every wrapper caller in groups that has callers
+// of its own by those callers, recursively, keeping the call sites of the
+// last hop. It also returns, for each caller reached that way, the
+// wrappers it calls through, nearest to the parent first.
+func (ct *CallTree) wrapperCallers(groups map[*analyzer.Function][]*analyzer.CallSite) (map[*analyzer.Function][]*analyzer.CallSite, map[*analyzer.Function][]*analyzer.Function) {
+	result := make(map[*analyzer.Function][]*analyzer.CallSite)
+	through := make(map[*analyzer.Function][]*analyzer.Function)
+	seen := make(map[string]bool)
+	type hop struct {
+		groups map[*analyzer.Function][]*analyzer.CallSite
+		path   []*analyzer.Function
+	}
+	queue := []hop{{groups: groups}}
+	
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		for caller, sites := range h.groups {
+			if caller.IsWrapper {
+				if seen[caller.Key()] {
+					continue
+				}
+				callers := ct.groupCallSitesByCaller(ct.filterCallers(ct.Analyzer.GetCallersOf(caller)))
+				if len(callers) > 0 {
+					seen[caller.Key()] = true
+					path := append(append([]*analyzer.Function(nil), h.path...), caller)
+					queue = append(queue, hop{groups: callers, path: path})
+					continue
+				}
+			}
+			if _, ok := result[caller]; !ok && len(h.path) > 0 {
+				through[caller] = h.path
+			}
+			result[caller] = append(result[caller], sites...)
+		}
+	}
+	// Sites were gathered in map order
+	for _, sites := range result {
+		sort.SliceStable(sites, func(i, j int) bool {
+			return analyzer.CallSiteLess(sites[i], sites[j])
+		})
+	}
+	return result, through
+}
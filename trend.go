@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogotrace/gogotrace/graph"
+)
+
+func init() {
+	subcommands["snapshot"] = subcommand{
+		summary: "Record caller counts and dead code in a history file for the current commit",
+		run:     runSnapshot,
+	}
+	subcommands["trend"] = subcommand{
+		summary: "Print how the metrics recorded by snapshot changed over time",
+		run:     runTrend,
+	}
+}
+
+// defaultHistory is where snapshot records metrics, relative to -dir.
+var defaultHistory = filepath.Join(".gogotrace", "history.jsonl")
+
+// trendRecord is one line of the history file: the metrics of a commit.
+type trendRecord struct {
+	Commit    string                  `json:"commit,omitempty"`
+	Date      time.Time               `json:"date"` // commit date, or when recorded outside git
+	Functions int                     `json:"functions"`
+	DeadCode  int                     `json:"deadCode"`
+	Callers   map[string]trendCallers `json:"callers,omitempty"` // by signature as given to -func
+}
+
+// trendCallers is the number of distinct callers of a tracked function.
+type trendCallers struct {
+	Direct     int `json:"direct"`
+	Transitive int `json:"transitive"`
+}
+
+func runSnapshot(args []string) int {
+	fs := newFlagSet("snapshot")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	history := fs.String("history", "", "History file to append to (default "+defaultHistory+" in -dir)")
+	var funcs, entryPatterns stringList
+	fs.Var(&funcs, "func", "Function whose callers to count, such as one being deprecated (repeatable)")
+	fs.Var(&entryPatterns, "entry", "Regexp matching the entry points from which code is not dead (repeatable, default main and init)")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace snapshot [-func \"<signature>\" ...] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(entryPatterns) == 0 {
+		entryPatterns = stringList{"main", "init"}
+	}
+	patterns, err := compileEntryPatterns(entryPatterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -entry pattern %v\n", err)
+		return 2
+	}
+	if *history == "" {
+		*history = filepath.Join(*dir, defaultHistory)
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	record := trendRecord{Date: time.Now().UTC().Truncate(time.Second)}
+	if absDir, err := filepath.Abs(*dir); err == nil {
+		if commit, err := git(absDir, "rev-parse", "HEAD"); err == nil {
+			record.Commit = commit
+			if date, err := git(absDir, "log", "-1", "--format=%cI", "HEAD"); err == nil {
+				if t, err := time.Parse(time.RFC3339, date); err == nil {
+					record.Date = t
+				}
+			}
+		}
+	}
+	for _, fn := range a.GetFunctions() {
+		if !*noTests || !fn.IsTest {
+			record.Functions++
+		}
+	}
+	unreachable, _ := unreachableFunctions(a, graph.Reachable(a, entryFunctions(a, patterns, *noTests), *noTests), *noTests)
+	record.DeadCode = len(unreachable)
+
+	for _, sig := range funcs {
+		fn, err := a.FindFunction(sig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		direct := make(map[string]bool)
+		for _, cs := range a.GetCallersOf(fn) {
+			if !*noTests || !cs.Caller.IsTest {
+				direct[cs.Caller.Key()] = true
+			}
+		}
+		if record.Callers == nil {
+			record.Callers = make(map[string]trendCallers)
+		}
+		record.Callers[sig] = trendCallers{Direct: len(direct), Transitive: len(a.TransitiveCallers(fn, *noTests))}
+	}
+
+	records, err := readHistory(*history)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		return 1
+	}
+	if err := writeHistory(*history, addRecord(records, record)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing history: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("\nRecorded %s in %s: %d functions, %d dead", shortCommit(record.Commit), *history, record.Functions, record.DeadCode)
+	for _, sig := range funcs {
+		c := record.Callers[sig]
+		fmt.Printf(", %s %d/%d callers", sig, c.Direct, c.Transitive)
+	}
+	fmt.Println()
+	return 0
+}
+
+func runTrend(args []string) int {
+	fs := newFlagSet("trend")
+	dir := fs.String("dir", ".", "Directory whose history to read")
+	history := fs.String("history", "", "History file written by snapshot (default "+defaultHistory+" in -dir)")
+	last := fs.Int("last", 0, "Only show the N most recent snapshots")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace trend [-history file] [-last N]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *history == "" {
+		*history = filepath.Join(*dir, defaultHistory)
+	}
+
+	records, err := readHistory(*history)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		return 1
+	}
+	if *last > 0 && len(records) > *last {
+		records = records[len(records)-*last:]
+	}
+	if len(records) == 0 {
+		fmt.Println("No snapshots recorded")
+		return 0
+	}
+
+	fmt.Println("Dead code:")
+	dead := make(series, len(records))
+	for i := range records {
+		dead[i] = &records[i].DeadCode
+	}
+	for i, r := range records {
+		fmt.Printf("  %s  %-8s  %6d%s  of %d functions\n", r.Date.Format("2006-01-02"), shortCommit(r.Commit), r.DeadCode, dead.delta(i), r.Functions)
+	}
+
+	// Functions tracked in any snapshot, in the order first seen
+	var tracked []string
+	seen := make(map[string]bool)
+	for _, r := range records {
+		var sigs []string
+		for sig := range r.Callers {
+			if !seen[sig] {
+				sigs = append(sigs, sig)
+			}
+		}
+		sort.Strings(sigs)
+		for _, sig := range sigs {
+			seen[sig] = true
+			tracked = append(tracked, sig)
+		}
+	}
+	for _, sig := range tracked {
+		direct, transitive := make(series, len(records)), make(series, len(records))
+		for i, r := range records {
+			if c, ok := r.Callers[sig]; ok {
+				direct[i], transitive[i] = &c.Direct, &c.Transitive
+			}
+		}
+		fmt.Printf("\nCallers of %s (direct, transitive):\n", sig)
+		for i, r := range records {
+			if direct[i] != nil {
+				line := fmt.Sprintf("  %s  %-8s  %6d%s  %6d%s", r.Date.Format("2006-01-02"), shortCommit(r.Commit), *direct[i], direct.delta(i), *transitive[i], transitive.delta(i))
+				fmt.Println(strings.TrimRight(line, " "))
+			}
+		}
+	}
+	return 0
+}
+
+// series is a metric over the snapshots, nil where a snapshot lacks it.
+type series []*int
+
+// delta formats the change of the metric at snapshot i since the latest
+// earlier snapshot that has it, as " (-3)" padded for alignment, or blanks
+// when none does.
+func (s series) delta(i int) string {
+	for j := i - 1; j >= 0; j-- {
+		if s[j] != nil {
+			return fmt.Sprintf(" %-7s", fmt.Sprintf("(%+d)", *s[i]-*s[j]))
+		}
+	}
+	return strings.Repeat(" ", 8)
+}
+
+// addRecord adds record to the history. A commit is recorded once: the
+// latest snapshot of it replaces the earlier one in place.
+func addRecord(records []trendRecord, record trendRecord) []trendRecord {
+	if record.Commit != "" {
+		for i, r := range records {
+			if r.Commit == record.Commit {
+				records[i] = record
+				return records
+			}
+		}
+	}
+	return append(records, record)
+}
+
+func shortCommit(commit string) string {
+	if commit == "" {
+		return "-"
+	}
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+// readHistory reads the records of a history file, oldest first by commit
+// date, whatever order the commits were snapshotted in.
+func readHistory(path string) ([]trendRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []trendRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var r trendRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+	return records, nil
+}
+
+// writeHistory writes records to a history file, one JSON object per line.
+func writeHistory(path string, records []trendRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryRoundTrip(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	path := filepath.Join(t.TempDir(), ".gogotrace", "history.jsonl")
+
+	// Snapshotting an older commit after a newer one
+	var records []trendRecord
+	records = addRecord(records, trendRecord{Commit: "bbb", Date: day(2), DeadCode: 5})
+	records = addRecord(records, trendRecord{Commit: "aaa", Date: day(1), DeadCode: 7})
+	if err := writeHistory(path, records); err != nil {
+		t.Fatalf("writeHistory: %v", err)
+	}
+	got, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory: %v", err)
+	}
+	if got[0].Commit != "aaa" || got[1].Commit != "bbb" {
+		t.Errorf("read %s, %s, want aaa, bbb by date", got[0].Commit, got[1].Commit)
+	}
+
+	// Re-running a snapshot replaces the record rather than moving it
+	callers := map[string]trendCallers{"func Old()": {Direct: 1, Transitive: 3}}
+	got = addRecord(got, trendRecord{Commit: "aaa", Date: day(1), DeadCode: 6, Callers: callers})
+	got = addRecord(got, trendRecord{Date: day(3), DeadCode: 4})
+	if err := writeHistory(path, got); err != nil {
+		t.Fatalf("writeHistory: %v", err)
+	}
+	reread, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory: %v", err)
+	}
+	want := []trendRecord{
+		{Commit: "aaa", Date: day(1), DeadCode: 6, Callers: callers},
+		{Commit: "bbb", Date: day(2), DeadCode: 5},
+		{Date: day(3), DeadCode: 4},
+	}
+	if !reflect.DeepEqual(reread, want) {
+		t.Errorf("read back %+v, want %+v", reread, want)
+	}
+}
+
+func TestSeriesDelta(t *testing.T) {
+	n := func(v int) *int { return &v }
+	s := series{n(10), nil, n(7), n(9)}
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{0, "        "},
+		{2, " (-3)   "},
+		{3, " (+2)   "},
+	}
+	for _, tt := range tests {
+		if got := s.delta(tt.i); got != tt.want {
+			t.Errorf("delta(%d) = %q, want %q", tt.i, got, tt.want)
+		}
+	}
+}
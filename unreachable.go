@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/graph"
+)
+
+func init() {
+	subcommands["unreachable"] = subcommand{
+		summary: "List functions not reachable from any entry point",
+		run:     runUnreachable,
+	}
+}
+
+func runUnreachable(args []string) int {
+	fs := newFlagSet("unreachable")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	var entryPatterns stringList
+	fs.Var(&entryPatterns, "entry", "Regexp matching entry point names such as main, Test.* or Handle.* (repeatable, default main and init)")
+	baseline := addBaselineFlags(fs, "unreachable")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace unreachable [-entry pattern ...] [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(entryPatterns) == 0 {
+		entryPatterns = stringList{"main", "init"}
+	}
+
+	patterns, err := compileEntryPatterns(entryPatterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -entry pattern %v\n", err)
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	entries := entryFunctions(a, patterns, *noTests)
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no function matches the entry patterns")
+		return 1
+	}
+
+	reached := graph.Reachable(a, entries, *noTests)
+
+	// Unreachable code that still has callers is only called from other
+	// unreachable code, which a zero-caller report misses
+	var orphans, islands []*analyzer.Function
+	unreachable, suppressed := unreachableFunctions(a, reached, *noTests)
+	for _, fn := range unreachable {
+		if len(a.GetCallersOf(fn)) == 0 {
+			orphans = append(orphans, fn)
+		} else {
+			islands = append(islands, fn)
+		}
+	}
+
+	fmt.Printf("%d entry points, %d reachable functions, %d prune candidates\n",
+		len(entries), len(reached), len(orphans)+len(islands))
+	printFunctionSet("Unreachable, no callers", orphans)
+	printFunctionSet("Unreachable, called only from unreachable code", islands)
+	printSuppressed(suppressed)
+	return baseline.check(functionFindings(orphans, islands))
+}
+
+// compileEntryPatterns anchors and compiles the -entry patterns.
+func compileEntryPatterns(entryPatterns []string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, p := range entryPatterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// entryFunctions returns the functions matching one of the patterns.
+func entryFunctions(a *analyzer.Analyzer, patterns []*regexp.Regexp, noTests bool) []*analyzer.Function {
+	var entries []*analyzer.Function
+	for _, fn := range a.GetFunctions() {
+		if noTests && fn.IsTest {
+			continue
+		}
+		if matchesEntry(fn, patterns) {
+			entries = append(entries, fn)
+		}
+	}
+	return entries
+}
+
+// unreachableFunctions returns the functions missing from reached, leaving
+// out those suppressed by a //gogotrace:ignore comment, which it counts.
+func unreachableFunctions(a *analyzer.Analyzer, reached map[string]*analyzer.Function, noTests bool) (unreachable []*analyzer.Function, suppressed int) {
+	for _, fn := range a.GetFunctions() {
+		if noTests && fn.IsTest {
+			continue
+		}
+		if _, ok := reached[fn.Key()]; ok {
+			continue
+		}
+		if a.Suppressed(fn, analyzer.RuleUnreachable) {
+			suppressed++
+			continue
+		}
+		unreachable = append(unreachable, fn)
+	}
+	return unreachable, suppressed
+}
+
+// matchesEntry reports whether fn's name, or Receiver.Name for methods,
+// matches one of the patterns.
+func matchesEntry(fn *analyzer.Function, patterns []*regexp.Regexp) bool {
+	name := fn.Name
+	if fn.Receiver != "" {
+		name = fn.Receiver + "." + fn.Name
+	}
+	for _, re := range patterns {
+		if re.MatchString(fn.Name) || re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gogotrace/gogotrace/analyzer"
+	"github.com/gogotrace/gogotrace/graph"
+)
+
+func init() {
+	subcommands["unsafe"] = subcommand{
+		summary: "Audit the functions using unsafe pointers or cgo and the exported entry points reaching them",
+		run:     runUnsafe,
+	}
+}
+
+func runUnsafe(args []string) int {
+	fs := newFlagSet("unsafe")
+	dir := fs.String("dir", ".", "Directory to analyze")
+	noTests := fs.Bool("no-test", false, "Exclude test functions from results")
+	baseline := addBaselineFlags(fs, "unsafe")
+	addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gogotrace unsafe [options]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	a, ok := loadAnalyzer(*dir)
+	if !ok {
+		return 1
+	}
+
+	var users []*analyzer.Function
+	suppressed := 0
+	for _, fn := range a.GetFunctions() {
+		if (*noTests && fn.IsTest) || len(a.UnsafeUses(fn)) == 0 {
+			continue
+		}
+		if a.Suppressed(fn, analyzer.RuleUnsafe) {
+			suppressed++
+			continue
+		}
+		users = append(users, fn)
+	}
+	sortFunctions(users)
+
+	fmt.Printf("Functions using unsafe pointers or cgo (%d):\n", len(users))
+	for _, fn := range users {
+		fmt.Printf("\n  %s in %s:%d [%s]\n", fn.Signature, fn.FullPath, fn.Line, strings.Join(a.UnsafeUses(fn), ", "))
+
+		reached, next := graph.ReverseReach(a, fn, *noTests, nil)
+		var entries []*analyzer.Function
+		if fn.IsExported() {
+			entries = append(entries, fn)
+		}
+		for _, caller := range reached {
+			if caller.IsExported() {
+				entries = append(entries, caller)
+			}
+		}
+		sortFunctions(entries)
+		if len(entries) == 0 {
+			fmt.Println("    No exported entry point reaches it")
+			continue
+		}
+		fmt.Printf("    Exported entry points reaching it (%d):\n", len(entries))
+		for _, entry := range entries {
+			fmt.Printf("      %s in %s:%d\n", entry.Signature, entry.FullPath, entry.Line)
+			if entry != fn {
+				fmt.Printf("          %s\n", formatPath(graph.PathTo(entry, next)))
+			}
+		}
+	}
+	printSuppressed(suppressed)
+	return baseline.check(functionFindings(users))
+}
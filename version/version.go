@@ -0,0 +1,69 @@
+// Package version reports build metadata of the running gogotrace binary.
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// BuildDate can be set at link time with
+// -ldflags "-X github.com/gogotrace/gogotrace/version.BuildDate=2024-01-02".
+// When empty, the VCS commit time recorded by the Go toolchain is used.
+var BuildDate string
+
+// Info identifies the gogotrace build that produced a report.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get reads the build metadata embedded by the Go toolchain.
+func Get() Info {
+	info := Info{Version: "(devel)", Date: BuildDate}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	info.GoVersion = bi.GoVersion
+	if bi.Main.Version != "" {
+		info.Version = bi.Main.Version
+	}
+
+	modified := false
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified && info.Commit != "" {
+		info.Commit += "-dirty"
+	}
+
+	return info
+}
+
+// String formats the metadata on a single line.
+func (i Info) String() string {
+	parts := []string{"gogotrace " + i.Version}
+	if i.Commit != "" {
+		parts = append(parts, "commit "+i.Commit)
+	}
+	if i.Date != "" {
+		parts = append(parts, "built "+i.Date)
+	}
+	if i.GoVersion != "" {
+		parts = append(parts, i.GoVersion)
+	}
+	return strings.Join(parts, ", ")
+}